@@ -0,0 +1,366 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/brettbuddin/fourier"
+	"github.com/brettbuddin/fourier/window"
+)
+
+// floorDB is the lowest level reported, in place of silence.
+const floorDB = -200
+
+// analyzer computes spectra of a signal frame by frame with a fixed FFT size
+// and window.
+//
+// Amplitude spectra are scaled so that a sinusoid of peak amplitude 1 centered
+// on a bin reads 1 (0 dBFS) whatever the window.
+type analyzer struct {
+	sampleRate float64
+	size, hop  int
+	window     []float64
+
+	// Window properties
+	coherentGain float64 // mean of the window
+	enbw         float64 // equivalent noise bandwidth, in bins
+	lobe         int     // half-width of the main lobe, in bins
+
+	buf []complex128
+	mag []float64
+}
+
+// newAnalyzer returns an analyzer for frames of size samples, taken every
+// size*(1-overlap) samples.
+func newAnalyzer(sampleRate, size int, overlap float64, wf window.Func) (*analyzer, error) {
+	if size < 4 || size&(size-1) != 0 {
+		return nil, errors.New("FFT size must be a power of two of at least 4")
+	}
+	if overlap < 0 || overlap >= 1 {
+		return nil, errors.New("overlap must be at least 0 and less than 1")
+	}
+
+	a := &analyzer{
+		sampleRate: float64(sampleRate),
+		size:       size,
+		hop:        int(math.Max(1, math.Round(float64(size)*(1-overlap)))),
+		window:     make([]float64, size),
+		buf:        make([]complex128, size),
+		mag:        make([]float64, size),
+	}
+
+	var sum, sumSquares float64
+	for i := range a.window {
+		// Periodic (DFT-even) window
+		w := wf(float64(i), size)
+		a.window[i] = w
+		sum += w
+		sumSquares += w * w
+	}
+	if sum == 0 {
+		return nil, errors.New("window is zero everywhere")
+	}
+	a.coherentGain = sum / float64(size)
+	a.enbw = float64(size) * sumSquares / (sum * sum)
+
+	lobe, err := mainLobe(a.window)
+	if err != nil {
+		return nil, err
+	}
+	a.lobe = lobe
+	return a, nil
+}
+
+// mainLobe returns the half-width, in whole bins, of the main lobe of the
+// window's spectrum: the distance to its first null.
+func mainLobe(w []float64) (int, error) {
+	const oversample = 8
+	buf := make([]complex128, oversample*len(w))
+	for i, v := range w {
+		buf[i] = complex(v, 0)
+	}
+	if err := fourier.Forward(buf); err != nil {
+		return 0, err
+	}
+
+	prev := math.Inf(1)
+	for i := 0; i < len(buf)/2; i++ {
+		v := real(buf[i])*real(buf[i]) + imag(buf[i])*imag(buf[i])
+		if v > prev {
+			return int(math.Ceil(float64(i-1) / oversample)), nil
+		}
+		prev = v
+	}
+	return len(w) / 2, nil
+}
+
+// numBins returns the number of bins in a one-sided spectrum.
+func (a *analyzer) numBins() int {
+	return a.size/2 + 1
+}
+
+// frequency returns the center frequency of bin k.
+func (a *analyzer) frequency(k float64) float64 {
+	return k * a.sampleRate / float64(a.size)
+}
+
+// frequencies returns the center frequencies of all bins.
+func (a *analyzer) frequencies() []float64 {
+	f := make([]float64, a.numBins())
+	for k := range f {
+		f[k] = a.frequency(float64(k))
+	}
+	return f
+}
+
+// numFrames returns the number of frames in a signal of n samples. A signal
+// shorter than a frame is zero-padded to one frame.
+func (a *analyzer) numFrames(n int) int {
+	if n <= a.size {
+		return 1
+	}
+	return 1 + (n-a.size)/a.hop
+}
+
+// amplitude computes the amplitude spectrum of the frame of x starting at
+// offset. Samples beyond the end of x are taken as silence.
+func (a *analyzer) amplitude(dest, x []float64, offset int) error {
+	for i := range a.buf {
+		var v float64
+		if j := offset + i; j < len(x) {
+			v = x[j] * a.window[i]
+		}
+		a.buf[i] = complex(v, 0)
+	}
+	if err := fourier.Forward(a.buf); err != nil {
+		return err
+	}
+	if err := fourier.Magnitude(a.mag, a.buf); err != nil {
+		return err
+	}
+
+	// Magnitude scales by 2/N, which accounts for the negative frequencies
+	// folded into each bin. DC and Nyquist have no mirror image.
+	for k := range dest {
+		dest[k] = a.mag[k] / a.coherentGain
+	}
+	dest[0] /= 2
+	dest[len(dest)-1] /= 2
+	return nil
+}
+
+// spectrum returns the RMS average of the amplitude spectra of all frames of
+// x.
+func (a *analyzer) spectrum(x []float64) ([]float64, error) {
+	var (
+		avg       = make([]float64, a.numBins())
+		amp       = make([]float64, a.numBins())
+		numFrames = a.numFrames(len(x))
+	)
+	for f := 0; f < numFrames; f++ {
+		if err := a.amplitude(amp, x, f*a.hop); err != nil {
+			return nil, err
+		}
+		for k, v := range amp {
+			avg[k] += v * v
+		}
+	}
+	for k := range avg {
+		avg[k] = math.Sqrt(avg[k] / float64(numFrames))
+	}
+	return avg, nil
+}
+
+// psd returns the one-sided power spectral density of x, in units squared per
+// Hz, by Welch's method: the average of the periodograms of all frames.
+func (a *analyzer) psd(x []float64) ([]float64, error) {
+	spectrum, err := a.spectrum(x)
+	if err != nil {
+		return nil, err
+	}
+
+	// An amplitude A in a bin is a power of A²/2 spread over the window's
+	// noise bandwidth. DC and Nyquist carry their full power A².
+	binWidth := a.sampleRate / float64(a.size)
+	for k, v := range spectrum {
+		p := v * v / 2
+		if k == 0 || k == len(spectrum)-1 {
+			p = v * v
+		}
+		spectrum[k] = p / (a.enbw * binWidth)
+	}
+	return spectrum, nil
+}
+
+// spectrogram returns the amplitude spectrum of every frame of x and the time
+// of the center of each frame, in seconds.
+func (a *analyzer) spectrogram(x []float64) ([][]float64, []float64, error) {
+	var (
+		numFrames = a.numFrames(len(x))
+		frames    = make([][]float64, numFrames)
+		times     = make([]float64, numFrames)
+	)
+	for f := range frames {
+		frames[f] = make([]float64, a.numBins())
+		if err := a.amplitude(frames[f], x, f*a.hop); err != nil {
+			return nil, nil, err
+		}
+		times[f] = (float64(f*a.hop) + float64(a.size)/2) / a.sampleRate
+	}
+	return frames, times, nil
+}
+
+// peak is a spectral peak.
+type peak struct {
+	Frequency float64 `json:"frequency"`
+	Level     float64 `json:"level"` // dBFS
+}
+
+// peaks returns up to n of the highest peaks in an amplitude spectrum that
+// reach threshold (in dBFS), highest first. Peaks within the main lobe of a
+// higher peak are ignored, and each peak's frequency and level are refined by
+// interpolating between bins.
+func (a *analyzer) peaks(spectrum []float64, n int, threshold float64) []peak {
+	var candidates []int
+	for k := 1; k < len(spectrum)-1; k++ {
+		if spectrum[k] > spectrum[k-1] && spectrum[k] >= spectrum[k+1] && toDB(spectrum[k]) >= threshold {
+			candidates = append(candidates, k)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return spectrum[candidates[i]] > spectrum[candidates[j]]
+	})
+
+	var (
+		peaks []peak
+		taken []int
+	)
+	for _, k := range candidates {
+		if len(peaks) == n {
+			break
+		}
+		masked := false
+		for _, t := range taken {
+			if abs(k-t) <= a.lobe {
+				masked = true
+				break
+			}
+		}
+		if masked {
+			continue
+		}
+		taken = append(taken, k)
+		peaks = append(peaks, a.interpolate(spectrum, k))
+	}
+	return peaks
+}
+
+// interpolate fits a parabola to the levels (in dB) of bin k and its
+// neighbours and returns the vertex.
+func (a *analyzer) interpolate(spectrum []float64, k int) peak {
+	var (
+		alpha = toDB(spectrum[k-1])
+		beta  = toDB(spectrum[k])
+		gamma = toDB(spectrum[k+1])
+		d     = alpha - 2*beta + gamma
+		p     float64
+	)
+	if d != 0 {
+		p = 0.5 * (alpha - gamma) / d
+	}
+	return peak{
+		Frequency: a.frequency(float64(k) + p),
+		Level:     beta - 0.25*(alpha-gamma)*p,
+	}
+}
+
+// harmonic is the measured level of one harmonic of a fundamental.
+type harmonic struct {
+	Order     int     `json:"order"`
+	Frequency float64 `json:"frequency"`
+	Level     float64 `json:"level"` // dBFS
+}
+
+// distortion is the result of a THD measurement.
+type distortion struct {
+	Fundamental harmonic   `json:"fundamental"`
+	Harmonics   []harmonic `json:"harmonics"`
+	THD         float64    `json:"thd"` // ratio of harmonic to fundamental amplitude
+	THDPercent  float64    `json:"thdPercent"`
+	THDDB       float64    `json:"thdDB"`
+}
+
+// thd measures the total harmonic distortion in an amplitude spectrum: the
+// RMS sum of the harmonics up to numHarmonics relative to the fundamental. If
+// fundamental is zero, the highest peak is taken as the fundamental.
+func (a *analyzer) thd(spectrum []float64, fundamental float64, numHarmonics int) (distortion, error) {
+	if fundamental <= 0 {
+		peaks := a.peaks(spectrum, 1, floorDB)
+		if len(peaks) == 0 {
+			return distortion{}, errors.New("no fundamental found")
+		}
+		fundamental = peaks[0].Frequency
+	}
+	if fundamental >= a.sampleRate/2 {
+		return distortion{}, errors.New("fundamental must be below Nyquist")
+	}
+
+	var (
+		d          distortion
+		sumSquares float64
+	)
+	fundamentalAmplitude := a.band(spectrum, fundamental)
+	if fundamentalAmplitude == 0 {
+		return distortion{}, errors.New("fundamental is silent")
+	}
+	d.Fundamental = harmonic{Order: 1, Frequency: fundamental, Level: toDB(fundamentalAmplitude)}
+
+	for order := 2; order <= numHarmonics; order++ {
+		f := float64(order) * fundamental
+		if f >= a.sampleRate/2 {
+			break
+		}
+		amp := a.band(spectrum, f)
+		sumSquares += amp * amp
+		d.Harmonics = append(d.Harmonics, harmonic{Order: order, Frequency: f, Level: toDB(amp)})
+	}
+
+	d.THD = math.Sqrt(sumSquares) / fundamentalAmplitude
+	d.THDPercent = 100 * d.THD
+	d.THDDB = toDB(d.THD)
+	return d, nil
+}
+
+// band returns the amplitude of a sinusoid at frequency f, summing the power
+// of the bins in the main lobe around it.
+func (a *analyzer) band(spectrum []float64, f float64) float64 {
+	var (
+		center = int(math.Round(f * float64(a.size) / a.sampleRate))
+		sum    float64
+	)
+	for k := center - a.lobe; k <= center+a.lobe; k++ {
+		if k < 0 || k >= len(spectrum) {
+			continue
+		}
+		sum += spectrum[k] * spectrum[k]
+	}
+	return math.Sqrt(sum / a.enbw)
+}
+
+// toDB converts an amplitude to decibels, bounded below by floorDB.
+func toDB(v float64) float64 {
+	return math.Max(floorDB, 20*math.Log10(v))
+}
+
+// powerToDB converts a power to decibels, bounded below by floorDB.
+func powerToDB(v float64) float64 {
+	return math.Max(floorDB, 10*math.Log10(v))
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
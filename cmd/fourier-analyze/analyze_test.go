@@ -0,0 +1,179 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/brettbuddin/fourier/window"
+	"github.com/stretchr/testify/require"
+)
+
+func sine(n int, freq, amp, sampleRate float64) []float64 {
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = amp * math.Sin(2*math.Pi*freq*float64(i)/sampleRate)
+	}
+	return x
+}
+
+func TestAnalyzer_Amplitude(t *testing.T) {
+	for _, name := range window.Names() {
+		t.Run(name, func(t *testing.T) {
+			wf, err := window.ByName(name)
+			require.NoError(t, err)
+			a, err := newAnalyzer(48000, 1024, 0.5, wf)
+			require.NoError(t, err)
+
+			// A sinusoid centered on bin 64 reads its amplitude whatever the
+			// window.
+			spectrum, err := a.spectrum(sine(8192, a.frequency(64), 0.5, 48000))
+			require.NoError(t, err)
+			require.InDelta(t, 0.5, spectrum[64], 1e-3)
+			require.InDelta(t, 0.5, a.band(spectrum, a.frequency(64)), 1e-2)
+
+			// DC
+			dc := make([]float64, 1024)
+			for i := range dc {
+				dc[i] = 0.25
+			}
+			spectrum, err = a.spectrum(dc)
+			require.NoError(t, err)
+			require.InDelta(t, 0.25, spectrum[0], 1e-9)
+		})
+	}
+}
+
+func TestAnalyzer_MainLobe(t *testing.T) {
+	for _, tc := range []struct {
+		wf   window.Func
+		lobe int
+	}{
+		{window.Rectangular, 1},
+		{window.Hann, 2},
+		{window.Blackman, 3},
+	} {
+		a, err := newAnalyzer(48000, 256, 0, tc.wf)
+		require.NoError(t, err)
+		require.Equal(t, tc.lobe, a.lobe)
+	}
+}
+
+func TestAnalyzer_Peaks(t *testing.T) {
+	var (
+		sampleRate = 44100.0
+		x          = sine(44100, 1000, 0.5, sampleRate)
+		y          = sine(44100, 3150.5, 0.05, sampleRate)
+	)
+	for i := range x {
+		x[i] += y[i]
+	}
+
+	a, err := newAnalyzer(int(sampleRate), 8192, 0.5, window.Hann)
+	require.NoError(t, err)
+	spectrum, err := a.spectrum(x)
+	require.NoError(t, err)
+
+	peaks := a.peaks(spectrum, 5, -60)
+	require.Len(t, peaks, 2)
+	require.InDelta(t, 1000, peaks[0].Frequency, 1)
+	require.InDelta(t, 20*math.Log10(0.5), peaks[0].Level, 1.5)
+	require.InDelta(t, 3150.5, peaks[1].Frequency, 1)
+	require.InDelta(t, 20*math.Log10(0.05), peaks[1].Level, 1.5)
+
+	require.Len(t, a.peaks(spectrum, 1, -60), 1)
+}
+
+func TestAnalyzer_THD(t *testing.T) {
+	var (
+		sampleRate = 48000.0
+		x          = sine(48000, 1000, 0.5, sampleRate)
+		h2         = sine(48000, 2000, 0.005, sampleRate)
+		h3         = sine(48000, 3000, 0.0025, sampleRate)
+	)
+	for i := range x {
+		x[i] += h2[i] + h3[i]
+	}
+
+	for _, wf := range []window.Func{window.Hann, window.Blackman} {
+		a, err := newAnalyzer(int(sampleRate), 4096, 0.5, wf)
+		require.NoError(t, err)
+		spectrum, err := a.spectrum(x)
+		require.NoError(t, err)
+
+		d, err := a.thd(spectrum, 0, 5)
+		require.NoError(t, err)
+		require.InDelta(t, 1000, d.Fundamental.Frequency, 1)
+		require.Len(t, d.Harmonics, 4)
+
+		expected := math.Sqrt(0.005*0.005+0.0025*0.0025) / 0.5
+		require.InEpsilon(t, expected, d.THD, 0.05)
+		require.InDelta(t, 100*expected, d.THDPercent, 0.05)
+		require.InDelta(t, 20*math.Log10(0.005), d.Harmonics[0].Level, 0.5)
+	}
+}
+
+func TestAnalyzer_PSD(t *testing.T) {
+	var (
+		sampleRate = 8000.0
+		rng        = rand.New(rand.NewSource(1))
+		x          = make([]float64, 1<<17)
+		variance   = 0.01
+	)
+	for i := range x {
+		x[i] = rng.NormFloat64() * math.Sqrt(variance)
+	}
+
+	a, err := newAnalyzer(int(sampleRate), 512, 0.5, window.Hann)
+	require.NoError(t, err)
+	psd, err := a.psd(x)
+	require.NoError(t, err)
+
+	// The PSD integrates to the variance.
+	var total float64
+	for _, v := range psd {
+		total += v * sampleRate / float64(a.size)
+	}
+	require.InEpsilon(t, variance, total, 0.02)
+
+	// White noise is flat at 2σ²/fs.
+	require.InEpsilon(t, 2*variance/sampleRate, psd[100], 0.3)
+}
+
+func TestAnalyzer_Spectrogram(t *testing.T) {
+	var (
+		sampleRate = 8000.0
+		x          = append(sine(4000, 500, 1, sampleRate), sine(4000, 2000, 1, sampleRate)...)
+	)
+	a, err := newAnalyzer(int(sampleRate), 256, 0, window.Hann)
+	require.NoError(t, err)
+	frames, times, err := a.spectrogram(x)
+	require.NoError(t, err)
+	require.Len(t, frames, 31)
+	require.Len(t, times, 31)
+	require.InDelta(t, 128/sampleRate, times[0], 1e-12)
+
+	loudest := func(frame []float64) float64 {
+		var best int
+		for k, v := range frame {
+			if v > frame[best] {
+				best = k
+			}
+		}
+		return a.frequency(float64(best))
+	}
+	require.Equal(t, 500.0, loudest(frames[0]))
+	require.Equal(t, 2000.0, loudest(frames[30]))
+}
+
+func TestAnalyzer_Errors(t *testing.T) {
+	_, err := newAnalyzer(48000, 1000, 0.5, window.Hann)
+	require.Error(t, err)
+	_, err = newAnalyzer(48000, 1024, 1, window.Hann)
+	require.Error(t, err)
+
+	a, err := newAnalyzer(48000, 1024, 0.5, window.Hann)
+	require.NoError(t, err)
+	_, err = a.thd(make([]float64, a.numBins()), 0, 5)
+	require.Error(t, err)
+}
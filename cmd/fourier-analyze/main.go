@@ -0,0 +1,242 @@
+// Command fourier-analyze measures the spectrum of a WAV or raw PCM file and
+// prints the result as text, CSV or JSON.
+//
+// Usage:
+//
+//	fourier-analyze [flags] -mode spectrum|psd|peaks|thd|spectrogram file
+//
+// The file may be "-" to read standard input. Raw PCM is read with -raw and
+// described with -rate, -channels, -bits and -float. Levels are in dBFS,
+// where a full-scale sine wave reads 0 dBFS, except for the power spectral
+// density, which is in dB relative to full scale squared per Hz.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/brettbuddin/fourier/wav"
+	"github.com/brettbuddin/fourier/window"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "fourier-analyze:", err)
+		os.Exit(1)
+	}
+}
+
+// options holds the parsed command-line flags.
+type options struct {
+	mode, format, output string
+
+	// Input
+	raw             bool
+	rawFormat       wav.Format
+	channel         int
+	start, duration float64
+
+	// Analysis
+	size         int
+	windowName   string
+	overlap      float64
+	numPeaks     int
+	threshold    float64
+	fundamental  float64
+	numHarmonics int
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	var (
+		opts  options
+		flags = flag.NewFlagSet("fourier-analyze", flag.ContinueOnError)
+	)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: fourier-analyze [flags] file")
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&opts.mode, "mode", "spectrum", "analysis: spectrum, psd, peaks, thd or spectrogram")
+	flags.StringVar(&opts.format, "format", "text", "output format: text, csv or json")
+	flags.StringVar(&opts.output, "o", "", "output `file` (default: standard output)")
+	flags.BoolVar(&opts.raw, "raw", false, "read headerless little-endian PCM described by -rate, -channels, -bits and -float")
+	flags.IntVar(&opts.rawFormat.SampleRate, "rate", 48000, "sample rate of raw input")
+	flags.IntVar(&opts.rawFormat.NumChannels, "channels", 1, "number of channels of raw input")
+	flags.IntVar(&opts.rawFormat.BitDepth, "bits", 16, "bit depth of raw input")
+	flags.BoolVar(&opts.rawFormat.Float, "float", false, "raw input is IEEE floating-point")
+	flags.IntVar(&opts.channel, "channel", -1, "channel to analyze, counting from 0 (default: the average of all channels)")
+	flags.Float64Var(&opts.start, "start", 0, "start of the region to analyze, in `seconds`")
+	flags.Float64Var(&opts.duration, "duration", 0, "length of the region to analyze, in `seconds` (default: to the end)")
+	flags.IntVar(&opts.size, "size", 4096, "FFT `size`, a power of two")
+	flags.StringVar(&opts.windowName, "window", "hann", "window function: "+strings.Join(window.Names(), ", "))
+	flags.Float64Var(&opts.overlap, "overlap", 0.5, "overlap between successive frames, as a fraction of the FFT size")
+	flags.IntVar(&opts.numPeaks, "peaks", 10, "maximum number of peaks to report")
+	flags.Float64Var(&opts.threshold, "threshold", -120, "lowest peak level to report, in `dBFS`")
+	flags.Float64Var(&opts.fundamental, "fundamental", 0, "fundamental frequency for THD, in `Hz` (default: the highest peak)")
+	flags.IntVar(&opts.numHarmonics, "harmonics", 10, "highest harmonic included in THD")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("exactly one input file is required")
+	}
+
+	wf, err := window.ByName(opts.windowName)
+	if err != nil {
+		return err
+	}
+	signal, sampleRate, err := load(flags.Arg(0), stdin, opts)
+	if err != nil {
+		return err
+	}
+	a, err := newAnalyzer(sampleRate, opts.size, opts.overlap, wf)
+	if err != nil {
+		return err
+	}
+	r, err := newReporter(opts.format)
+	if err != nil {
+		return err
+	}
+
+	out := stdout
+	if opts.output != "" {
+		file, err := os.Create(opts.output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	switch opts.mode {
+	case "spectrum":
+		spectrum, err := a.spectrum(signal)
+		if err != nil {
+			return err
+		}
+		return r.spectrum(out, a.frequencies(), toDBs(spectrum), "level_dbfs")
+
+	case "psd":
+		psd, err := a.psd(signal)
+		if err != nil {
+			return err
+		}
+		for k, v := range psd {
+			psd[k] = powerToDB(v)
+		}
+		return r.spectrum(out, a.frequencies(), psd, "psd_db_per_hz")
+
+	case "peaks":
+		spectrum, err := a.spectrum(signal)
+		if err != nil {
+			return err
+		}
+		return r.peaks(out, a.peaks(spectrum, opts.numPeaks, opts.threshold))
+
+	case "thd":
+		spectrum, err := a.spectrum(signal)
+		if err != nil {
+			return err
+		}
+		d, err := a.thd(spectrum, opts.fundamental, opts.numHarmonics)
+		if err != nil {
+			return err
+		}
+		return r.thd(out, d)
+
+	case "spectrogram":
+		frames, times, err := a.spectrogram(signal)
+		if err != nil {
+			return err
+		}
+		for _, frame := range frames {
+			toDBs(frame)
+		}
+		return r.spectrogram(out, a.frequencies(), times, frames)
+
+	default:
+		return fmt.Errorf("unknown mode %q", opts.mode)
+	}
+}
+
+// load reads the input file and returns the channel (or mix of channels) to
+// analyze, limited to the region given by the options.
+func load(path string, stdin io.Reader, opts options) ([]float64, int, error) {
+	var r io.Reader = stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	var (
+		d   *wav.Decoder
+		err error
+	)
+	if opts.raw {
+		d, err = wav.NewRawDecoder(r, opts.rawFormat)
+	} else {
+		d, err = wav.NewDecoder(r)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("reading %s: %v", path, err)
+	}
+	samples, err := d.ReadAll()
+	if err != nil {
+		return nil, 0, fmt.Errorf("reading %s: %v", path, err)
+	}
+
+	var (
+		f           = d.Format()
+		numChannels = f.NumChannels
+		numFrames   = len(samples) / numChannels
+		signal      = make([]float64, numFrames)
+	)
+	switch {
+	case opts.channel >= numChannels:
+		return nil, 0, fmt.Errorf("channel %d out of range; the input has %d channels", opts.channel, numChannels)
+	case opts.channel >= 0:
+		for i := range signal {
+			signal[i] = samples[i*numChannels+opts.channel]
+		}
+	default:
+		for i := range signal {
+			var sum float64
+			for c := 0; c < numChannels; c++ {
+				sum += samples[i*numChannels+c]
+			}
+			signal[i] = sum / float64(numChannels)
+		}
+	}
+
+	if opts.start < 0 || opts.duration < 0 {
+		return nil, 0, errors.New("start and duration cannot be negative")
+	}
+	begin := int(opts.start * float64(f.SampleRate))
+	if begin >= len(signal) {
+		return nil, 0, errors.New("start is beyond the end of the input")
+	}
+	signal = signal[begin:]
+	if opts.duration > 0 {
+		if n := int(opts.duration * float64(f.SampleRate)); n < len(signal) {
+			signal = signal[:n]
+		}
+	}
+	return signal, f.SampleRate, nil
+}
+
+// toDBs converts amplitudes to decibels in place.
+func toDBs(v []float64) []float64 {
+	for i := range v {
+		v[i] = toDB(v[i])
+	}
+	return v
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/brettbuddin/fourier/wav"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fourier-analyze")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var (
+		path     = filepath.Join(dir, "tone.wav")
+		left     = sine(48000, 1000, 0.5, 48000)
+		right    = sine(48000, 440, 0.25, 48000)
+		samples  = wav.Interleave([][]float64{left, right})
+		stdout   bytes.Buffer
+		stderr   bytes.Buffer
+		analysis = func(args ...string) []byte {
+			stdout.Reset()
+			require.NoError(t, run(args, nil, &stdout, &stderr))
+			return stdout.Bytes()
+		}
+	)
+	require.NoError(t, wav.WriteFile(path, samples, wav.Format{SampleRate: 48000, NumChannels: 2, BitDepth: 24}))
+
+	// Peaks of one channel as JSON
+	var peaks struct {
+		Peaks []peak `json:"peaks"`
+	}
+	require.NoError(t, json.Unmarshal(analysis("-mode", "peaks", "-format", "json", "-channel", "1", "-threshold", "-40", path), &peaks))
+	require.Len(t, peaks.Peaks, 1)
+	require.InDelta(t, 440, peaks.Peaks[0].Frequency, 1)
+	require.InDelta(t, 20*math.Log10(0.25), peaks.Peaks[0].Level, 1.5)
+
+	// Spectrum of the mix as CSV
+	rows, err := csv.NewReader(bytes.NewReader(analysis("-format", "csv", "-size", "1024", "-window", "blackman", path))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 514)
+	require.Equal(t, []string{"frequency_hz", "level_dbfs"}, rows[0])
+
+	// Spectrogram as JSON
+	var spectrogram struct {
+		Frequencies []float64   `json:"frequencies"`
+		Times       []float64   `json:"times"`
+		Levels      [][]float64 `json:"levels"`
+	}
+	require.NoError(t, json.Unmarshal(analysis("-mode", "spectrogram", "-format", "json", "-size", "256", "-duration", "0.1", path), &spectrogram))
+	require.Len(t, spectrogram.Frequencies, 129)
+	require.Len(t, spectrogram.Levels, len(spectrogram.Times))
+	require.Len(t, spectrogram.Levels[0], 129)
+
+	// PSD and text output
+	text := string(analysis("-mode", "psd", "-size", "64", path))
+	require.True(t, strings.HasPrefix(strings.TrimSpace(text), "frequency_hz"))
+	require.Len(t, strings.Split(strings.TrimSpace(text), "\n"), 34)
+
+	// Output to a file
+	out := filepath.Join(dir, "thd.txt")
+	analysis("-mode", "thd", "-channel", "0", "-o", out, path)
+	report, err := ioutil.ReadFile(out)
+	require.NoError(t, err)
+	require.Contains(t, string(report), "THD:")
+}
+
+func TestRun_Raw(t *testing.T) {
+	var (
+		fundamental = sine(48000, 1000, 0.5, 48000)
+		harmonic    = sine(48000, 2000, 0.05, 48000)
+		raw         bytes.Buffer
+		stdout      bytes.Buffer
+	)
+	for i, v := range fundamental {
+		binary.Write(&raw, binary.LittleEndian, float32(v+harmonic[i]))
+	}
+
+	require.NoError(t, run([]string{"-raw", "-bits", "32", "-float", "-mode", "thd", "-format", "csv", "-"}, &raw, &stdout, ioutil.Discard))
+	rows, err := csv.NewReader(&stdout).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, "thd_percent", rows[0][2])
+
+	thd, err := strconv.ParseFloat(rows[1][2], 64)
+	require.NoError(t, err)
+	require.InDelta(t, 10, thd, 0.2)
+}
+
+func TestRun_Errors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fourier-analyze")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "tone.wav")
+	require.NoError(t, wav.WriteFile(path, sine(1000, 1000, 0.5, 48000), wav.Format{SampleRate: 48000, NumChannels: 1, BitDepth: 16}))
+
+	for _, args := range [][]string{
+		{},
+		{"-mode", "cepstrum", path},
+		{"-format", "xml", path},
+		{"-window", "kaiser", path},
+		{"-size", "1000", path},
+		{"-channel", "1", path},
+		{"-start", "1", path},
+		{filepath.Join(dir, "missing.wav")},
+	} {
+		require.Error(t, run(args, nil, ioutil.Discard, ioutil.Discard), "%v", args)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+// reporter writes analysis results in one output format.
+type reporter interface {
+	spectrum(w io.Writer, frequencies, levels []float64, unit string) error
+	peaks(w io.Writer, peaks []peak) error
+	thd(w io.Writer, d distortion) error
+	spectrogram(w io.Writer, frequencies, times []float64, frames [][]float64) error
+}
+
+// newReporter returns the reporter for a format name.
+func newReporter(format string) (reporter, error) {
+	switch format {
+	case "text":
+		return textReporter{}, nil
+	case "csv":
+		return csvReporter{}, nil
+	case "json":
+		return jsonReporter{}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+// textReporter writes aligned, human-readable tables.
+type textReporter struct{}
+
+func (textReporter) spectrum(w io.Writer, frequencies, levels []float64, unit string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "frequency_hz\t%s\t\n", unit)
+	for k, f := range frequencies {
+		fmt.Fprintf(tw, "%.2f\t%.2f\t\n", f, levels[k])
+	}
+	return tw.Flush()
+}
+
+func (textReporter) peaks(w io.Writer, peaks []peak) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "frequency_hz\tlevel_dbfs\t\n")
+	for _, p := range peaks {
+		fmt.Fprintf(tw, "%.2f\t%.2f\t\n", p.Frequency, p.Level)
+	}
+	return tw.Flush()
+}
+
+func (textReporter) thd(w io.Writer, d distortion) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "harmonic\tfrequency_hz\tlevel_dbfs\t\n")
+	for _, h := range append([]harmonic{d.Fundamental}, d.Harmonics...) {
+		fmt.Fprintf(tw, "%d\t%.2f\t%.2f\t\n", h.Order, h.Frequency, h.Level)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "THD: %.4f%% (%.2f dB)\n", d.THDPercent, d.THDDB)
+	return err
+}
+
+func (textReporter) spectrogram(w io.Writer, frequencies, times []float64, frames [][]float64) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "time_s\\frequency_hz\t")
+	for _, f := range frequencies {
+		fmt.Fprintf(tw, "%.2f\t", f)
+	}
+	fmt.Fprintln(tw)
+	for i, frame := range frames {
+		fmt.Fprintf(tw, "%.4f\t", times[i])
+		for _, v := range frame {
+			fmt.Fprintf(tw, "%.2f\t", v)
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+// csvReporter writes comma-separated values with a header row.
+type csvReporter struct{}
+
+func (csvReporter) spectrum(w io.Writer, frequencies, levels []float64, unit string) error {
+	rows := [][]string{{"frequency_hz", unit}}
+	for k, f := range frequencies {
+		rows = append(rows, []string{formatFloat(f), formatFloat(levels[k])})
+	}
+	return csv.NewWriter(w).WriteAll(rows)
+}
+
+func (csvReporter) peaks(w io.Writer, peaks []peak) error {
+	rows := [][]string{{"frequency_hz", "level_dbfs"}}
+	for _, p := range peaks {
+		rows = append(rows, []string{formatFloat(p.Frequency), formatFloat(p.Level)})
+	}
+	return csv.NewWriter(w).WriteAll(rows)
+}
+
+func (csvReporter) thd(w io.Writer, d distortion) error {
+	return csv.NewWriter(w).WriteAll([][]string{
+		{"fundamental_hz", "fundamental_dbfs", "thd_percent", "thd_db"},
+		{formatFloat(d.Fundamental.Frequency), formatFloat(d.Fundamental.Level), formatFloat(d.THDPercent), formatFloat(d.THDDB)},
+	})
+}
+
+func (csvReporter) spectrogram(w io.Writer, frequencies, times []float64, frames [][]float64) error {
+	cw := csv.NewWriter(w)
+	header := []string{"time_s"}
+	for _, f := range frequencies {
+		header = append(header, formatFloat(f))
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	row := make([]string, len(header))
+	for i, frame := range frames {
+		row[0] = formatFloat(times[i])
+		for k, v := range frame {
+			row[k+1] = formatFloat(v)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// jsonReporter writes a single JSON object.
+type jsonReporter struct{}
+
+func (jsonReporter) spectrum(w io.Writer, frequencies, levels []float64, unit string) error {
+	return writeJSON(w, struct {
+		Unit        string    `json:"unit"`
+		Frequencies []float64 `json:"frequencies"`
+		Levels      []float64 `json:"levels"`
+	}{unit, frequencies, levels})
+}
+
+func (jsonReporter) peaks(w io.Writer, peaks []peak) error {
+	if peaks == nil {
+		peaks = []peak{}
+	}
+	return writeJSON(w, struct {
+		Peaks []peak `json:"peaks"`
+	}{peaks})
+}
+
+func (jsonReporter) thd(w io.Writer, d distortion) error {
+	if d.Harmonics == nil {
+		d.Harmonics = []harmonic{}
+	}
+	return writeJSON(w, d)
+}
+
+func (jsonReporter) spectrogram(w io.Writer, frequencies, times []float64, frames [][]float64) error {
+	return writeJSON(w, struct {
+		Frequencies []float64   `json:"frequencies"`
+		Times       []float64   `json:"times"`
+		Levels      [][]float64 `json:"levels"`
+	}{frequencies, times, frames})
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,262 @@
+// Command fourier-convolve convolves a WAV file with one or more impulse
+// responses and writes the result, including the full tail, to a new WAV file.
+//
+// Usage:
+//
+//	fourier-convolve [flags] -o output.wav input.wav ir.wav [ir.wav ...]
+//
+// The channels of the impulse response files are taken together, in order. A
+// single impulse response channel is applied to every input channel;
+// otherwise there must be one impulse response channel per input channel.
+// Impulse responses recorded at a different sample rate from the input are
+// resampled to match, keeping their gain.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/brettbuddin/fourier"
+	"github.com/brettbuddin/fourier/ir"
+	"github.com/brettbuddin/fourier/resample"
+	"github.com/brettbuddin/fourier/wav"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "fourier-convolve:", err)
+		os.Exit(1)
+	}
+}
+
+// options holds the parsed command-line flags.
+type options struct {
+	output     string
+	blockSize  int
+	wet, dry   float64
+	normalize  string
+	outputPeak string
+	bits       int
+	float      bool
+}
+
+func run(args []string, stderr io.Writer) error {
+	var (
+		opts  options
+		flags = flag.NewFlagSet("fourier-convolve", flag.ContinueOnError)
+	)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: fourier-convolve [flags] -o output.wav input.wav ir.wav [ir.wav ...]")
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&opts.output, "o", "", "output WAV `file` (required)")
+	flags.IntVar(&opts.blockSize, "block", 512, "processing block `size` in samples")
+	flags.Float64Var(&opts.wet, "wet", 1, "linear `gain` of the convolved signal")
+	flags.Float64Var(&opts.dry, "dry", 0, "linear `gain` of the unprocessed signal")
+	flags.StringVar(&opts.normalize, "normalize", "none", "impulse response normalization: none, peak or energy (unity gain for broadband material)")
+	flags.StringVar(&opts.outputPeak, "output-peak", "", "scale the output so its peak is at this `dBFS` level (default: no scaling)")
+	flags.IntVar(&opts.bits, "bits", 0, "output bit depth: 8, 16, 24 or 32, or 32 or 64 with -float (default: same as input)")
+	flags.BoolVar(&opts.float, "float", false, "write IEEE floating-point samples; -float=false writes integer PCM (default: same as input)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if opts.output == "" || flags.NArg() < 2 {
+		flags.Usage()
+		return errors.New("an output file, an input file and at least one impulse response are required")
+	}
+	if opts.blockSize <= 0 {
+		return errors.New("block size must be positive")
+	}
+
+	input, format, err := wav.ReadFile(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("reading %s: %v", flags.Arg(0), err)
+	}
+	irs, err := loadImpulseResponses(flags.Args()[1:], format, opts.normalize, stderr)
+	if err != nil {
+		return err
+	}
+	if len(irs) != 1 && len(irs) != format.NumChannels {
+		return fmt.Errorf("%d impulse response channels for %d input channels", len(irs), format.NumChannels)
+	}
+
+	// -float only overrides the input's sample type when it's given.
+	var float *bool
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "float" {
+			float = &opts.float
+		}
+	})
+	outFormat, err := outputFormat(format, opts.bits, float)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	output, err := convolve(input, irs, format.NumChannels, opts)
+	if err != nil {
+		return err
+	}
+	elapsed := time.Since(start)
+
+	peak := ir.Peak(output)
+	if opts.outputPeak != "" {
+		db, err := strconv.ParseFloat(opts.outputPeak, 64)
+		if err != nil {
+			return fmt.Errorf("invalid output peak %q", opts.outputPeak)
+		}
+		ir.NormalizePeak(output, math.Pow(10, db/20))
+		peak = ir.Peak(output)
+	}
+
+	if err := wav.WriteFile(opts.output, output, outFormat); err != nil {
+		return fmt.Errorf("writing %s: %v", opts.output, err)
+	}
+
+	var (
+		numFrames = len(output) / format.NumChannels
+		duration  = time.Duration(float64(numFrames) / float64(format.SampleRate) * float64(time.Second))
+	)
+	fmt.Fprintf(stderr, "wrote %s: %d frames (%s), peak %.2f dBFS\n", opts.output, numFrames, duration.Round(time.Millisecond), 20*math.Log10(peak))
+	fmt.Fprintf(stderr, "processed in %s (%.1fx real time)\n", elapsed.Round(time.Millisecond), duration.Seconds()/elapsed.Seconds())
+	if peak > 1 && !outFormat.Float {
+		fmt.Fprintln(stderr, "warning: output clipped; use -output-peak or -float")
+	}
+	return nil
+}
+
+// loadImpulseResponses reads the channels of each impulse response file,
+// resampling them to the input's sample rate and normalizing them.
+func loadImpulseResponses(paths []string, input wav.Format, normalize string, stderr io.Writer) ([][]float64, error) {
+	var irs [][]float64
+	for _, path := range paths {
+		samples, format, err := wav.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %v", path, err)
+		}
+
+		for _, ch := range wav.Deinterleave(samples, format.NumChannels) {
+			if format.SampleRate != input.SampleRate {
+				ch, err = resampleIR(ch, format.SampleRate, input.SampleRate)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if len(ch) == 0 {
+				return nil, fmt.Errorf("%s is empty", path)
+			}
+			irs = append(irs, ch)
+		}
+		if format.SampleRate != input.SampleRate {
+			fmt.Fprintf(stderr, "resampled %s from %d Hz to %d Hz\n", path, format.SampleRate, input.SampleRate)
+		}
+	}
+
+	for _, ch := range irs {
+		switch normalize {
+		case "none":
+		case "peak":
+			ir.NormalizePeak(ch, 1)
+		case "energy":
+			ir.NormalizeEnergy(ch, 1)
+		default:
+			return nil, fmt.Errorf("unknown normalization %q", normalize)
+		}
+	}
+	return irs, nil
+}
+
+// resampleIR converts an impulse response from one sample rate to another.
+// Resampling keeps the amplitude of the samples while changing how many there
+// are, so the result is scaled by inRate/outRate to keep the response's gain.
+func resampleIR(h []float64, inRate, outRate int) ([]float64, error) {
+	out, err := resample.Resample(h, float64(inRate), float64(outRate), resample.High)
+	if err != nil {
+		return nil, err
+	}
+	scale := float64(inRate) / float64(outRate)
+	for i := range out {
+		out[i] *= scale
+	}
+	return out, nil
+}
+
+// outputFormat returns the format of the output file. If float is nil, the
+// sample type is the same as the input's unless bits calls for another.
+func outputFormat(input wav.Format, bits int, float *bool) (wav.Format, error) {
+	f := input
+	if float != nil && *float != input.Float {
+		f.Float = *float
+		f.BitDepth = 24
+		if f.Float {
+			f.BitDepth = 32
+		}
+	}
+	if bits != 0 {
+		f.BitDepth = bits
+
+		// Only 32-bit samples can be either type, so the depth settles the
+		// type unless -float is given.
+		if float == nil {
+			f.Float = bits == 64 || (bits == 32 && input.Float)
+		}
+	}
+	if f.Float && f.BitDepth != 32 && f.BitDepth != 64 {
+		return f, fmt.Errorf("unsupported floating-point bit depth %d", f.BitDepth)
+	}
+	if !f.Float && f.BitDepth != 8 && f.BitDepth != 16 && f.BitDepth != 24 && f.BitDepth != 32 {
+		return f, fmt.Errorf("unsupported bit depth %d", f.BitDepth)
+	}
+	return f, nil
+}
+
+// convolve runs each channel of the interleaved input through its own
+// Convolver and returns the interleaved output, including the full tail.
+func convolve(input []float64, irs [][]float64, numChannels int, opts options) ([]float64, error) {
+	var (
+		numFrames = len(input) / numChannels
+		convs     = make([]*fourier.Convolver, numChannels)
+		tail      int
+	)
+	for c := range convs {
+		response := irs[0]
+		if len(irs) > 1 {
+			response = irs[c]
+		}
+		conv, err := fourier.NewConvolver(opts.blockSize, response,
+			fourier.ForChannel(c, numChannels),
+			fourier.NonUniform(true),
+			fourier.WetGain(opts.wet),
+			fourier.DryGain(opts.dry),
+			fourier.MaxIRLength(0),
+		)
+		if err != nil {
+			return nil, err
+		}
+		convs[c] = conv
+		if n := conv.Latency() + conv.TailLength(); n > tail {
+			tail = n
+		}
+	}
+
+	output := make([]float64, (numFrames+tail)*numChannels)
+	for _, conv := range convs {
+		if err := conv.Convolve(output, input, numFrames); err != nil {
+			return nil, err
+		}
+
+		// The output has room for the longest tail, so one call drains it.
+		if _, err := conv.Flush(output[numFrames*numChannels:]); err != nil && err != io.EOF {
+			return nil, err
+		}
+	}
+	return output, nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/brettbuddin/fourier/wav"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fourier-convolve")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var (
+		inputPath = filepath.Join(dir, "input.wav")
+		leftPath  = filepath.Join(dir, "left.wav")
+		rightPath = filepath.Join(dir, "right.wav")
+		outPath   = filepath.Join(dir, "out.wav")
+
+		format = wav.Format{SampleRate: 48000, NumChannels: 2, BitDepth: 32, Float: true}
+		input  = make([]float64, 2*1000)
+		left   = make([]float64, 3000)
+		right  = []float64{0.5, 0.25}
+	)
+	for i := range input {
+		input[i] = 0.5 * math.Sin(float64(i)*0.01)
+	}
+	for i := range left {
+		left[i] = 0.1 * math.Exp(-float64(i)/500)
+	}
+	require.NoError(t, wav.WriteFile(inputPath, input, format))
+	require.NoError(t, wav.WriteFile(leftPath, left, wav.Format{SampleRate: 48000, NumChannels: 1, BitDepth: 64, Float: true}))
+	require.NoError(t, wav.WriteFile(rightPath, right, wav.Format{SampleRate: 48000, NumChannels: 1, BitDepth: 64, Float: true}))
+
+	var stderr bytes.Buffer
+	require.NoError(t, run([]string{"-o", outPath, "-block", "64", "-dry", "0.5", inputPath, leftPath, rightPath}, &stderr))
+	require.Contains(t, stderr.String(), "peak")
+
+	output, outFormat, err := wav.ReadFile(outPath)
+	require.NoError(t, err)
+	require.Equal(t, format, outFormat)
+
+	channels := wav.Deinterleave(input, 2)
+	expected := [][]float64{
+		directConvolve(channels[0], left),
+		directConvolve(channels[1], right),
+	}
+	for c := range expected {
+		for i := range channels[c] {
+			expected[c][i] += 0.5 * channels[c][i]
+		}
+	}
+
+	outChannels := wav.Deinterleave(output, 2)
+	require.Len(t, outChannels[0], len(expected[0]))
+	for c := range expected {
+		for i, v := range expected[c] {
+			require.InDelta(t, v, outChannels[c][i], 1e-6, "channel %d sample %d", c, i)
+		}
+	}
+
+	// Normalizing the output and changing its format
+	require.NoError(t, run([]string{"-o", outPath, "-bits", "16", "-output-peak", "-6", inputPath, rightPath}, &stderr))
+	output, outFormat, err = wav.ReadFile(outPath)
+	require.NoError(t, err)
+	require.Equal(t, 16, outFormat.BitDepth)
+	require.False(t, outFormat.Float)
+
+	var peak float64
+	for _, v := range output {
+		peak = math.Max(peak, math.Abs(v))
+	}
+	require.InDelta(t, math.Pow(10, -6.0/20), peak, 1e-4)
+}
+
+func TestRun_ResampledIR(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fourier-convolve")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var (
+		inputPath = filepath.Join(dir, "input.wav")
+		irPath    = filepath.Join(dir, "ir.wav")
+		outPath   = filepath.Join(dir, "out.wav")
+		input     = make([]float64, 8000)
+		h         = make([]float64, 1000)
+		gain      float64
+	)
+	for i := range input {
+		input[i] = 0.25
+	}
+	for i := range h {
+		h[i] = 0.01 * math.Exp(-float64(i)/100)
+		gain += h[i]
+	}
+	require.NoError(t, wav.WriteFile(inputPath, input, wav.Format{SampleRate: 48000, NumChannels: 1, BitDepth: 64, Float: true}))
+	require.NoError(t, wav.WriteFile(irPath, h, wav.Format{SampleRate: 24000, NumChannels: 1, BitDepth: 64, Float: true}))
+
+	var stderr bytes.Buffer
+	require.NoError(t, run([]string{"-o", outPath, inputPath, irPath}, &stderr))
+	require.Contains(t, stderr.String(), "resampled")
+
+	// Once the response has settled, a constant input comes out at the
+	// response's gain at DC, whatever the sample rate it was recorded at.
+	output, _, err := wav.ReadFile(outPath)
+	require.NoError(t, err)
+	require.InEpsilon(t, 0.25*gain, output[6000], 0.01)
+}
+
+func TestRun_Errors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fourier-convolve")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var (
+		inputPath = filepath.Join(dir, "input.wav")
+		irPath    = filepath.Join(dir, "ir.wav")
+		outPath   = filepath.Join(dir, "out.wav")
+		stderr    bytes.Buffer
+	)
+	require.NoError(t, wav.WriteFile(inputPath, make([]float64, 30), wav.Format{SampleRate: 44100, NumChannels: 3, BitDepth: 16}))
+	require.NoError(t, wav.WriteFile(irPath, make([]float64, 20), wav.Format{SampleRate: 44100, NumChannels: 2, BitDepth: 16}))
+
+	require.Error(t, run([]string{inputPath, irPath}, &stderr))
+	require.Error(t, run([]string{"-o", outPath, inputPath, irPath}, &stderr))
+	require.Error(t, run([]string{"-o", outPath, "-normalize", "loud", inputPath, irPath, irPath}, &stderr))
+	require.Error(t, run([]string{"-o", outPath, "-bits", "12", inputPath, irPath, irPath}, &stderr))
+}
+
+// directConvolve computes the full linear convolution of two signals.
+func directConvolve(x, h []float64) []float64 {
+	y := make([]float64, len(x)+len(h)-1)
+	for i, xv := range x {
+		for j, hv := range h {
+			y[i+j] += xv * hv
+		}
+	}
+	return y
+}
@@ -0,0 +1,208 @@
+// Command fourier-filter designs a windowed-sinc FIR filter with the filter
+// package and writes its coefficients as Go source, a C header, CSV, JSON or a
+// WAV impulse response. A table of the filter's frequency response is printed
+// to standard error.
+//
+// Usage:
+//
+//	fourier-filter [flags] -type lowpass -cutoff 1000
+//	fourier-filter [flags] -type bandpass -cutoff 300,3000
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/brettbuddin/fourier/filter"
+	"github.com/brettbuddin/fourier/window"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "fourier-filter:", err)
+		os.Exit(1)
+	}
+}
+
+// design describes a filter.
+type design struct {
+	Type       string    `json:"type"`
+	SampleRate float64   `json:"sampleRate"`
+	Cutoffs    []float64 `json:"cutoffs"`
+	Window     string    `json:"window"`
+	Taps       int       `json:"taps"`
+}
+
+func (d design) String() string {
+	cutoffs := make([]string, len(d.Cutoffs))
+	for i, c := range d.Cutoffs {
+		cutoffs[i] = strconv.FormatFloat(c, 'g', -1, 64)
+	}
+	return fmt.Sprintf("%s, %d taps, %s window, cutoff %s Hz at %g Hz",
+		d.Type, d.Taps, d.Window, strings.Join(cutoffs, "-"), d.SampleRate)
+}
+
+// options holds the parsed command-line flags.
+type options struct {
+	design
+	cutoffs        string
+	format, output string
+	name, pkg      string
+	ctype          string
+	points         int
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func run(args []string, stdout, stderr io.Writer) error {
+	var (
+		opts  options
+		flags = flag.NewFlagSet("fourier-filter", flag.ContinueOnError)
+	)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: fourier-filter [flags] -type type -cutoff hz[,hz]")
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&opts.Type, "type", "lowpass", "filter type: lowpass, highpass, bandpass or bandreject")
+	flags.StringVar(&opts.cutoffs, "cutoff", "", "cutoff frequency in `Hz`, or two comma-separated band edges for bandpass and bandreject")
+	flags.Float64Var(&opts.SampleRate, "rate", 48000, "sample rate in `Hz`")
+	flags.IntVar(&opts.Taps, "taps", 101, "number of coefficients")
+	flags.StringVar(&opts.Window, "window", "blackman", "window function: "+strings.Join(window.Names(), ", "))
+	flags.StringVar(&opts.format, "format", "csv", "output format: go, c, csv, json or wav")
+	flags.StringVar(&opts.output, "o", "", "output `file` (default: standard output)")
+	flags.StringVar(&opts.name, "name", "", "identifier for go and c output (default: the filter type)")
+	flags.StringVar(&opts.pkg, "package", "main", "package name for go output")
+	flags.StringVar(&opts.ctype, "ctype", "float", "element type for c output: float or double")
+	flags.IntVar(&opts.points, "points", 16, "number of points in the frequency response table; 0 disables it")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return errors.New("unexpected arguments")
+	}
+
+	if err := opts.parse(); err != nil {
+		return err
+	}
+	h, err := makeFilter(opts.design)
+	if err != nil {
+		return err
+	}
+
+	if opts.output == "" {
+		err = write(stdout, h, opts)
+	} else {
+		err = writeFile(opts.output, h, opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	if opts.points > 0 {
+		return printResponse(stderr, h, opts.design, opts.points)
+	}
+	return nil
+}
+
+// parse validates the flags and fills in the design.
+func (o *options) parse() error {
+	if o.SampleRate <= 0 {
+		return errors.New("sample rate must be positive")
+	}
+	if o.Taps <= 0 {
+		return errors.New("number of taps must be positive")
+	}
+	if _, err := window.ByName(o.Window); err != nil {
+		return err
+	}
+
+	if _, ok := types[o.Type]; !ok {
+		return fmt.Errorf("unknown filter type %q", o.Type)
+	}
+	if o.cutoffs == "" {
+		return errors.New("a cutoff frequency is required")
+	}
+	for _, field := range strings.Split(o.cutoffs, ",") {
+		f, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return fmt.Errorf("invalid cutoff %q", field)
+		}
+		o.Cutoffs = append(o.Cutoffs, f)
+	}
+
+	if o.name == "" {
+		o.name = o.Type
+	}
+	if !identifier.MatchString(o.name) {
+		return fmt.Errorf("%q is not a valid identifier", o.name)
+	}
+	if !identifier.MatchString(o.pkg) {
+		return fmt.Errorf("%q is not a valid package name", o.pkg)
+	}
+	if o.ctype != "float" && o.ctype != "double" {
+		return fmt.Errorf("unknown C type %q", o.ctype)
+	}
+	return nil
+}
+
+// types maps the names of filter types to the filter package's.
+var types = map[string]filter.Type{
+	"lowpass":    filter.LowPass,
+	"highpass":   filter.HighPass,
+	"bandpass":   filter.BandPass,
+	"bandreject": filter.BandReject,
+}
+
+// makeFilter builds the filter kernel for a design.
+func makeFilter(d design) ([]float64, error) {
+	wf, err := window.ByName(d.Window)
+	if err != nil {
+		return nil, err
+	}
+	return filter.Design(filter.Spec{
+		Type:       types[d.Type],
+		SampleRate: d.SampleRate,
+		Cutoffs:    d.Cutoffs,
+		Window:     wf,
+	}, d.Taps)
+}
+
+// printResponse prints the filter's gain at evenly spaced frequencies from DC
+// to Nyquist, and at each cutoff.
+func printResponse(w io.Writer, h []float64, d design, points int) error {
+	// The filter package takes frequencies as fractions of the sample rate.
+	var freqs []float64
+	for i := 0; i < points; i++ {
+		var f float64
+		if points > 1 {
+			f = float64(i) / float64(points-1) / 2
+		}
+		freqs = append(freqs, f)
+	}
+	for _, c := range d.Cutoffs {
+		freqs = append(freqs, c/d.SampleRate)
+	}
+	r, err := filter.ResponseAt(h, nil, freqs)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "%s\n\n", d)
+	fmt.Fprintf(w, "%14s  %10s  %10s\n", "frequency_hz", "gain", "gain_db")
+	for k, gain := range r.Magnitude() {
+		if k == points {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%14.2f  %10.6f  %10.2f\n", freqs[k]*d.SampleRate, gain, 20*math.Log10(math.Max(gain, 1e-10)))
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/brettbuddin/fourier/filter"
+	"github.com/brettbuddin/fourier/wav"
+	"github.com/brettbuddin/fourier/window"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	var (
+		stdout, stderr bytes.Buffer
+		generate       = func(args ...string) []byte {
+			stdout.Reset()
+			stderr.Reset()
+			require.NoError(t, run(args, &stdout, &stderr))
+			return stdout.Bytes()
+		}
+	)
+	expected, err := filter.Design(filter.Spec{
+		Type:       filter.LowPass,
+		SampleRate: 44100,
+		Cutoffs:    []float64{1000},
+		Window:     window.Hamming,
+	}, 63)
+	require.NoError(t, err)
+
+	// JSON
+	var result struct {
+		design
+		Coefficients []float64 `json:"coefficients"`
+	}
+	require.NoError(t, json.Unmarshal(generate("-cutoff", "1000", "-rate", "44100", "-taps", "63", "-window", "hamming", "-format", "json"), &result))
+	require.Equal(t, "lowpass", result.Type)
+	require.Equal(t, []float64{1000}, result.Cutoffs)
+	require.Equal(t, expected, result.Coefficients)
+
+	// The response table covers DC to Nyquist, then the cutoff.
+	table := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+	require.Contains(t, table[0], "lowpass, 63 taps, hamming window, cutoff 1000 Hz at 44100 Hz")
+	require.Len(t, table, 2+17+2)
+	require.Equal(t, "0.00", strings.Fields(table[3])[0])
+	require.Equal(t, "22050.00", strings.Fields(table[18])[0])
+	require.Equal(t, "1000.00", strings.Fields(table[20])[0])
+
+	// CSV
+	rows, err := csv.NewReader(bytes.NewReader(generate("-type", "bandpass", "-cutoff", "300, 3000", "-format", "csv", "-points", "0"))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 102)
+	require.Equal(t, []string{"index", "coefficient"}, rows[0])
+	require.Empty(t, stderr.String())
+
+	// Go
+	src := generate("-type", "highpass", "-cutoff", "500", "-format", "go", "-name", "HighPass", "-package", "dsp")
+	file, err := parser.ParseFile(token.NewFileSet(), "highpass.go", src, parser.ParseComments)
+	require.NoError(t, err)
+	require.Equal(t, "dsp", file.Name.Name)
+	require.NotNil(t, file.Scope.Lookup("HighPass"))
+
+	// C
+	header := string(generate("-type", "bandreject", "-cutoff", "50,60", "-format", "c", "-ctype", "double", "-taps", "11"))
+	require.Contains(t, header, "#define BANDREJECT_TAPS 11")
+	require.Contains(t, header, "static const double bandreject[BANDREJECT_TAPS] = {")
+	require.Equal(t, 11, strings.Count(header, "e-")+strings.Count(header, "e+"))
+
+	// WAV
+	dir, err := ioutil.TempDir("", "fourier-filter")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "lowpass.wav")
+	generate("-cutoff", "1000", "-rate", "44100", "-taps", "63", "-window", "hamming", "-format", "wav", "-o", path)
+	samples, format, err := wav.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 44100, format.SampleRate)
+	require.InDeltaSlice(t, expected, samples, 1e-7)
+}
+
+func TestRun_Errors(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"-cutoff", "30000"},
+		{"-cutoff", "0"},
+		{"-cutoff", "1000,2000"},
+		{"-type", "bandpass", "-cutoff", "1000"},
+		{"-type", "bandpass", "-cutoff", "2000,1000"},
+		{"-type", "notch", "-cutoff", "1000"},
+		{"-cutoff", "1000", "-taps", "0"},
+		{"-type", "highpass", "-cutoff", "1000", "-taps", "100"},
+		{"-cutoff", "1000", "-window", "kaiser"},
+		{"-cutoff", "1000", "-format", "xml"},
+		{"-cutoff", "1000", "-name", "2fast"},
+		{"-cutoff", "1000", "-ctype", "int"},
+		{"-cutoff", "1000", "extra"},
+	} {
+		require.Error(t, run(args, ioutil.Discard, ioutil.Discard), "%v", args)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/brettbuddin/fourier/wav"
+)
+
+// write writes the coefficients in the format given by the options.
+func write(w io.Writer, h []float64, opts options) error {
+	switch opts.format {
+	case "go":
+		return writeGo(w, h, opts)
+	case "c":
+		return writeC(w, h, opts)
+	case "csv":
+		return writeCSV(w, h)
+	case "json":
+		return writeJSON(w, h, opts.design)
+	case "wav":
+		return writeWAV(w, h, opts.design)
+	default:
+		return fmt.Errorf("unknown output format %q", opts.format)
+	}
+}
+
+// writeFile writes the kernel to a file, creating or truncating it. The error
+// from closing the file is returned, so a failed flush isn't missed.
+func writeFile(path string, h []float64, opts options) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file, h, opts); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func writeGo(w io.Writer, h []float64, opts options) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "// Code generated by fourier-filter; DO NOT EDIT.\n\n")
+	fmt.Fprintf(bw, "package %s\n\n", opts.pkg)
+	fmt.Fprintf(bw, "// %s holds the coefficients of a %s.\n", opts.name, opts.design)
+	fmt.Fprintf(bw, "var %s = []float64{\n", opts.name)
+	for _, v := range h {
+		fmt.Fprintf(bw, "\t%s,\n", strconv.FormatFloat(v, 'g', -1, 64))
+	}
+	fmt.Fprintf(bw, "}\n")
+	return bw.Flush()
+}
+
+func writeC(w io.Writer, h []float64, opts options) error {
+	var (
+		bw     = bufio.NewWriter(w)
+		guard  = strings.ToUpper(opts.name) + "_H"
+		length = strings.ToUpper(opts.name) + "_TAPS"
+	)
+	fmt.Fprintf(bw, "/* Generated by fourier-filter; do not edit. */\n")
+	fmt.Fprintf(bw, "/* %s */\n\n", opts.design)
+	fmt.Fprintf(bw, "#ifndef %s\n#define %s\n\n", guard, guard)
+	fmt.Fprintf(bw, "#define %s %d\n\n", length, len(h))
+	fmt.Fprintf(bw, "static const %s %s[%s] = {\n", opts.ctype, opts.name, length)
+	for _, v := range h {
+		if opts.ctype == "float" {
+			fmt.Fprintf(bw, "    %sf,\n", strconv.FormatFloat(v, 'e', 8, 32))
+		} else {
+			fmt.Fprintf(bw, "    %s,\n", strconv.FormatFloat(v, 'e', 16, 64))
+		}
+	}
+	fmt.Fprintf(bw, "};\n\n#endif /* %s */\n", guard)
+	return bw.Flush()
+}
+
+func writeCSV(w io.Writer, h []float64) error {
+	rows := [][]string{{"index", "coefficient"}}
+	for i, v := range h {
+		rows = append(rows, []string{strconv.Itoa(i), strconv.FormatFloat(v, 'g', -1, 64)})
+	}
+	return csv.NewWriter(w).WriteAll(rows)
+}
+
+func writeJSON(w io.Writer, h []float64, d design) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		design
+		Coefficients []float64 `json:"coefficients"`
+	}{d, h})
+}
+
+// writeWAV writes the coefficients as a mono impulse response. Samples are
+// 32-bit float so that coefficients beyond ±1 aren't clipped.
+func writeWAV(w io.Writer, h []float64, d design) error {
+	e, err := wav.NewEncoder(w, wav.Format{
+		SampleRate:  int(d.SampleRate),
+		NumChannels: 1,
+		BitDepth:    32,
+		Float:       true,
+	})
+	if err != nil {
+		return err
+	}
+	if err := e.Write(h); err != nil {
+		return err
+	}
+	return e.Close()
+}
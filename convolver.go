@@ -3,26 +3,113 @@ package fourier
 import (
 	"errors"
 	"fmt"
+	"io"
 	"math"
+	"sync/atomic"
 )
 
-// Maximum impulse response length of 10 seconds at 96kHz
-const maxIRSamples = 20 * 96000
+// DefaultMaxIRLength is the default maximum impulse response length: 20
+// seconds at 96kHz. See MaxIRLength.
+const DefaultMaxIRLength = 20 * 96000
 
-// Convolver performs partioned convolution using the overlap-add method. It is
-// designed to convolve very long input streams with a FIR filter.
+// Sizes in bytes of the buffer element types, for estimating memory use.
+const (
+	sizeofFloat64    = 8
+	sizeofComplex128 = 16
+)
+
+// IRLengthError is returned when an impulse response is longer than the
+// maximum length configured for a Convolver.
+type IRLengthError struct {
+	Length, Max int
+}
+
+func (e *IRLengthError) Error() string {
+	return fmt.Sprintf("impulse response length %d exceeds maximum of %d", e.Length, e.Max)
+}
+
+// Method is a partitioned convolution method.
+type Method int
+
+// Supported partitioned convolution methods.
+const (
+	// OverlapAdd transforms each input block zero-padded to the FFT size and
+	// carries the tail of each inverse transform over into the next block.
+	OverlapAdd Method = iota
+
+	// OverlapSave transforms a sliding window of the most recent input and
+	// discards the circularly aliased head of each inverse transform. No
+	// overlap needs to be carried between blocks.
+	OverlapSave
+)
+
+// Convolver performs partioned convolution using the overlap-add (or
+// overlap-save) method. It is designed to convolve very long input streams
+// with a FIR filter.
 type Convolver struct {
+	// Mix parameters. Kept first for the alignment of their atomic fields.
+	params mix
+
 	// Sizes
 	blockSize, fftSize int
 
+	// Configuration
+	method               Method
+	nonUniform           bool
+	directHead           bool
+	fixedLatency         bool
+	background           bool
+	maxIRLength          int
+	truncate             bool
+	channel, numChannels int
+
 	// Buffers
-	inputSegments, responseSegments [][]complex128
-	output, temp                    []complex128
-	input, overlap                  []float64
+	input, dry, output, fade []float64
+
+	// dryLine delays the dry signal by the engine's latency so that it lines
+	// up with the wet signal.
+	dryLine []float64
+	dryPos  int
+
+	engine engine
+	worker *tailWorker
+
+	// Impulse response crossfade
+	swapped    *swapRequest
+	swap       atomic.Value
+	next       engine
+	fadePos    int
+	fadeLength int
+
+	// remaining is the number of samples of output that may still be
+	// non-zero if no further input arrives.
+	remaining int
+
+	// truncated is the number of samples truncated from the impulse response
+	// last set with SetImpulseResponse.
+	truncated int
+}
+
+// engine is a strategy for partitioning an impulse response and convolving a
+// mono stream against it.
+type engine interface {
+	// process convolves in against the impulse response and writes the result
+	// to out. Both buffers have the same length, which is never larger than
+	// the Convolver's block size.
+	process(out, in []float64) error
+
+	// latency returns the number of samples the output is delayed by.
+	latency() int
 
-	// Internal state
-	inputSegmentPos, inputPos int
-	channel, numChannels      int
+	// length returns the length of the impulse response.
+	length() int
+
+	// reset clears all input history and pending output.
+	reset()
+
+	// seed copies as much of another engine's input history as this engine
+	// can use, so that it can take over from it mid-stream.
+	seed(from engine) error
 }
 
 // NewConvolver returns a new Convolver.
@@ -32,179 +119,445 @@ type Convolver struct {
 // it's maximum number of samples you plan on processing for each call to
 // Convolve.
 //
-// The length of the impulse response is limited to 1920000 samples (20s * 96kHz).
-// Exceeding this maximum length will result in truncation of the IR.
+// The length of the impulse response is limited to DefaultMaxIRLength samples
+// unless configured otherwise with MaxIRLength. A longer impulse response
+// results in an *IRLengthError, or in truncation if TruncateIR is given.
 func NewConvolver(desiredBlockSize int, ir []float64, opts ...ConvolverOption) (*Convolver, error) {
 	if desiredBlockSize == 0 {
 		return nil, errors.New("block size cannot be zero")
 	}
 
+	c, err := configure(desiredBlockSize, opts)
+	if err != nil {
+		return c, err
+	}
+	c.input = make([]float64, c.blockSize)
+	c.dry = make([]float64, c.blockSize)
+	c.output = make([]float64, c.blockSize)
+	c.fade = make([]float64, c.blockSize)
+	c.dryLine = make([]float64, 0, c.blockSize)
+	c.params.allocate()
+
+	if c.background {
+		c.worker = newTailWorker()
+	}
+
+	if err := c.SetImpulseResponse(ir); err != nil {
+		c.Close()
+		return c, err
+	}
+	return c, nil
+}
+
+// configure returns a Convolver with the options applied and checked but
+// without any buffers allocated.
+func configure(desiredBlockSize int, opts []ConvolverOption) (*Convolver, error) {
 	blockSize, fftSize := calcPartitionSize(desiredBlockSize)
 
 	c := &Convolver{
 		blockSize:   blockSize,
 		fftSize:     fftSize,
 		numChannels: 1,
-		input:       make([]float64, fftSize),
-		overlap:     make([]float64, fftSize),
-		output:      make([]complex128, fftSize),
-		temp:        make([]complex128, fftSize),
+		maxIRLength: DefaultMaxIRLength,
 	}
+	c.params.init()
 
 	for _, opt := range opts {
 		if err := opt(c); err != nil {
 			return c, err
 		}
 	}
+	if c.background && !c.nonUniform {
+		return c, errors.New("background tail processing requires non-uniform partitioning")
+	}
+	return c, nil
+}
+
+// EstimateMemory returns the approximate number of bytes a Convolver created
+// with the same arguments would allocate for an impulse response of irLength
+// samples. It returns an *IRLengthError if the length exceeds the maximum the
+// options allow. While a crossfade started by SwapImpulseResponse is in
+// progress, the memory for both impulse responses is held.
+func EstimateMemory(desiredBlockSize, irLength int, opts ...ConvolverOption) (int, error) {
+	if desiredBlockSize == 0 {
+		return 0, errors.New("block size cannot be zero")
+	}
+	if irLength <= 0 {
+		return 0, errors.New("impulse response length cannot be zero")
+	}
+	c, err := configure(desiredBlockSize, opts)
+	if err != nil {
+		return 0, err
+	}
+	if c.maxIRLength > 0 && irLength > c.maxIRLength {
+		if !c.truncate {
+			return 0, &IRLengthError{Length: irLength, Max: c.maxIRLength}
+		}
+		irLength = c.maxIRLength
+	}
+
+	// Input, dry, output and crossfade buffers
+	size := 4 * c.blockSize * sizeofFloat64
+	if c.params.maxDelay > 0 {
+		size += (c.params.maxDelay + 2) * sizeofFloat64
+	}
 
-	return c, c.SetImpulseResponse(ir)
+	switch {
+	case c.nonUniform:
+		size += nonUniformSize(c.blockSize, irLength, c.directHead, c.background)
+	case c.fixedLatency:
+		size += stageSize(c.blockSize, 0, irLength)
+	default:
+		size += uniformSize(c.blockSize, c.fftSize, irLength)
+	}
+	return size, nil
 }
 
-// SetImpulseResponse sets the impulse response used in convolution.
+// SetImpulseResponse sets the impulse response used in convolution. Input
+// history is discarded, as is any swap scheduled with SwapImpulseResponse that
+// hasn't started yet. To change the impulse response during playback, use
+// PrepareImpulseResponse and SwapImpulseResponse instead.
 func (c *Convolver) SetImpulseResponse(ir []float64) error {
-	if len(ir) == 0 {
-		return errors.New("impulse response length cannot be zero")
+	e, truncated, err := c.newEngine(ir)
+	if err != nil {
+		return err
 	}
+	c.engine = e
+	c.next = nil
+	c.truncated = truncated
+	c.discardSwap()
+	c.resizeDryLine()
+	return nil
+}
 
-	var (
-		fftSize             = c.fftSize
-		blockSize           = c.blockSize
-		fillSize            = fftSize - blockSize
-		irSize              = min(len(ir), maxIRSamples)
-		numResponseSegments = int(irSize/fillSize + 1)
-	)
+// Truncated returns the number of samples that were truncated from the end of
+// the impulse response last set with NewConvolver or SetImpulseResponse. It's
+// only ever non-zero when the Convolver is configured with TruncateIR.
+func (c *Convolver) Truncated() int {
+	return c.truncated
+}
 
-	numInputSegments := numResponseSegments
-	if blockSize <= 128 {
-		numInputSegments = 3 * numResponseSegments
+// ImpulseResponse is an impulse response that has been partitioned and
+// transformed for a particular Convolver. See PrepareImpulseResponse.
+type ImpulseResponse struct {
+	engine    engine
+	truncated int
+
+	// used is set, atomically, once the response has been swapped in.
+	used int32
+}
+
+// swapRequest is an impulse response scheduled with SwapImpulseResponse.
+type swapRequest struct {
+	r          *ImpulseResponse
+	fadeLength int
+}
+
+// Truncated returns the number of samples that were truncated from the end of
+// the impulse response. It's only ever non-zero when the Convolver is
+// configured with TruncateIR.
+func (r *ImpulseResponse) Truncated() int {
+	return r.truncated
+}
+
+// PrepareImpulseResponse partitions and transforms an impulse response for use
+// with SwapImpulseResponse. It allocates, so it should be called away from the
+// audio thread. It's safe to call concurrently with Convolve.
+func (c *Convolver) PrepareImpulseResponse(ir []float64) (*ImpulseResponse, error) {
+	e, truncated, err := c.newEngine(ir)
+	if err != nil {
+		return nil, err
+	}
+	return &ImpulseResponse{engine: e, truncated: truncated}, nil
+}
+
+// SwapImpulseResponse schedules a change to a prepared impulse response. At the
+// start of the next call to Convolve, the new response takes over the input
+// history of the current one and the output crossfades (equal-power) from the
+// current response to the new one over fadeLength samples. Nothing is
+// allocated during the crossfade.
+//
+// Input history is only kept for as long as the current response, so a longer
+// response starts with part of its tail missing. With the overlap-add method,
+// the first block after the swap is also missing the tail of the previous
+// block. The crossfade masks both.
+//
+// It's safe to call concurrently with Convolve. A prepared impulse response can
+// only be swapped in once.
+func (c *Convolver) SwapImpulseResponse(r *ImpulseResponse, fadeLength int) error {
+	if r == nil || r.engine == nil {
+		return errors.New("impulse response has not been prepared")
+	}
+	if fadeLength < 0 {
+		return errors.New("fade length cannot be negative")
+	}
+	if !atomic.CompareAndSwapInt32(&r.used, 0, 1) {
+		return errors.New("impulse response has already been swapped in")
+	}
+	c.swap.Store(&swapRequest{r: r, fadeLength: fadeLength})
+	return nil
+}
+
+// newEngine returns an engine for an impulse response, configured according to
+// the Convolver's options. It also returns the number of samples truncated
+// from the impulse response.
+func (c *Convolver) newEngine(ir []float64) (engine, int, error) {
+	if len(ir) == 0 {
+		return nil, 0, errors.New("impulse response length cannot be zero")
 	}
 
-	// Allocate input segments
-	inputSegments := make([][]complex128, numInputSegments)
-	for i := range inputSegments {
-		inputSegments[i] = make([]complex128, fftSize)
+	var truncated int
+	if c.maxIRLength > 0 && len(ir) > c.maxIRLength {
+		if !c.truncate {
+			return nil, 0, &IRLengthError{Length: len(ir), Max: c.maxIRLength}
+		}
+		truncated = len(ir) - c.maxIRLength
+		ir = ir[:c.maxIRLength]
 	}
 
-	// Allocate frequency response segments
-	responseSegments := make([][]complex128, numResponseSegments)
-	for i := range responseSegments {
-		responseSegments[i] = make([]complex128, fftSize)
+	if c.nonUniform {
+		return newNonUniform(c.blockSize, ir, c.directHead, c.worker), truncated, nil
+	}
+	if c.fixedLatency {
+		return newBuffered(c.blockSize, ir), truncated, nil
+	}
+	return newUniform(c.blockSize, c.fftSize, c.method, ir), truncated, nil
+}
+
+// beginSwap starts a crossfade to an impulse response scheduled with
+// SwapImpulseResponse, if there is one.
+func (c *Convolver) beginSwap() error {
+	req, _ := c.swap.Load().(*swapRequest)
+	if req == nil || req == c.swapped {
+		return nil
 	}
-	loadIR(responseSegments, ir, fillSize)
+	c.swapped = req
+	r := req.r
 
-	c.inputSegments = inputSegments
-	c.responseSegments = responseSegments
+	// A crossfade that's already underway is cut short.
+	if c.next != nil {
+		c.engine = c.next
+	}
 
+	if err := r.engine.seed(c.engine); err != nil {
+		return err
+	}
+	if c.remaining > 0 {
+		c.remaining = max(c.remaining, r.engine.latency()+r.engine.length()-1)
+	}
+	c.next = r.engine
+	c.fadePos = 0
+	c.fadeLength = req.fadeLength
+	if c.fadeLength == 0 {
+		c.endSwap()
+	}
 	return nil
 }
 
+// discardSwap marks any swap scheduled with SwapImpulseResponse as taken, so
+// that it doesn't start.
+func (c *Convolver) discardSwap() {
+	c.swapped, _ = c.swap.Load().(*swapRequest)
+}
+
+// endSwap completes a crossfade.
+func (c *Convolver) endSwap() {
+	c.engine = c.next
+	c.next = nil
+	c.resizeDryLine()
+}
+
+// resizeDryLine matches the length of the dry delay line to the latency of the
+// engine. The latency never exceeds the block size, so this doesn't allocate.
+func (c *Convolver) resizeDryLine() {
+	n := c.engine.latency()
+	if n == len(c.dryLine) {
+		return
+	}
+	if n > cap(c.dryLine) {
+		c.dryLine = make([]float64, n)
+	} else {
+		c.dryLine = c.dryLine[:n]
+		zero(c.dryLine)
+	}
+	c.dryPos = 0
+}
+
+// delayDry pushes a dry sample into the dry delay line and returns the sample
+// it displaces.
+func (c *Convolver) delayDry(v float64) float64 {
+	if len(c.dryLine) == 0 {
+		return v
+	}
+	out := c.dryLine[c.dryPos]
+	c.dryLine[c.dryPos] = v
+	if c.dryPos++; c.dryPos == len(c.dryLine) {
+		c.dryPos = 0
+	}
+	return out
+}
+
 // Convolve convolves an a chunk of input against the loaded impulse response.
 func (c *Convolver) Convolve(out, in []float64, numSamples int) error {
 	var (
-		numResponseSegments = len(c.responseSegments)
-		numInputSegments    = len(c.inputSegments)
 		channel             = c.channel
 		numChannels         = c.numChannels
-		step                = numInputSegments / numResponseSegments
-		fftSize             = c.fftSize
-		blockSize           = c.blockSize
 		numSamplesProcessed = 0
 	)
 
+	if err := c.beginSwap(); err != nil {
+		return err
+	}
+	tail := c.Latency() + c.TailLength()
+	c.params.load()
+
 	for numSamplesProcessed < numSamples {
-		var (
-			numRemaining        = numSamples - numSamplesProcessed
-			blockLimit          = blockSize - c.inputPos
-			numSamplesToProcess = min(numRemaining, blockLimit)
-		)
+		numSamplesToProcess := min(numSamples-numSamplesProcessed, c.blockSize)
 
 		// Copy the input into the internal input buffer. If we've stepped
 		// beyond the length of our input, leave zeros in the buffer.
 		for i := 0; i < numSamplesToProcess; i++ {
-			inIdx := channel + numSamplesProcessed + i*numChannels
+			inIdx := channel + (numSamplesProcessed+i)*numChannels
 			var v float64
 			if inIdx <= len(in)-1 {
 				v = in[inIdx]
 			}
-			c.input[c.inputPos+i] = v
-		}
-		inputSegment := c.inputSegments[c.inputSegmentPos]
-		if err := cmplxCopyReal(inputSegment, c.input); err != nil {
-			return err
-		}
-
-		// Forward FFT
-		if err := Forward(inputSegment); err != nil {
-			return err
-		}
-
-		// Multiply
-		if c.inputPos == 0 {
-			cmplxZero(c.temp)
-
-			index := c.inputSegmentPos
-			for i := 1; i < numResponseSegments; i++ {
-				index += step
-				if index >= numInputSegments {
-					index -= numInputSegments
-				}
-				cmplxMultiplyAdd(c.temp, c.inputSegments[index], c.responseSegments[i])
+			c.dry[i] = c.delayDry(v)
+			c.input[i] = c.params.preDelay(v)
+
+			// Keep track of how long the output will ring on for
+			if v != 0 {
+				c.remaining = tail
+			} else if c.remaining > 0 {
+				c.remaining--
 			}
 		}
 
-		if err := cmplxCopy(c.output, c.temp); err != nil {
-			return err
-		}
-		if err := cmplxMultiplyAdd(c.output, inputSegment, c.responseSegments[0]); err != nil {
+		var (
+			input  = c.input[:numSamplesToProcess]
+			output = c.output[:numSamplesToProcess]
+		)
+		if err := c.engine.process(output, input); err != nil {
 			return err
 		}
-
-		// Inverse FFT
-		if err := Inverse(c.output); err != nil {
-			return err
+		if c.next != nil {
+			if err := c.crossfade(output, input); err != nil {
+				return err
+			}
 		}
 
-		// Add overlap to the output
-		for i := 0; i < numSamplesToProcess; i++ {
+		for i, v := range output {
 			var (
-				outIdx = numSamplesProcessed + channel + i*numChannels
-				pos    = c.inputPos + i
+				outIdx = channel + (numSamplesProcessed+i)*numChannels
+				mixed  = c.params.mix(v, c.dry[i])
 			)
+
 			// Guard against stepping outside the bounds of the output buffer
 			if outIdx > len(out)-1 {
 				continue
 			}
-			out[outIdx] = real(c.output[pos]) + c.overlap[pos]
+			out[outIdx] = mixed
 		}
 
-		c.inputPos += numSamplesToProcess
+		numSamplesProcessed += numSamplesToProcess
+	}
 
-		if c.inputPos == blockSize {
-			c.inputPos = 0
-			zero(c.input)
+	return nil
+}
 
-			// Additional overlap when segment size > block size
-			arErr := cmplxAddReal(c.output[blockSize:], c.overlap[blockSize:], fftSize-2*blockSize)
-			if arErr != nil {
-				return arErr
-			}
+// crossfade mixes the output of the incoming engine into the output of the
+// outgoing one.
+func (c *Convolver) crossfade(output, input []float64) error {
+	fade := c.fade[:len(output)]
+	if err := c.next.process(fade, input); err != nil {
+		return err
+	}
+	for i := range output {
+		if c.fadePos < c.fadeLength {
+			x := 0.5 * math.Pi * float64(c.fadePos) / float64(c.fadeLength)
+			output[i] = output[i]*math.Cos(x) + fade[i]*math.Sin(x)
+			c.fadePos++
+		} else {
+			output[i] = fade[i]
+		}
+	}
+	if c.fadePos == c.fadeLength {
+		c.endSwap()
+	}
+	return nil
+}
 
-			// Save the tail of the output as overlap
-			for i := 0; i < fftSize-blockSize; i++ {
-				c.overlap[i] = real(c.output[i+blockSize])
-			}
+// Flush writes the remainder of the output that's still ringing out from past
+// input, as though the input had continued with silence. The output is written
+// to out in the same layout as Convolve. It returns the number of samples
+// written (per channel) and io.EOF once the output has decayed to silence.
+// Call it repeatedly to drain a tail longer than out.
+func (c *Convolver) Flush(out []float64) (n int, err error) {
+	capacity := 0
+	if len(out) > c.channel {
+		capacity = (len(out) - c.channel + c.numChannels - 1) / c.numChannels
+	}
+	n = min(capacity, c.remaining)
+	if err := c.Convolve(out, nil, n); err != nil {
+		return 0, err
+	}
+	if c.remaining == 0 {
+		return n, io.EOF
+	}
+	return n, nil
+}
 
-			// Step the current segment backwards
-			if c.inputSegmentPos > 0 {
-				c.inputSegmentPos--
-			} else {
-				c.inputSegmentPos = numInputSegments - 1
-			}
-		}
+// Reset clears all input history and pending output, as though the Convolver
+// had just been created. An impulse response crossfade that's underway is
+// completed immediately, and a swap that hasn't started yet is discarded.
+func (c *Convolver) Reset() {
+	if c.next != nil {
+		c.endSwap()
+	}
+	c.discardSwap()
+	c.engine.reset()
+	c.params.reset()
+	zero(c.dryLine)
+	c.dryPos = 0
+	c.remaining = 0
+}
 
-		numSamplesProcessed += numSamplesToProcess
+// Latency returns the number of samples the output of the Convolver is delayed
+// by. By default, a uniformly partitioned Convolver transforms partial blocks
+// as they arrive and has no latency. See FixedLatency and NonUniform.
+func (c *Convolver) Latency() int {
+	return c.engine.latency()
+}
+
+// TailLength returns the number of samples the output continues for after the
+// input falls silent, not including latency. It includes the pre-delay. During
+// an impulse response crossfade, it's the longer of the two responses.
+func (c *Convolver) TailLength() int {
+	n := c.engine.length()
+	if c.next != nil {
+		n = max(n, c.next.length())
 	}
+	return n - 1 + c.params.tail()
+}
 
+// SetOffline switches a Convolver configured with BackgroundTail between
+// computing its late partitions on the worker goroutine and computing them
+// synchronously within Convolve. Both produce identical output; offline
+// rendering avoids the hand-off when there is no real-time deadline to meet.
+// It must not be called concurrently with Convolve.
+func (c *Convolver) SetOffline(offline bool) {
+	if c.worker != nil {
+		c.worker.offline = offline
+	}
+}
+
+// Close stops the Convolver's background worker, if it has one. The Convolver
+// must not be used after it has been closed.
+func (c *Convolver) Close() error {
+	if c.worker != nil {
+		c.worker.close()
+		c.worker = nil
+	}
 	return nil
 }
 
@@ -233,6 +586,19 @@ func loadIR(segments [][]complex128, ir []float64, fillSize int) {
 	}
 }
 
+// copySegments copies a ring of segments into another ring, preserving the
+// order of the segments relative to each ring's current position. If the
+// rings differ in length, only the most recent segments are copied.
+func copySegments(dest [][]complex128, destPos int, src [][]complex128, srcPos int) {
+	var (
+		ldest = len(dest)
+		lsrc  = len(src)
+	)
+	for i := 0; i < min(ldest, lsrc); i++ {
+		copy(dest[(destPos+i)%ldest], src[(srcPos+i)%lsrc])
+	}
+}
+
 // cmplxMultiplyAdd multiplies two complex buffers and adds the result to another.
 func cmplxMultiplyAdd(dest, a, b []complex128) error {
 	var (
@@ -316,6 +682,13 @@ func min(a, b int) int {
 	return b
 }
 
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
 // calcPartitionSize calculates the appropriate size of the partitions used in
 // convolution. It returns two values: a block size that's quantized (up) to the
 // nearest power of two and the FFT size we should use.
@@ -348,3 +721,85 @@ func ForChannel(channel, numChannels int) ConvolverOption {
 		return nil
 	}
 }
+
+// WithMethod configures the partitioned convolution method used by a
+// Convolver. The default is OverlapAdd. Both methods produce the same output.
+// The method has no effect on a non-uniformly partitioned Convolver, which
+// always uses overlap-save.
+func WithMethod(m Method) ConvolverOption {
+	return func(c *Convolver) error {
+		switch m {
+		case OverlapAdd, OverlapSave:
+		default:
+			return fmt.Errorf("unknown convolution method: %d", m)
+		}
+		c.method = m
+		return nil
+	}
+}
+
+// NonUniform configures a Convolver to partition the impulse response
+// non-uniformly: short partitions at the head of the response and
+// progressively longer ones in the tail. This keeps the per-block cost low for
+// long impulse responses and small block sizes.
+//
+// Each partition is transformed once per completed block of its own size. When
+// directHead is true, the first block of the impulse response is applied with a
+// direct-form FIR filter and the Convolver has no latency. Otherwise, the
+// output is delayed by the block size.
+func NonUniform(directHead bool) ConvolverOption {
+	return func(c *Convolver) error {
+		c.nonUniform = true
+		c.directHead = directHead
+		return nil
+	}
+}
+
+// BackgroundTail configures a non-uniformly partitioned Convolver to compute
+// its late (large) partitions on a background goroutine, leaving only the head
+// of the impulse response to be computed within Convolve. Each late partition
+// is given a full block period of its own size to complete; if the worker
+// falls behind, Convolve waits for it, so output is always identical to
+// synchronous processing. Use SetOffline to process synchronously and Close to
+// stop the worker.
+func BackgroundTail() ConvolverOption {
+	return func(c *Convolver) error {
+		c.background = true
+		return nil
+	}
+}
+
+// FixedLatency configures a uniformly partitioned Convolver to buffer input
+// until a block is complete, transforming each block exactly once, rather than
+// transforming the partial block on every call to Convolve. This makes the cost
+// independent of how many samples the caller provides at a time, at the price
+// of delaying the output by the block size (see Latency). It uses the
+// overlap-save method. A non-uniformly partitioned Convolver always works this
+// way, so the option has no effect on one.
+func FixedLatency() ConvolverOption {
+	return func(c *Convolver) error {
+		c.fixedLatency = true
+		return nil
+	}
+}
+
+// MaxIRLength sets the maximum length of impulse response, in samples, the
+// Convolver accepts. Zero or less removes the limit. The default is
+// DefaultMaxIRLength. See EstimateMemory to check a length against a memory
+// budget.
+func MaxIRLength(n int) ConvolverOption {
+	return func(c *Convolver) error {
+		c.maxIRLength = n
+		return nil
+	}
+}
+
+// TruncateIR configures a Convolver to truncate impulse responses longer than
+// the maximum length rather than reject them. The number of samples truncated
+// is reported by Truncated.
+func TruncateIR() ConvolverOption {
+	return func(c *Convolver) error {
+		c.truncate = true
+		return nil
+	}
+}
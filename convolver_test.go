@@ -2,11 +2,11 @@ package fourier
 
 import (
 	"fmt"
+	"io"
 	"math"
+	"runtime"
 	"testing"
 
-	"github.com/brettbuddin/fourier/filter"
-	"github.com/brettbuddin/fourier/window"
 	"github.com/stretchr/testify/require"
 )
 
@@ -137,6 +137,80 @@ func BenchmarkConvolver(b *testing.B) {
 	}
 }
 
+func TestConvolution_OverlapSave(t *testing.T) {
+	for _, blockSize := range []int{8, 64, 256} {
+		for _, impulseSize := range []int{3, 100, 1500} {
+			name := fmt.Sprintf("block=%d/ir=%d", blockSize, impulseSize)
+			t.Run(name, func(t *testing.T) {
+				var (
+					impulse = make([]float64, impulseSize)
+					input   = make([]float64, 4000)
+					add     = make([]float64, len(input))
+					save    = make([]float64, len(input))
+				)
+				for i := range impulse {
+					impulse[i] = math.Sin(float64(i)*0.3) / float64(i+1)
+				}
+				for i := range input {
+					input[i] = math.Cos(float64(i) * 0.05)
+				}
+
+				ola, err := NewConvolver(blockSize, impulse)
+				require.NoError(t, err)
+				ols, err := NewConvolver(blockSize, impulse, WithMethod(OverlapSave))
+				require.NoError(t, err)
+
+				// Feed irregular chunk sizes to exercise partial blocks.
+				for i, n := 0, 1; i < len(input); i, n = i+n, n%37+5 {
+					end := min(i+n, len(input))
+					require.NoError(t, ola.Convolve(add[i:end], input[i:end], end-i))
+					require.NoError(t, ols.Convolve(save[i:end], input[i:end], end-i))
+				}
+
+				for i := range add {
+					require.InDelta(t, add[i], save[i], epsilon)
+				}
+			})
+		}
+	}
+}
+
+func TestConvolver_UnknownMethod(t *testing.T) {
+	_, err := NewConvolver(64, []float64{1}, WithMethod(Method(42)))
+	require.Error(t, err)
+}
+
+func BenchmarkConvolver_Method(b *testing.B) {
+	methods := []struct {
+		name   string
+		method Method
+	}{
+		{"add", OverlapAdd},
+		{"save", OverlapSave},
+	}
+	for _, blockSize := range []int{64, 256, 1024} {
+		for _, irSize := range []int{500, 48000} {
+			for _, m := range methods {
+				name := fmt.Sprintf("%s/block=%d/ir=%d", m.name, blockSize, irSize)
+				b.Run(name, func(b *testing.B) {
+					var (
+						ir  = make([]float64, irSize)
+						in  = make([]float64, blockSize)
+						out = make([]float64, blockSize)
+					)
+					conv, _ := NewConvolver(blockSize, ir, WithMethod(m.method))
+
+					b.ReportAllocs()
+					b.ResetTimer()
+					for i := 0; i < b.N; i++ {
+						conv.Convolve(out, in, blockSize)
+					}
+				})
+			}
+		}
+	}
+}
+
 func TestConvolution_Interleaved(t *testing.T) {
 	var (
 		blockSize   = 8
@@ -171,6 +245,42 @@ func TestConvolution_Interleaved(t *testing.T) {
 	require.InEpsilonSlice(t, expected, output, epsilon)
 }
 
+func TestConvolution_InterleavedChunks(t *testing.T) {
+	var (
+		blockSize   = 4
+		numChannels = 2
+		impulse     = []float64{1, 1}
+		input       = make([]float64, numChannels*20)
+		output      = make([]float64, numChannels*20)
+	)
+
+	for i := 0; i < len(input); i += numChannels {
+		input[i] = float64(i/numChannels) + 1
+		input[i+1] = -input[i]
+	}
+
+	for ch := 0; ch < numChannels; ch++ {
+		conv, err := NewConvolver(blockSize, impulse, ForChannel(ch, numChannels))
+		require.NoError(t, err)
+
+		// Convolve in chunks of 5 frames, which is larger than the block size.
+		for i := 0; i < len(input); i += 5 * numChannels {
+			end := i + 5*numChannels
+			require.NoError(t, conv.Convolve(output[i:end], input[i:end], 5))
+		}
+	}
+
+	for i := 0; i < len(output); i += numChannels {
+		n := float64(i/numChannels) + 1
+		expected := 2*n - 1
+		if n == 1 {
+			expected = 1
+		}
+		require.InDelta(t, expected, output[i], epsilon)
+		require.InDelta(t, -expected, output[i+1], epsilon)
+	}
+}
+
 func ExampleConvolver_simple() {
 	var (
 		blockSize = 8
@@ -225,76 +335,273 @@ func ExampleConvolver_chunks() {
 	// Output: [1 3 5 7 9 11 13 15 17 19 21 23 25 27 29 31 16]
 }
 
-func ExampleConvolver_filtering() {
-	var (
-		blockSize  = 256
-		sampleRate = 320.0
-		cutoff     = 30.0
-		kernel     = make([]float64, 32)
-		in         = make([]float64, blockSize)
-	)
+func carrier(dest []float64, fc, fs float64) {
+	for i := 0; i < len(dest); i++ {
+		dest[i] += math.Cos((float64(i) * 2 * math.Pi * fc) / fs)
+	}
+}
 
-	// Sum two cosine waves, one at 10Hz and another at 90Hz
-	carrier(in, 10.0, sampleRate)
-	carrier(in, 90.0, sampleRate)
+func cmplxCarrier(dest []complex128, fc, fs float64) {
+	destf := make([]float64, len(dest))
+	carrier(destf, fc, fs)
+	for i := range dest {
+		dest[i] = complex(destf[i], 0)
+	}
+}
 
-	// Calculate bins with high magnitude before filtering
-	spikesBefore := detectSpikes(in)
+func roundTo(out []float64, epsilon float64) {
+	for i := range out {
+		out[i] = math.Round(out[i]*epsilon) / epsilon
+	}
+}
 
-	// Build a filter kernel that filters frequencies higher than 30Hz at 320Hz
-	// sampling rate and convolve the summed signal with it.
-	filter.MakeLowPass(kernel, window.Lanczos, cutoff/sampleRate)
-	conv, _ := NewConvolver(blockSize, kernel)
+func TestConvolver_ResetAndFlush(t *testing.T) {
+	for _, blockSize := range []int{64, 128, 256} {
+		for _, tc := range []struct {
+			name    string
+			opts    []ConvolverOption
+			latency int
+		}{
+			{"uniform", nil, 0},
+			{"overlap-save", []ConvolverOption{WithMethod(OverlapSave)}, 0},
+			{"fixed-latency", []ConvolverOption{FixedLatency()}, blockSize},
+			{"non-uniform", []ConvolverOption{NonUniform(false)}, blockSize},
+			{"non-uniform-head", []ConvolverOption{NonUniform(true)}, 0},
+		} {
+			t.Run(fmt.Sprintf("%s/block=%d", tc.name, blockSize), func(t *testing.T) {
+				var (
+					impulse = make([]float64, 1000)
+					input   = make([]float64, 777)
+				)
+				for i := range impulse {
+					impulse[i] = math.Sin(float64(i)*0.3) / float64(i+1)
+				}
+				for i := range input {
+					input[i] = math.Cos(float64(i) * 0.05)
+				}
+				expected := directConvolve(input, impulse)
+
+				conv, err := NewConvolver(blockSize, impulse, tc.opts...)
+				require.NoError(t, err)
+				require.Equal(t, tc.latency, conv.Latency())
+				require.Equal(t, len(impulse)-1, conv.TailLength())
+
+				// Nothing to flush before any input has arrived.
+				n, err := conv.Flush(make([]float64, 10))
+				require.Equal(t, 0, n)
+				require.Equal(t, io.EOF, err)
+
+				render := func() []float64 {
+					output := make([]float64, len(input))
+					require.NoError(t, conv.Convolve(output, input, len(input)))
+
+					// Drain the tail in chunks smaller than the tail.
+					chunk := make([]float64, 100)
+					for {
+						n, err := conv.Flush(chunk)
+						output = append(output, chunk[:n]...)
+						if err == io.EOF {
+							break
+						}
+						require.NoError(t, err)
+						require.Equal(t, len(chunk), n)
+					}
+					return output
+				}
+
+				// The flushed output covers the full convolution plus latency.
+				output := render()
+				require.Len(t, output, len(expected)+tc.latency)
+				for i, v := range expected {
+					require.InDelta(t, v, output[i+tc.latency], epsilon, "sample %d", i)
+				}
+
+				// Interrupt a render part way through, reset and render again.
+				// Nothing from before the reset may leak into the output.
+				partial := make([]float64, 300)
+				require.NoError(t, conv.Convolve(partial, input, len(partial)))
+				conv.Reset()
+
+				output = render()
+				require.Len(t, output, len(expected)+tc.latency)
+				for i, v := range expected {
+					require.InDelta(t, v, output[i+tc.latency], epsilon, "sample %d", i)
+				}
+			})
+		}
+	}
+}
 
-	out := make([]float64, blockSize)
-	conv.Convolve(out, in, len(out))
+func TestConvolver_FlushInterleaved(t *testing.T) {
+	conv, err := NewConvolver(8, []float64{1, 1, 1}, ForChannel(1, 2))
+	require.NoError(t, err)
 
-	// Calculate bins with high magnitude after filtering
-	spikesAfter := detectSpikes(out)
+	out := make([]float64, 4)
+	require.NoError(t, conv.Convolve(out, []float64{0, 1, 0, 2}, 2))
+	require.Equal(t, []float64{0, 1, 0, 3}, roundedCopy(out))
 
-	fmt.Println("spikes at (before):", spikesBefore)
-	fmt.Println("spikes at (after):", spikesAfter)
-	// Output: spikes at (before): [8 72 184 248]
-	// spikes at (after): [8 248]
+	out = make([]float64, 6)
+	n, err := conv.Flush(out)
+	require.Equal(t, 2, n)
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, []float64{0, 3, 0, 2, 0, 0}, roundedCopy(out))
 }
 
-func magnitude(src []float64) []float64 {
-	dest := make([]float64, len(src))
-	freq := make([]complex128, len(src))
-	for i, v := range src {
-		freq[i] = complex(v, 0)
-	}
-	Forward(freq)
-	Magnitude(dest, freq)
-	return dest
+func roundedCopy(v []float64) []float64 {
+	out := append([]float64(nil), v...)
+	roundTo(out, 1e10)
+	return out
 }
 
-func detectSpikes(buf []float64) []int {
-	var spikes []int
-	for i, v := range magnitude(buf) {
-		if v > 0.2 {
-			spikes = append(spikes, i)
+func TestConvolution_FixedLatency(t *testing.T) {
+	for _, blockSize := range []int{8, 64, 512} {
+		for _, impulseSize := range []int{3, 100, 1500} {
+			t.Run(fmt.Sprintf("block=%d/ir=%d", blockSize, impulseSize), func(t *testing.T) {
+				var (
+					impulse = testIR(impulseSize, 0.3)
+					input   = make([]float64, 3000)
+					output  = make([]float64, len(input)+blockSize)
+				)
+				for i := range input {
+					input[i] = math.Cos(float64(i) * 0.05)
+				}
+
+				conv, err := NewConvolver(blockSize, impulse, FixedLatency())
+				require.NoError(t, err)
+				require.Equal(t, blockSize, conv.Latency())
+
+				for i, n := 0, 1; i < len(output); i, n = i+n, n%37+5 {
+					var (
+						end     = min(i+n, len(output))
+						inBegin = min(i, len(input))
+						inEnd   = min(end, len(input))
+					)
+					require.NoError(t, conv.Convolve(output[i:end], input[inBegin:inEnd], end-i))
+				}
+
+				for i, v := range directConvolve(input, impulse)[:len(input)] {
+					require.InDelta(t, v, output[i+blockSize], epsilon, "sample %d", i)
+				}
+				for i := 0; i < blockSize; i++ {
+					require.Zero(t, output[i])
+				}
+			})
 		}
 	}
-	return spikes
 }
 
-func carrier(dest []float64, fc, fs float64) {
-	for i := 0; i < len(dest); i++ {
-		dest[i] += math.Cos((float64(i) * 2 * math.Pi * fc) / fs)
+func BenchmarkConvolver_CallSize(b *testing.B) {
+	var (
+		blockSize  = 512
+		numSamples = 4410
+		ir         = testIR(48000, 0.3)
+		in         = make([]float64, numSamples)
+		out        = make([]float64, numSamples)
+	)
+	for _, bm := range []struct {
+		name string
+		opts []ConvolverOption
+	}{
+		{"zero-latency", nil},
+		{"fixed-latency", []ConvolverOption{FixedLatency()}},
+	} {
+		for _, callSize := range []int{1, 7, 64, 441} {
+			// Each operation convolves the same number of samples, split into
+			// calls of callSize samples.
+			b.Run(fmt.Sprintf("%s/call=%d", bm.name, callSize), func(b *testing.B) {
+				conv, _ := NewConvolver(blockSize, ir, bm.opts...)
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					for j := 0; j < numSamples; j += callSize {
+						end := min(j+callSize, numSamples)
+						conv.Convolve(out[j:end], in[j:end], end-j)
+					}
+				}
+			})
+		}
 	}
 }
 
-func cmplxCarrier(dest []complex128, fc, fs float64) {
-	destf := make([]float64, len(dest))
-	carrier(destf, fc, fs)
-	for i := range dest {
-		dest[i] = complex(destf[i], 0)
-	}
+func TestConvolver_MaxIRLength(t *testing.T) {
+	ir := make([]float64, 1000)
+	ir[0] = 1
+
+	_, err := NewConvolver(64, ir, MaxIRLength(999))
+	lerr, ok := err.(*IRLengthError)
+	require.True(t, ok)
+	require.Equal(t, 1000, lerr.Length)
+	require.Equal(t, 999, lerr.Max)
+
+	conv, err := NewConvolver(64, ir, MaxIRLength(1000))
+	require.NoError(t, err)
+	require.Equal(t, 0, conv.Truncated())
+
+	conv, err = NewConvolver(64, ir, MaxIRLength(600), TruncateIR())
+	require.NoError(t, err)
+	require.Equal(t, 400, conv.Truncated())
+	require.Equal(t, 599, conv.TailLength())
+
+	r, err := conv.PrepareImpulseResponse(ir[:700])
+	require.NoError(t, err)
+	require.Equal(t, 100, r.Truncated())
+
+	require.NoError(t, conv.SetImpulseResponse(ir[:10]))
+	require.Equal(t, 0, conv.Truncated())
+
+	// No limit
+	long := make([]float64, DefaultMaxIRLength+1)
+	_, err = NewConvolver(4096, long)
+	require.IsType(t, &IRLengthError{}, err)
+	_, err = NewConvolver(4096, long, MaxIRLength(0))
+	require.NoError(t, err)
 }
 
-func roundTo(out []float64, epsilon float64) {
-	for i := range out {
-		out[i] = math.Round(out[i]*epsilon) / epsilon
+func TestEstimateMemory(t *testing.T) {
+	irSize := 10 * 48000
+	ir := make([]float64, irSize)
+	ir[0] = 1
+
+	for _, tc := range []struct {
+		name      string
+		blockSize int
+		opts      []ConvolverOption
+	}{
+		{"uniform-small", 64, nil},
+		{"uniform-large", 1024, nil},
+		{"fixed-latency", 256, []ConvolverOption{FixedLatency()}},
+		{"non-uniform", 64, []ConvolverOption{NonUniform(false)}},
+		{"non-uniform-head", 64, []ConvolverOption{NonUniform(true), PreDelay(4800)}},
+		{"non-uniform-background", 64, []ConvolverOption{NonUniform(true), BackgroundTail()}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			estimate, err := EstimateMemory(tc.blockSize, irSize, tc.opts...)
+			require.NoError(t, err)
+
+			var before, after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+			conv, err := NewConvolver(tc.blockSize, ir, tc.opts...)
+			runtime.ReadMemStats(&after)
+			require.NoError(t, err)
+			defer conv.Close()
+
+			measured := float64(after.TotalAlloc - before.TotalAlloc)
+			require.InEpsilon(t, measured, float64(estimate), 0.05)
+		})
 	}
+
+	_, err := EstimateMemory(64, 1000, MaxIRLength(999))
+	require.IsType(t, &IRLengthError{}, err)
+
+	// Options NewConvolver rejects are rejected here too.
+	_, err = EstimateMemory(64, 1000, BackgroundTail())
+	require.Error(t, err)
+
+	truncated, err := EstimateMemory(64, 1000, MaxIRLength(500), TruncateIR())
+	require.NoError(t, err)
+	exact, err := EstimateMemory(64, 500)
+	require.NoError(t, err)
+	require.Equal(t, exact, truncated)
 }
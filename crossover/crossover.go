@@ -0,0 +1,69 @@
+// Package crossover splits a signal into frequency bands that sum back to the
+// original: flat in magnitude, and either delayed (linear phase) or passed
+// through an all-pass filter (Linkwitz-Riley).
+package crossover
+
+import (
+	"errors"
+	"fmt"
+)
+
+// maxBands is the most bands a crossover splits into.
+const maxBands = 5
+
+// config holds the options shared by the crossovers.
+type config struct {
+	numChannels int
+}
+
+// Option is a configuration option for a crossover.
+type Option func(*config) error
+
+// Channels configures a crossover to split every channel of buffers that
+// contain numChannels interleaved channels. The default is 1.
+func Channels(numChannels int) Option {
+	return func(c *config) error {
+		if numChannels < 1 {
+			return errors.New("number of channels cannot be less than 1")
+		}
+		c.numChannels = numChannels
+		return nil
+	}
+}
+
+func newConfig(opts []Option) (config, error) {
+	c := config{numChannels: 1}
+	for _, opt := range opts {
+		if err := opt(&c); err != nil {
+			return config{}, err
+		}
+	}
+	return c, nil
+}
+
+// validateFrequencies checks the crossover frequencies, in Hz, between bands.
+func validateFrequencies(sampleRate float64, frequencies []float64) error {
+	if sampleRate <= 0 {
+		return errors.New("sample rate must be positive")
+	}
+	if len(frequencies) < 1 || len(frequencies) > maxBands-1 {
+		return fmt.Errorf("between 1 and %d crossover frequencies are required", maxBands-1)
+	}
+	for i, f := range frequencies {
+		if f <= 0 || f >= sampleRate/2 {
+			return fmt.Errorf("crossover frequency %g Hz must be between 0 and %g Hz (Nyquist)", f, sampleRate/2)
+		}
+		if i > 0 && f <= frequencies[i-1] {
+			return fmt.Errorf("crossover frequencies %g Hz and %g Hz must be in increasing order", frequencies[i-1], f)
+		}
+	}
+	return nil
+}
+
+// validateBands checks that there's a buffer for each band.
+func validateBands(bands [][]float64, numBands int) error {
+	if len(bands) != numBands {
+		return fmt.Errorf("expected %d band buffers, got %d", numBands, len(bands))
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package crossover
+
+import (
+	"errors"
+
+	"github.com/brettbuddin/fourier"
+	"github.com/brettbuddin/fourier/filter"
+	"github.com/brettbuddin/fourier/window"
+)
+
+// LinearPhase is a crossover built from linear-phase FIR filters designed with
+// the filter package. Each band is the difference between the windowed-sinc
+// low-pass filters at the crossover frequencies on either side of it, so the
+// bands sum exactly to the input delayed by half the kernel length.
+type LinearPhase struct {
+	kernels     [][]float64
+	convolvers  [][]*fourier.Convolver
+	numChannels int
+}
+
+// NewLinearPhase returns a crossover that splits its input into
+// len(frequencies)+1 bands at the given frequencies, in Hz and in increasing
+// order. Up to five bands are supported.
+//
+// Each band's kernel is numTaps long, which must be odd, and is designed with
+// the window wf, or the Blackman window if wf is nil. The kernels are run
+// through Convolvers with the given block size, one per band and channel.
+func NewLinearPhase(sampleRate float64, frequencies []float64, numTaps int, wf window.Func, blockSize int, opts ...Option) (*LinearPhase, error) {
+	if err := validateFrequencies(sampleRate, frequencies); err != nil {
+		return nil, err
+	}
+	if numTaps < 3 || numTaps%2 == 0 {
+		return nil, errors.New("number of taps must be odd and at least 3")
+	}
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// The low-pass filters at each crossover frequency, followed by the
+	// delayed impulse they are all subtracted from.
+	lows := make([][]float64, 0, len(frequencies)+1)
+	for _, f := range frequencies {
+		h, err := filter.Design(filter.Spec{
+			Type:       filter.LowPass,
+			SampleRate: sampleRate,
+			Cutoffs:    []float64{f},
+			Window:     wf,
+		}, numTaps)
+		if err != nil {
+			return nil, err
+		}
+		lows = append(lows, h)
+	}
+	impulse := make([]float64, numTaps)
+	impulse[numTaps/2] = 1
+	lows = append(lows, impulse)
+
+	x := &LinearPhase{numChannels: cfg.numChannels}
+	for band, low := range lows {
+		kernel := make([]float64, numTaps)
+		for i := range kernel {
+			kernel[i] = low[i]
+			if band > 0 {
+				kernel[i] -= lows[band-1][i]
+			}
+		}
+
+		convolvers := make([]*fourier.Convolver, cfg.numChannels)
+		for ch := range convolvers {
+			c, err := fourier.NewConvolver(blockSize, kernel, fourier.ForChannel(ch, cfg.numChannels))
+			if err != nil {
+				x.Close()
+				return nil, err
+			}
+			convolvers[ch] = c
+		}
+		x.kernels = append(x.kernels, kernel)
+		x.convolvers = append(x.convolvers, convolvers)
+	}
+	return x, nil
+}
+
+// NumBands returns the number of bands the crossover splits into.
+func (x *LinearPhase) NumBands() int {
+	return len(x.kernels)
+}
+
+// Kernel returns the FIR kernel for a band, lowest band first.
+func (x *LinearPhase) Kernel(band int) []float64 {
+	h := make([]float64, len(x.kernels[band]))
+	copy(h, x.kernels[band])
+	return h
+}
+
+// Latency returns the number of samples the bands are delayed by: half the
+// kernel length plus the latency of the Convolvers.
+func (x *LinearPhase) Latency() int {
+	return len(x.kernels[0])/2 + x.convolvers[0][0].Latency()
+}
+
+// Process splits numSamples samples of each channel of in into bands, one
+// buffer per band with the same layout as in.
+func (x *LinearPhase) Process(bands [][]float64, in []float64, numSamples int) error {
+	if err := validateBands(bands, len(x.kernels)); err != nil {
+		return err
+	}
+	for i, convolvers := range x.convolvers {
+		for _, c := range convolvers {
+			if err := c.Convolve(bands[i], in, numSamples); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Reset clears the input history of the crossover's Convolvers.
+func (x *LinearPhase) Reset() {
+	for _, convolvers := range x.convolvers {
+		for _, c := range convolvers {
+			c.Reset()
+		}
+	}
+}
+
+// Close releases the crossover's Convolvers. The crossover must not be used
+// after it has been closed.
+func (x *LinearPhase) Close() error {
+	for _, convolvers := range x.convolvers {
+		for _, c := range convolvers {
+			if c == nil {
+				continue
+			}
+			if err := c.Close(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
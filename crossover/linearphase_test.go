@@ -0,0 +1,118 @@
+package crossover
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/brettbuddin/fourier/filter"
+	"github.com/brettbuddin/fourier/window"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinearPhaseKernels(t *testing.T) {
+	const sampleRate = 48000
+	crossovers := []float64{1000, 6000}
+	x, err := NewLinearPhase(sampleRate, crossovers, 511, nil, 64)
+	require.NoError(t, err)
+	defer x.Close()
+	require.Equal(t, 3, x.NumBands())
+
+	// The kernels sum to a delayed impulse.
+	sum := make([]float64, 511)
+	for band := 0; band < x.NumBands(); band++ {
+		h := x.Kernel(band)
+		require.Equal(t, filter.TypeI, filter.SymmetryOf(h))
+		for i, v := range h {
+			sum[i] += v
+		}
+	}
+	for i, v := range sum {
+		var expect float64
+		if i == 255 {
+			expect = 1
+		}
+		require.InDelta(t, expect, v, 1e-12)
+	}
+
+	// Each band passes its own range and rejects the others.
+	for band, tc := range []struct{ pass, stop float64 }{
+		{pass: 200, stop: 3000},
+		{pass: 3000, stop: 12000},
+		{pass: 15000, stop: 200},
+	} {
+		h := x.Kernel(band)
+		r, err := filter.ResponseAt(h, nil, []float64{tc.pass / sampleRate, tc.stop / sampleRate})
+		require.NoError(t, err)
+		db := r.MagnitudeDB()
+		require.InDelta(t, 0, db[0], 0.01, "band %d", band)
+		require.True(t, db[1] < -60, "band %d: %g dB", band, db[1])
+	}
+}
+
+func TestLinearPhaseProcess(t *testing.T) {
+	const (
+		sampleRate  = 44100
+		numChannels = 2
+		numSamples  = 3000
+		numTaps     = 101
+	)
+	x, err := NewLinearPhase(sampleRate, []float64{150, 1000, 4000, 12000}, numTaps, window.Hann, 128, Channels(numChannels))
+	require.NoError(t, err)
+	defer x.Close()
+	require.Equal(t, 5, x.NumBands())
+	require.Equal(t, numTaps/2, x.Latency())
+
+	rng := rand.New(rand.NewSource(1))
+	in := make([]float64, numSamples*numChannels)
+	for i := range in {
+		in[i] = rng.Float64()*2 - 1
+	}
+	bands := make([][]float64, x.NumBands())
+	for i := range bands {
+		bands[i] = make([]float64, len(in))
+	}
+
+	for pos := 0; pos < numSamples; {
+		n := 1 + rng.Intn(200)
+		if pos+n > numSamples {
+			n = numSamples - pos
+		}
+		chunk := make([][]float64, len(bands))
+		for i := range chunk {
+			chunk[i] = bands[i][pos*numChannels:]
+		}
+		require.NoError(t, x.Process(chunk, in[pos*numChannels:], n))
+		pos += n
+	}
+
+	// The bands sum to the input, delayed.
+	latency := x.Latency()
+	for i := 0; i < numSamples; i++ {
+		for ch := 0; ch < numChannels; ch++ {
+			var sum, expect float64
+			for _, b := range bands {
+				sum += b[i*numChannels+ch]
+			}
+			if i >= latency {
+				expect = in[(i-latency)*numChannels+ch]
+			}
+			require.InDelta(t, expect, sum, 1e-9)
+		}
+	}
+
+	x.Reset()
+	require.Error(t, x.Process(bands[:1], in, numSamples))
+}
+
+func TestLinearPhaseErrors(t *testing.T) {
+	_, err := NewLinearPhase(48000, []float64{1000}, 100, nil, 64)
+	require.Error(t, err)
+	_, err = NewLinearPhase(48000, []float64{1000}, 1, nil, 64)
+	require.Error(t, err)
+	_, err = NewLinearPhase(48000, []float64{30000}, 101, nil, 64)
+	require.Error(t, err)
+	_, err = NewLinearPhase(48000, []float64{1000}, 101, nil, 0)
+	require.Error(t, err)
+	_, err = NewLinearPhase(48000, []float64{1000}, 101, nil, 64, Channels(-1))
+	require.Error(t, err)
+}
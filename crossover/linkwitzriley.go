@@ -0,0 +1,150 @@
+package crossover
+
+import (
+	"fmt"
+
+	"github.com/brettbuddin/fourier/filter"
+	"github.com/brettbuddin/fourier/iir"
+)
+
+// Slope is the order of a Linkwitz-Riley crossover: the rate, in multiples of
+// 6 dB per octave, at which each band rolls off.
+type Slope int
+
+// Linkwitz-Riley slopes.
+const (
+	LR2 Slope = 2 // 12 dB per octave
+	LR4 Slope = 4 // 24 dB per octave
+	LR8 Slope = 8 // 48 dB per octave
+)
+
+// LinkwitzRiley is a crossover built from Linkwitz-Riley filters: squared
+// Butterworth filters, whose low- and high-pass outputs are each -6 dB at the
+// crossover frequency and sum to an all-pass response.
+//
+// With more than two bands, each band is also run through the all-pass
+// responses of the crossovers above it, so that every band has the same phase
+// and the bands sum to the product of those all-pass responses: flat in
+// magnitude, but not in phase.
+//
+// For LR2, the high-pass side of each crossover is inverted, as the two sides
+// would otherwise cancel at the crossover frequency.
+type LinkwitzRiley struct {
+	sections [][]iir.Biquad
+	filters  []*iir.Filter
+}
+
+// NewLinkwitzRiley returns a crossover that splits its input into
+// len(frequencies)+1 bands at the given frequencies, in Hz and in increasing
+// order. Up to five bands are supported.
+func NewLinkwitzRiley(sampleRate float64, frequencies []float64, slope Slope, opts ...Option) (*LinkwitzRiley, error) {
+	if err := validateFrequencies(sampleRate, frequencies); err != nil {
+		return nil, err
+	}
+	if slope != LR2 && slope != LR4 && slope != LR8 {
+		return nil, fmt.Errorf("unsupported slope LR%d", slope)
+	}
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	type split struct {
+		low, high, allPass []iir.Biquad
+	}
+	splits := make([]split, len(frequencies))
+	for i, f := range frequencies {
+		var (
+			spec = iir.Spec{Order: int(slope) / 2, SampleRate: sampleRate, Cutoffs: []float64{f}}
+			s    split
+		)
+		spec.Type = filter.LowPass
+		low, err := iir.Butterworth(spec)
+		if err != nil {
+			return nil, err
+		}
+		spec.Type = filter.HighPass
+		high, err := iir.Butterworth(spec)
+		if err != nil {
+			return nil, err
+		}
+		s.low = append(low, low...)
+		s.high = append(high, high...)
+		if slope == LR2 {
+			h := &s.high[0]
+			h.B0, h.B1, h.B2 = -h.B0, -h.B1, -h.B2
+		}
+		for _, b := range low {
+			s.allPass = append(s.allPass, allPass(b))
+		}
+		splits[i] = s
+	}
+
+	// Band k is the high-pass side of the crossovers below it, the low-pass
+	// side of the crossover above it and the all-pass responses of the
+	// crossovers above that.
+	x := &LinkwitzRiley{}
+	for band := 0; band <= len(splits); band++ {
+		var sections []iir.Biquad
+		for i, s := range splits {
+			switch {
+			case i < band:
+				sections = append(sections, s.high...)
+			case i == band:
+				sections = append(sections, s.low...)
+			default:
+				sections = append(sections, s.allPass...)
+			}
+		}
+		f, err := iir.NewFilter(sections, iir.Channels(cfg.numChannels), iir.Smoothing(0))
+		if err != nil {
+			return nil, err
+		}
+		x.sections = append(x.sections, sections)
+		x.filters = append(x.filters, f)
+	}
+	return x, nil
+}
+
+// allPass returns the all-pass section with the same poles as b: the
+// numerator is the denominator reversed.
+func allPass(b iir.Biquad) iir.Biquad {
+	if b.A2 == 0 {
+		return iir.Biquad{B0: b.A1, B1: 1, A1: b.A1}
+	}
+	return iir.Biquad{B0: b.A2, B1: b.A1, B2: 1, A1: b.A1, A2: b.A2}
+}
+
+// NumBands returns the number of bands the crossover splits into.
+func (x *LinkwitzRiley) NumBands() int {
+	return len(x.filters)
+}
+
+// Sections returns the second-order sections that make up a band, lowest band
+// first, for analysis with iir.Coefficients.
+func (x *LinkwitzRiley) Sections(band int) []iir.Biquad {
+	s := make([]iir.Biquad, len(x.sections[band]))
+	copy(s, x.sections[band])
+	return s
+}
+
+// Process splits numSamples samples of each channel of in into bands, one
+// buffer per band with the same layout as in.
+func (x *LinkwitzRiley) Process(bands [][]float64, in []float64, numSamples int) error {
+	if err := validateBands(bands, len(x.filters)); err != nil {
+		return err
+	}
+	for i, f := range x.filters {
+		if err := f.Process(bands[i], in, numSamples); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reset clears the state of the crossover's filters.
+func (x *LinkwitzRiley) Reset() {
+	for _, f := range x.filters {
+		f.Reset()
+	}
+}
@@ -0,0 +1,199 @@
+package crossover
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+
+	"github.com/brettbuddin/fourier/filter"
+	"github.com/brettbuddin/fourier/iir"
+	"github.com/stretchr/testify/require"
+)
+
+// responseAt returns the response of sections in cascade at freqs, given as
+// fractions of the sample rate.
+func responseAt(t *testing.T, sections []iir.Biquad, freqs []float64) []complex128 {
+	values := make([]complex128, len(freqs))
+	for i := range values {
+		values[i] = 1
+	}
+	for _, s := range sections {
+		b, a := s.Coefficients()
+		r, err := filter.ResponseAt(b, a, freqs)
+		require.NoError(t, err)
+		for i, v := range r.Values {
+			values[i] *= v
+		}
+	}
+	return values
+}
+
+func TestLinkwitzRileyResponse(t *testing.T) {
+	const sampleRate = 48000
+	freqs := make([]float64, 512)
+	for i := range freqs {
+		freqs[i] = float64(i) / float64(2*len(freqs))
+	}
+
+	for _, slope := range []Slope{LR2, LR4, LR8} {
+		for _, crossovers := range [][]float64{
+			{1000},
+			{250, 2500},
+			{120, 800, 5000},
+			{80, 400, 2000, 10000},
+		} {
+			x, err := NewLinkwitzRiley(sampleRate, crossovers, slope)
+			require.NoError(t, err)
+			require.Equal(t, len(crossovers)+1, x.NumBands())
+
+			// The bands sum to an all-pass response.
+			sum := make([]complex128, len(freqs))
+			for band := 0; band < x.NumBands(); band++ {
+				for i, v := range responseAt(t, x.Sections(band), freqs) {
+					sum[i] += v
+				}
+			}
+			for i, v := range sum {
+				require.InDelta(t, 1, cmplx.Abs(v), 1e-9, "LR%d %v at %g", slope, crossovers, freqs[i])
+			}
+
+			// With two bands, each is -6 dB at the crossover. With more, the
+			// neighboring crossovers pull that a little further down.
+			for band := 0; band < x.NumBands(); band++ {
+				var edges []float64
+				if band > 0 {
+					edges = append(edges, crossovers[band-1]/sampleRate)
+				}
+				if band < len(crossovers) {
+					edges = append(edges, crossovers[band]/sampleRate)
+				}
+				for _, v := range responseAt(t, x.Sections(band), edges) {
+					db := 20 * math.Log10(cmplx.Abs(v))
+					if len(crossovers) == 1 {
+						require.InDelta(t, -20*math.Log10(2), db, 1e-9)
+					} else {
+						require.InDelta(t, -20*math.Log10(2), db, 1)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestLinkwitzRileySlope(t *testing.T) {
+	const sampleRate = 48000
+	for _, slope := range []Slope{LR2, LR4, LR8} {
+		x, err := NewLinkwitzRiley(sampleRate, []float64{1000}, slope)
+		require.NoError(t, err)
+
+		// An octave and more away from the crossover, each band rolls off at
+		// 6 dB per octave per order.
+		var (
+			low  = responseAt(t, x.Sections(0), []float64{8000. / sampleRate, 16000. / sampleRate})
+			high = responseAt(t, x.Sections(1), []float64{125. / sampleRate, 62.5 / sampleRate})
+			db   = func(v complex128) float64 { return 20 * math.Log10(cmplx.Abs(v)) }
+		)
+		require.InDelta(t, 6.02*float64(slope), db(high[0])-db(high[1]), 0.1)
+		require.True(t, db(low[0])-db(low[1]) > 6.02*float64(slope)-0.1)
+	}
+}
+
+func TestLinkwitzRileyProcess(t *testing.T) {
+	const (
+		sampleRate  = 44100
+		numChannels = 2
+		numSamples  = 4096
+	)
+	crossovers := []float64{200, 1500, 6000}
+	x, err := NewLinkwitzRiley(sampleRate, crossovers, LR4, Channels(numChannels))
+	require.NoError(t, err)
+
+	in := make([]float64, numSamples*numChannels)
+	rng := rand.New(rand.NewSource(1))
+	for i := range in {
+		in[i] = rng.Float64()*2 - 1
+	}
+
+	bands := make([][]float64, x.NumBands())
+	for i := range bands {
+		bands[i] = make([]float64, len(in))
+	}
+
+	// Process in irregular chunks.
+	for pos := 0; pos < numSamples; {
+		n := 1 + rng.Intn(300)
+		if pos+n > numSamples {
+			n = numSamples - pos
+		}
+		chunk := make([][]float64, len(bands))
+		for i := range chunk {
+			chunk[i] = bands[i][pos*numChannels:]
+		}
+		require.NoError(t, x.Process(chunk, in[pos*numChannels:], n))
+		pos += n
+	}
+
+	// The bands sum to the input passed through the all-pass responses of
+	// every crossover.
+	var allPasses []iir.Biquad
+	for _, f := range crossovers {
+		low, err := iir.Butterworth(iir.Spec{Type: filter.LowPass, Order: 2, SampleRate: sampleRate, Cutoffs: []float64{f}})
+		require.NoError(t, err)
+		for _, s := range low {
+			allPasses = append(allPasses, allPass(s))
+		}
+	}
+	ap, err := iir.NewFilter(allPasses, iir.Channels(numChannels))
+	require.NoError(t, err)
+	expect := make([]float64, len(in))
+	require.NoError(t, ap.Process(expect, in, numSamples))
+
+	for i := range in {
+		var sum float64
+		for _, b := range bands {
+			sum += b[i]
+		}
+		require.InDelta(t, expect[i], sum, 1e-9)
+	}
+
+	x.Reset()
+	require.Error(t, x.Process(bands[:2], in, numSamples))
+}
+
+func TestAllPass(t *testing.T) {
+	sections, err := iir.Butterworth(iir.Spec{Type: filter.LowPass, Order: 5, SampleRate: 48000, Cutoffs: []float64{3000}})
+	require.NoError(t, err)
+
+	freqs := []float64{0, 0.01, 0.05, 0.1, 0.3, 0.49}
+	for _, s := range sections {
+		for _, v := range responseAt(t, []iir.Biquad{allPass(s)}, freqs) {
+			require.InDelta(t, 1, cmplx.Abs(v), 1e-12)
+		}
+	}
+	// No extra delay at DC.
+	v := responseAt(t, []iir.Biquad{allPass(sections[len(sections)-1])}, []float64{0})
+	require.InDelta(t, 1, real(v[0]), 1e-12)
+}
+
+func TestLinkwitzRileyErrors(t *testing.T) {
+	for _, v := range []struct {
+		sampleRate  float64
+		frequencies []float64
+		slope       Slope
+		opts        []Option
+	}{
+		{sampleRate: 0, frequencies: []float64{1000}, slope: LR4},
+		{sampleRate: 48000, frequencies: nil, slope: LR4},
+		{sampleRate: 48000, frequencies: []float64{100, 200, 400, 800, 1600}, slope: LR4},
+		{sampleRate: 48000, frequencies: []float64{2000, 1000}, slope: LR4},
+		{sampleRate: 48000, frequencies: []float64{1000, 1000}, slope: LR4},
+		{sampleRate: 48000, frequencies: []float64{24000}, slope: LR4},
+		{sampleRate: 48000, frequencies: []float64{-10}, slope: LR4},
+		{sampleRate: 48000, frequencies: []float64{1000}, slope: 6},
+		{sampleRate: 48000, frequencies: []float64{1000}, slope: LR4, opts: []Option{Channels(0)}},
+	} {
+		_, err := NewLinkwitzRiley(v.sampleRate, v.frequencies, v.slope, v.opts...)
+		require.Error(t, err, "%+v", v)
+	}
+}
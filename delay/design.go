@@ -0,0 +1,120 @@
+// Package delay provides filters that delay a signal by a fraction of a
+// sample, and a delay line whose delay can vary smoothly over time.
+package delay
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/brettbuddin/fourier/window"
+)
+
+// WindowedSinc returns a numTaps-long FIR filter that delays by delay
+// samples: a sinc function centered on the delay, tapered by wf centered on
+// the delay too, or by the Blackman window if wf is nil. The filter is most
+// accurate with the delay near the middle of the kernel, (numTaps-1)/2, and
+// is normalized to unity gain at DC.
+func WindowedSinc(delay float64, numTaps int, wf window.Func) ([]float64, error) {
+	if numTaps < 2 {
+		return nil, errors.New("number of taps must be at least 2")
+	}
+	if delay < 0 || delay > float64(numTaps-1) {
+		return nil, fmt.Errorf("delay must be between 0 and %d samples", numTaps-1)
+	}
+	if wf == nil {
+		wf = window.Blackman
+	}
+
+	var (
+		h      = make([]float64, numTaps)
+		center = float64(numTaps-1) / 2
+		sum    float64
+	)
+	for i := range h {
+		var (
+			m = float64(i) - delay
+			x = m + center
+		)
+		if x < 0 || x > float64(numTaps-1) {
+			continue
+		}
+		h[i] = window.Sinc(m) * wf(x, numTaps-1)
+		sum += h[i]
+	}
+	for i := range h {
+		h[i] /= sum
+	}
+	return h, nil
+}
+
+// Lagrange returns the order+1 taps of a Lagrange interpolator that delays
+// by delay samples. Its response is maximally flat at DC, and it reproduces
+// polynomials up to the given order exactly. It's most accurate with the
+// delay near order/2.
+func Lagrange(delay float64, order int) ([]float64, error) {
+	if order < 1 {
+		return nil, errors.New("order must be at least 1")
+	}
+	if delay < 0 || delay > float64(order) {
+		return nil, fmt.Errorf("delay must be between 0 and %d samples", order)
+	}
+	h := make([]float64, order+1)
+	lagrange(h, delay)
+	return h, nil
+}
+
+// lagrange fills h with the taps of a Lagrange interpolator of order
+// len(h)-1 that delays by delay samples.
+func lagrange(h []float64, delay float64) {
+	for n := range h {
+		v := 1.0
+		for k := range h {
+			if k != n {
+				v *= (delay - float64(k)) / float64(n-k)
+			}
+		}
+		h[n] = v
+	}
+}
+
+// Thiran returns the coefficients of an all-pass filter of the given order
+// that delays by delay samples, with a group delay that is maximally flat at
+// DC. a is the denominator, with a[0] = 1, and b the numerator, which is a
+// reversed. The filter is stable for delays greater than order-1 and works
+// best with the delay close to order.
+//
+// Reference: J.-P. Thiran, "Recursive Digital Filters with Maximally Flat
+// Group Delay", IEEE Transactions on Circuit Theory, 1971.
+func Thiran(delay float64, order int) (b, a []float64, err error) {
+	if order < 1 {
+		return nil, nil, errors.New("order must be at least 1")
+	}
+	if delay <= float64(order-1) {
+		return nil, nil, fmt.Errorf("delay must be greater than %d samples", order-1)
+	}
+
+	a = make([]float64, order+1)
+	for k := range a {
+		v := binomial(order, k)
+		if k%2 == 1 {
+			v = -v
+		}
+		for n := 0; n <= order; n++ {
+			v *= (delay - float64(order) + float64(n)) / (delay - float64(order) + float64(k) + float64(n))
+		}
+		a[k] = v
+	}
+	b = make([]float64, order+1)
+	for k := range b {
+		b[k] = a[order-k]
+	}
+	return b, a, nil
+}
+
+func binomial(n, k int) float64 {
+	v := 1.0
+	for i := 1; i <= k; i++ {
+		v = v * float64(n-k+i) / float64(i)
+	}
+	return v
+}
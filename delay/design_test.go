@@ -0,0 +1,109 @@
+package delay
+
+import (
+	"math"
+	"testing"
+
+	"github.com/brettbuddin/fourier/filter"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindowedSinc(t *testing.T) {
+	// An integer delay is an impulse.
+	h, err := WindowedSinc(5, 11, nil)
+	require.NoError(t, err)
+	for i, v := range h {
+		if i == 5 {
+			require.InDelta(t, 1, v, 1e-12)
+		} else {
+			require.InDelta(t, 0, v, 1e-12)
+		}
+	}
+
+	for _, delay := range []float64{15.25, 15.5, 16.8} {
+		h, err := WindowedSinc(delay, 32, nil)
+		require.NoError(t, err)
+		r, err := filter.ResponseAt(h, nil, []float64{0.01, 0.1, 0.2})
+		require.NoError(t, err)
+		for i, d := range r.GroupDelay() {
+			require.InDelta(t, delay, d, 1e-2)
+			require.InDelta(t, 0, r.MagnitudeDB()[i], 0.01)
+		}
+	}
+
+	_, err = WindowedSinc(12, 11, nil)
+	require.Error(t, err)
+	_, err = WindowedSinc(0.5, 1, nil)
+	require.Error(t, err)
+}
+
+func TestLagrange(t *testing.T) {
+	h, err := Lagrange(2, 4)
+	require.NoError(t, err)
+	require.Equal(t, []float64{0, 0, 1, 0, 0}, h)
+
+	// A Lagrange interpolator reproduces polynomials up to its order.
+	poly := func(x float64) float64 { return 2 - x + 0.5*x*x - 0.25*x*x*x }
+	for _, delay := range []float64{0.3, 1.5, 2.7} {
+		h, err := Lagrange(delay, 3)
+		require.NoError(t, err)
+
+		// Sample the polynomial with the newest sample at x = 10.
+		var y float64
+		for k, v := range h {
+			y += v * poly(10-float64(k))
+		}
+		require.InDelta(t, poly(10-delay), y, 1e-9)
+	}
+
+	_, err = Lagrange(5, 4)
+	require.Error(t, err)
+	_, err = Lagrange(0, 0)
+	require.Error(t, err)
+}
+
+func TestThiran(t *testing.T) {
+	for _, v := range []struct {
+		delay float64
+		order int
+	}{
+		{0.6, 1},
+		{1.3, 1},
+		{2.4, 2},
+		{3.1, 3},
+		{4.5, 4},
+	} {
+		b, a, err := Thiran(v.delay, v.order)
+		require.NoError(t, err)
+		require.Equal(t, 1.0, a[0])
+		for k := range a {
+			require.Equal(t, a[k], b[v.order-k])
+		}
+
+		freqs := []float64{0, 0.01, 0.05, 0.2, 0.45}
+		r, err := filter.ResponseAt(b, a, freqs)
+		require.NoError(t, err)
+		for i, m := range r.Magnitude() {
+			require.InDelta(t, 1, m, 1e-12, "%+v at %g", v, freqs[i])
+		}
+		require.InDelta(t, v.delay, r.GroupDelay()[0], 1e-9, "%+v", v)
+		require.InDelta(t, v.delay, r.GroupDelay()[1], 1e-3, "%+v", v)
+	}
+
+	// The first-order filter has the textbook coefficient.
+	_, a, err := Thiran(0.5, 1)
+	require.NoError(t, err)
+	require.InDelta(t, 1.0/3, a[1], 1e-12)
+
+	_, _, err = Thiran(1, 2)
+	require.Error(t, err)
+	_, _, err = Thiran(1, 0)
+	require.Error(t, err)
+}
+
+func TestBinomial(t *testing.T) {
+	require.Equal(t, 1.0, binomial(5, 0))
+	require.Equal(t, 10.0, binomial(5, 2))
+	require.Equal(t, 252.0, binomial(10, 5))
+	require.True(t, math.Abs(binomial(30, 15)-155117520) < 1e-6)
+}
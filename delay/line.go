@@ -0,0 +1,337 @@
+package delay
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/brettbuddin/fourier/window"
+)
+
+// Interpolation is the way a Line reads between samples.
+type Interpolation int
+
+// Interpolations, from cheapest to most accurate at high frequencies.
+const (
+	// Linear interpolates between the two nearest samples. It attenuates
+	// high frequencies, most of all halfway between samples.
+	Linear Interpolation = iota
+	// Cubic interpolates through the four nearest samples with a third-order
+	// Lagrange interpolator. Delays must be at least 1 sample.
+	Cubic
+	// AllPass interpolates with a first-order Thiran all-pass filter, which
+	// passes every frequency at full level but, being recursive, smears
+	// sudden jumps in delay. Delays must be at least 0.5 samples.
+	AllPass
+	// Sinc interpolates through the 16 nearest samples with a
+	// Kaiser-windowed sinc function. Delays must be at least 7 samples.
+	Sinc
+)
+
+func (i Interpolation) String() string {
+	switch i {
+	case Linear:
+		return "linear"
+	case Cubic:
+		return "cubic"
+	case AllPass:
+		return "all-pass"
+	case Sinc:
+		return "sinc"
+	default:
+		return fmt.Sprintf("Interpolation(%d)", int(i))
+	}
+}
+
+// minDelay returns the shortest delay the interpolation can produce without
+// reading samples that haven't arrived yet.
+func (i Interpolation) minDelay() float64 {
+	switch i {
+	case Cubic:
+		return 1
+	case AllPass:
+		return 0.5
+	case Sinc:
+		return sincTaps/2 - 1
+	default:
+		return 0
+	}
+}
+
+// The Sinc interpolation's kernel is tabulated at sincPhases points per
+// sample across sincTaps samples, tapered by a Kaiser window with sincBeta.
+const (
+	sincTaps   = 16
+	sincPhases = 512
+	sincBeta   = 8.6
+)
+
+// Line is a delay line whose delay, in fractions of a sample, can change over
+// time. Changes made with SetDelay glide linearly to the new delay, and
+// Modulate takes a delay for every sample.
+type Line struct {
+	interp   Interpolation
+	maxDelay float64
+
+	buf  []float64
+	mask int
+	pos  int
+
+	delay     float64
+	step      float64
+	remaining int
+	glide     int
+
+	// allPass holds the last output of the AllPass interpolation.
+	allPass float64
+	// table holds the Sinc interpolation's kernel.
+	table []float64
+
+	channel, numChannels int
+}
+
+// NewLine returns a Line that delays by delay samples, up to maxDelay.
+func NewLine(delay, maxDelay float64, interp Interpolation, opts ...Option) (*Line, error) {
+	if interp < Linear || interp > Sinc {
+		return nil, fmt.Errorf("unknown interpolation %v", interp)
+	}
+	if maxDelay < interp.minDelay() || math.IsInf(maxDelay, 0) || math.IsNaN(maxDelay) {
+		return nil, fmt.Errorf("maximum delay must be at least %g samples for %v interpolation", interp.minDelay(), interp)
+	}
+
+	l := &Line{
+		interp:      interp,
+		maxDelay:    maxDelay,
+		glide:       DefaultGlide,
+		numChannels: 1,
+	}
+	for _, opt := range opts {
+		if err := opt(l); err != nil {
+			return nil, err
+		}
+	}
+	if err := l.validate(delay); err != nil {
+		return nil, err
+	}
+	l.delay = delay
+
+	size := 1
+	for size < int(math.Ceil(maxDelay))+sincTaps {
+		size *= 2
+	}
+	l.buf = make([]float64, size)
+	l.mask = size - 1
+
+	if interp == Sinc {
+		var (
+			n  = sincTaps * sincPhases
+			wf = window.Kaiser(sincBeta)
+		)
+		l.table = make([]float64, n+1)
+		for i := range l.table {
+			l.table[i] = window.Sinc(float64(i-n/2)/sincPhases) * wf(float64(i), n)
+		}
+	}
+	return l, nil
+}
+
+func (l *Line) validate(delay float64) error {
+	if min := l.interp.minDelay(); delay < min || delay > l.maxDelay || math.IsNaN(delay) {
+		return fmt.Errorf("delay %g must be between %g and %g samples", delay, min, l.maxDelay)
+	}
+	return nil
+}
+
+// Delay returns the current delay, in samples.
+func (l *Line) Delay() float64 {
+	return l.delay
+}
+
+// SetDelay sets the delay, in samples, which glides there from the current
+// delay over the number of samples given by Glide.
+func (l *Line) SetDelay(delay float64) error {
+	if err := l.validate(delay); err != nil {
+		return err
+	}
+	if l.glide == 0 {
+		l.delay, l.remaining = delay, 0
+		return nil
+	}
+	l.step = (delay - l.delay) / float64(l.glide)
+	l.remaining = l.glide
+	return nil
+}
+
+// Tick delays a single sample.
+func (l *Line) Tick(x float64) float64 {
+	if l.remaining > 0 {
+		l.delay += l.step
+		l.remaining--
+	}
+	return l.tick(x, l.delay)
+}
+
+func (l *Line) tick(x, delay float64) float64 {
+	l.pos = (l.pos + 1) & l.mask
+	l.buf[l.pos] = x
+	return l.read(delay)
+}
+
+// sample returns the sample k samples before the newest one.
+func (l *Line) sample(k int) float64 {
+	return l.buf[(l.pos-k)&l.mask]
+}
+
+func (l *Line) read(delay float64) float64 {
+	var (
+		i    = int(delay)
+		frac = delay - float64(i)
+	)
+	switch l.interp {
+	case Cubic:
+		// Interpolate between the samples either side of the delay, at 1+frac
+		// along the four from i-1 to i+2.
+		var h [4]float64
+		lagrange(h[:], 1+frac)
+		return h[0]*l.sample(i-1) + h[1]*l.sample(i) + h[2]*l.sample(i+1) + h[3]*l.sample(i+2)
+	case AllPass:
+		// Keep the fractional part between 0.5 and 1.5, where the filter's
+		// delay is most accurate.
+		m := int(delay - 0.5)
+		var (
+			d   = delay - float64(m)
+			eta = (1 - d) / (1 + d)
+		)
+		l.allPass = eta*l.sample(m) + l.sample(m+1) - eta*l.allPass
+		return l.allPass
+	case Sinc:
+		var (
+			center = sincTaps * sincPhases / 2
+			sum    float64
+		)
+		for k := 1 - sincTaps/2; k <= sincTaps/2; k++ {
+			var (
+				u = (float64(k)-frac)*sincPhases + float64(center)
+				j = int(u)
+				w float64
+			)
+			if j < 0 {
+				continue
+			}
+			if j >= len(l.table)-1 {
+				w = l.table[len(l.table)-1]
+			} else {
+				w = l.table[j] + (u-float64(j))*(l.table[j+1]-l.table[j])
+			}
+			sum += w * l.sample(i+k)
+		}
+		return sum
+	default:
+		return (1-frac)*l.sample(i) + frac*l.sample(i+1)
+	}
+}
+
+// Process delays numSamples samples of in into out, with the delay gliding as
+// set by SetDelay. Both buffers are interleaved with the number of channels
+// given by ForChannel; the other channels of out are left untouched. in and
+// out may be the same buffer.
+func (l *Line) Process(out, in []float64, numSamples int) error {
+	if err := l.validateBuffers(out, in, numSamples); err != nil {
+		return err
+	}
+	for i := 0; i < numSamples; i++ {
+		idx := i*l.numChannels + l.channel
+		out[idx] = l.Tick(in[idx])
+	}
+	return nil
+}
+
+// Modulate delays numSamples samples of in into out like Process, but by the
+// delay given for each sample in delays. Any glide in progress is cancelled,
+// and the delay is left at the last one given.
+func (l *Line) Modulate(out, in, delays []float64, numSamples int) error {
+	if err := l.validateBuffers(out, in, numSamples); err != nil {
+		return err
+	}
+	if len(delays) < numSamples {
+		return fmt.Errorf("delays must hold %d values", numSamples)
+	}
+	for _, d := range delays[:numSamples] {
+		if err := l.validate(d); err != nil {
+			return err
+		}
+	}
+	for i, d := range delays[:numSamples] {
+		idx := i*l.numChannels + l.channel
+		out[idx] = l.tick(in[idx], d)
+	}
+	if numSamples > 0 {
+		l.delay = delays[numSamples-1]
+		l.remaining = 0
+	}
+	return nil
+}
+
+func (l *Line) validateBuffers(out, in []float64, numSamples int) error {
+	if numSamples < 0 {
+		return errors.New("number of samples cannot be negative")
+	}
+	if numSamples == 0 {
+		return nil
+	}
+	if need := (numSamples-1)*l.numChannels + l.channel + 1; len(in) < need || len(out) < need {
+		return fmt.Errorf("buffers must hold %d samples", need)
+	}
+	return nil
+}
+
+// Reset clears the delay line, and finishes any glide in progress.
+func (l *Line) Reset() {
+	for i := range l.buf {
+		l.buf[i] = 0
+	}
+	l.allPass = 0
+	if l.remaining > 0 {
+		l.delay += l.step * float64(l.remaining)
+		l.remaining = 0
+	}
+}
+
+// DefaultGlide is the number of samples a Line's delay takes to glide to a
+// new delay by default.
+const DefaultGlide = 256
+
+// Option is a configuration option for Line.
+type Option func(*Line) error
+
+// ForChannel configures a Line to delay a specific channel when the buffers
+// contain multiple interleaved channels.
+func ForChannel(channel, numChannels int) Option {
+	return func(l *Line) error {
+		if channel < 0 {
+			return errors.New("channel cannot be negative")
+		}
+		if numChannels < 1 {
+			return errors.New("number of channels cannot be less than 1")
+		}
+		if channel >= numChannels {
+			return errors.New("channel out of range of total number of channels")
+		}
+		l.channel = channel
+		l.numChannels = numChannels
+		return nil
+	}
+}
+
+// Glide sets the number of samples a Line's delay takes to glide to a new
+// delay set with SetDelay. Zero changes the delay immediately. The default is
+// DefaultGlide.
+func Glide(samples int) Option {
+	return func(l *Line) error {
+		if samples < 0 {
+			return errors.New("glide cannot be negative")
+		}
+		l.glide = samples
+		return nil
+	}
+}
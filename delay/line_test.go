@@ -0,0 +1,178 @@
+package delay
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLineConstantDelay(t *testing.T) {
+	const freq = 0.02
+	for _, v := range []struct {
+		interp    Interpolation
+		tolerance float64
+	}{
+		{Linear, 2e-3},
+		{Cubic, 1e-4},
+		{AllPass, 1e-3},
+		{Sinc, 1e-4},
+	} {
+		for _, delay := range []float64{10, 10.25, 10.5, 12.9} {
+			l, err := NewLine(delay, 20, v.interp)
+			require.NoError(t, err)
+			require.Equal(t, delay, l.Delay())
+
+			var maxErr float64
+			for n := 0; n < 2000; n++ {
+				y := l.Tick(math.Sin(2 * math.Pi * freq * float64(n)))
+				if n < 100 {
+					continue
+				}
+				expect := math.Sin(2 * math.Pi * freq * (float64(n) - delay))
+				maxErr = math.Max(maxErr, math.Abs(y-expect))
+			}
+			require.True(t, maxErr < v.tolerance, "%v at %g: error %g", v.interp, delay, maxErr)
+		}
+	}
+}
+
+func TestLineIntegerDelay(t *testing.T) {
+	for _, interp := range []Interpolation{Linear, Cubic, Sinc} {
+		l, err := NewLine(8, 16, interp)
+		require.NoError(t, err)
+		for n := 0; n < 40; n++ {
+			y := l.Tick(float64(n + 1))
+			var expect float64
+			if n >= 8 {
+				expect = float64(n - 7)
+			}
+			require.InDelta(t, expect, y, 1e-12, "%v", interp)
+		}
+	}
+}
+
+func TestLineGlide(t *testing.T) {
+	const (
+		freq  = 0.01
+		glide = 1000
+	)
+	l, err := NewLine(5, 50, Cubic, Glide(glide))
+	require.NoError(t, err)
+
+	x := func(n float64) float64 { return math.Sin(2 * math.Pi * freq * n) }
+	for n := 0; n < 100; n++ {
+		l.Tick(x(float64(n)))
+	}
+	require.NoError(t, l.SetDelay(25))
+
+	// The delay ramps from 5 to 25 samples, and the output follows it.
+	var maxErr float64
+	for n := 100; n < 100+glide+100; n++ {
+		y := l.Tick(x(float64(n)))
+		delay := 5 + 20*math.Min(float64(n-99), glide)/glide
+		require.InDelta(t, delay, l.Delay(), 1e-9)
+		maxErr = math.Max(maxErr, math.Abs(y-x(float64(n)-delay)))
+	}
+	require.True(t, maxErr < 1e-4, "error %g", maxErr)
+
+	// Without a glide, the delay jumps.
+	l, err = NewLine(5, 50, Linear, Glide(0))
+	require.NoError(t, err)
+	require.NoError(t, l.SetDelay(7.5))
+	require.Equal(t, 7.5, l.Delay())
+
+	// Reset finishes the glide.
+	l, err = NewLine(5, 50, Linear)
+	require.NoError(t, err)
+	require.NoError(t, l.SetDelay(9))
+	l.Tick(1)
+	l.Reset()
+	require.InDelta(t, 9, l.Delay(), 1e-12)
+	require.Equal(t, 0.0, l.Tick(0))
+}
+
+func TestLineModulate(t *testing.T) {
+	const (
+		freq       = 0.01
+		numSamples = 4000
+	)
+	var (
+		in     = make([]float64, 2*numSamples)
+		out    = make([]float64, 2*numSamples)
+		delays = make([]float64, numSamples)
+	)
+	for n := 0; n < numSamples; n++ {
+		in[2*n] = math.Sin(2 * math.Pi * freq * float64(n))
+		in[2*n+1] = 42
+		out[2*n+1] = 42
+		delays[n] = 20 + 10*math.Sin(2*math.Pi*float64(n)/1000)
+	}
+
+	for _, interp := range []Interpolation{Cubic, Sinc} {
+		l, err := NewLine(20, 40, interp, ForChannel(0, 2))
+		require.NoError(t, err)
+
+		// Modulating in blocks matches modulating all at once.
+		for pos := 0; pos < numSamples; pos += 64 {
+			n := numSamples - pos
+			if n > 64 {
+				n = 64
+			}
+			require.NoError(t, l.Modulate(out[2*pos:], in[2*pos:], delays[pos:], n))
+		}
+		require.Equal(t, delays[numSamples-1], l.Delay())
+
+		var maxErr float64
+		for n := 100; n < numSamples; n++ {
+			expect := math.Sin(2 * math.Pi * freq * (float64(n) - delays[n]))
+			maxErr = math.Max(maxErr, math.Abs(out[2*n]-expect))
+			require.Equal(t, 42.0, out[2*n+1])
+		}
+		require.True(t, maxErr < 1e-4, "%v: error %g", interp, maxErr)
+	}
+}
+
+func TestLineProcess(t *testing.T) {
+	var (
+		a, _ = NewLine(3.3, 10, Cubic, Glide(10))
+		b, _ = NewLine(3.3, 10, Cubic, Glide(10))
+		in   = make([]float64, 100)
+		out  = make([]float64, 100)
+	)
+	for i := range in {
+		in[i] = math.Cos(float64(i) / 3)
+	}
+	require.NoError(t, a.SetDelay(6))
+	require.NoError(t, b.SetDelay(6))
+	require.NoError(t, a.Process(out, in, len(in)))
+	for i, v := range in {
+		require.Equal(t, b.Tick(v), out[i])
+	}
+}
+
+func TestLineErrors(t *testing.T) {
+	_, err := NewLine(1, 10, Interpolation(9))
+	require.Error(t, err)
+	_, err = NewLine(0.5, 10, Cubic)
+	require.Error(t, err)
+	_, err = NewLine(5, 10, Sinc)
+	require.Error(t, err)
+	_, err = NewLine(5, 4, Linear)
+	require.Error(t, err)
+	_, err = NewLine(5, 5, Sinc)
+	require.Error(t, err)
+	_, err = NewLine(1, 10, Linear, ForChannel(2, 2))
+	require.Error(t, err)
+	_, err = NewLine(1, 10, Linear, Glide(-1))
+	require.Error(t, err)
+
+	l, err := NewLine(1, 10, Linear)
+	require.NoError(t, err)
+	require.Error(t, l.SetDelay(11))
+	require.Error(t, l.SetDelay(-1))
+	require.Error(t, l.Modulate(make([]float64, 4), make([]float64, 4), []float64{1, 2, 3, 12}, 4))
+	require.Error(t, l.Modulate(make([]float64, 4), make([]float64, 4), []float64{1, 2}, 4))
+	require.Error(t, l.Process(make([]float64, 2), make([]float64, 4), 4))
+	require.Error(t, l.Process(nil, nil, -1))
+}
@@ -0,0 +1,74 @@
+package fourier_test
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/brettbuddin/fourier"
+	"github.com/brettbuddin/fourier/filter"
+	"github.com/brettbuddin/fourier/window"
+)
+
+func ExampleConvolver_filtering() {
+	var (
+		blockSize  = 256
+		sampleRate = 320.0
+		cutoff     = 30.0
+		in         = make([]float64, blockSize)
+	)
+
+	// Sum two cosine waves, one at 10Hz and another at 90Hz
+	carrier(in, 10.0, sampleRate)
+	carrier(in, 90.0, sampleRate)
+
+	// Calculate bins with high magnitude before filtering
+	spikesBefore := detectSpikes(in)
+
+	// Build a filter kernel that filters frequencies higher than 30Hz at 320Hz
+	// sampling rate and convolve the summed signal with it.
+	kernel, _ := filter.Design(filter.Spec{
+		Type:       filter.LowPass,
+		SampleRate: sampleRate,
+		Cutoffs:    []float64{cutoff},
+		Window:     window.Lanczos,
+	}, 32)
+	conv, _ := fourier.NewConvolver(blockSize, kernel)
+
+	out := make([]float64, blockSize)
+	conv.Convolve(out, in, len(out))
+
+	// Calculate bins with high magnitude after filtering
+	spikesAfter := detectSpikes(out)
+
+	fmt.Println("spikes at (before):", spikesBefore)
+	fmt.Println("spikes at (after):", spikesAfter)
+	// Output: spikes at (before): [8 72 184 248]
+	// spikes at (after): [8 248]
+}
+
+func magnitude(src []float64) []float64 {
+	dest := make([]float64, len(src))
+	freq := make([]complex128, len(src))
+	for i, v := range src {
+		freq[i] = complex(v, 0)
+	}
+	fourier.Forward(freq)
+	fourier.Magnitude(dest, freq)
+	return dest
+}
+
+func detectSpikes(buf []float64) []int {
+	var spikes []int
+	for i, v := range magnitude(buf) {
+		if v > 0.2 {
+			spikes = append(spikes, i)
+		}
+	}
+	return spikes
+}
+
+func carrier(dest []float64, fc, fs float64) {
+	for i := 0; i < len(dest); i++ {
+		dest[i] += math.Cos((float64(i) * 2 * math.Pi * fc) / fs)
+	}
+}
@@ -0,0 +1,432 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Band is one band of an equiripple filter specification. Frequencies are
+// fractions of the sample rate, so Nyquist is 0.5.
+type Band struct {
+	// Low and High are the edges of the band.
+	Low, High float64
+
+	// Gain is the desired gain across the band. For a Differentiator, the
+	// desired gain rises linearly with frequency and Gain is its slope: the
+	// desired gain at frequency f is Gain·f, so a Gain of 2π approximates the
+	// derivative.
+	Gain float64
+
+	// Weight is the importance of the error in this band relative to the
+	// others. A zero Weight is taken as 1.
+	Weight float64
+}
+
+// EquirippleType is the kind of filter designed by MakeEquiripple.
+type EquirippleType int
+
+// Kinds of equiripple filter.
+const (
+	// Multiband is a linear-phase filter with a symmetric kernel and any
+	// number of pass and stop bands: low-pass, high-pass, band-pass,
+	// band-reject and so on. A kernel with an even number of taps always has
+	// zero gain at Nyquist.
+	Multiband EquirippleType = iota
+
+	// Differentiator is a filter with an antisymmetric kernel whose gain rises
+	// linearly with frequency and whose phase is shifted by +90°.
+	Differentiator
+
+	// Hilbert is a Hilbert transformer: a filter with an antisymmetric kernel
+	// and a phase shift of -90°. It always has zero gain at DC, and at Nyquist
+	// when the kernel has an odd number of taps.
+	Hilbert
+)
+
+const (
+	// equirippleDensity is the number of grid frequencies per extremal
+	// frequency.
+	equirippleDensity = 16
+
+	// equirippleIterations is the most exchanges MakeEquiripple makes before
+	// giving up.
+	equirippleIterations = 40
+)
+
+// MakeEquiripple creates an optimal (minimax) FIR filter impulse response with
+// the Parks-McClellan algorithm. The kernel minimizes the largest weighted
+// difference between its gain and the desired gain across the bands; the
+// error ripples with equal height through each band. Frequencies outside the
+// bands are transition bands where the gain is left unconstrained.
+//
+// Bands must lie between 0 and 0.5 in increasing order without overlapping.
+// It returns an error if the specification is invalid or the algorithm fails
+// to converge, which can happen when transition bands are very narrow or very
+// wide for the number of taps.
+//
+// Reference: J. H. McClellan, T. W. Parks and L. R. Rabiner, "A Computer
+// Program for Designing Optimum FIR Linear Phase Digital Filters", IEEE
+// Transactions on Audio and Electroacoustics, 1973.
+func MakeEquiripple(h []float64, bands []Band, kind EquirippleType) error {
+	if err := validateBands(bands, kind); err != nil {
+		return err
+	}
+
+	var (
+		numTaps       = len(h)
+		odd           = numTaps%2 == 1
+		antisymmetric = kind != Multiband
+	)
+
+	// The gain of a linear-phase kernel is Q(f)·P(f), where P is a sum of
+	// cosines and Q depends on the symmetry and parity of the kernel. The
+	// exchange approximates D/Q by P with weight W·Q.
+	var (
+		numCosines = numTaps / 2
+		q          func(f float64) float64
+	)
+	switch {
+	case !antisymmetric && odd:
+		numCosines++
+		q = func(float64) float64 { return 1 }
+	case !antisymmetric:
+		q = func(f float64) float64 { return math.Cos(math.Pi * f) }
+	case odd:
+		q = func(f float64) float64 { return math.Sin(2 * math.Pi * f) }
+	default:
+		q = func(f float64) float64 { return math.Sin(math.Pi * f) }
+	}
+	if numCosines < 1 {
+		return fmt.Errorf("%d taps are too few for this kind of filter", numTaps)
+	}
+
+	g := newGrid(bands, kind, numCosines, odd != antisymmetric, antisymmetric)
+	if len(g.freqs) <= numCosines {
+		return errors.New("bands are too narrow for the number of taps")
+	}
+	for i, f := range g.freqs {
+		v := q(f)
+		g.desired[i] /= v
+		g.weights[i] *= v
+	}
+
+	p, err := exchange(g, numCosines)
+	if err != nil {
+		return err
+	}
+
+	// Recover the cosine coefficients of P from samples at the nodes of a
+	// DCT-II, where the cosines are orthogonal.
+	alpha := make([]float64, numCosines)
+	for m := 0; m < numCosines; m++ {
+		v := p(math.Cos(math.Pi * (float64(m) + 0.5) / float64(numCosines)))
+		for k := range alpha {
+			alpha[k] += v * math.Cos(math.Pi*float64(k)*(float64(m)+0.5)/float64(numCosines))
+		}
+	}
+	alpha[0] /= float64(numCosines)
+	for k := 1; k < numCosines; k++ {
+		alpha[k] *= 2 / float64(numCosines)
+	}
+	at := func(k int) float64 {
+		if k < 0 || k >= numCosines {
+			return 0
+		}
+		return alpha[k]
+	}
+
+	// Multiply out Q·P into a sum of cosines or sines and place the terms
+	// symmetrically about the center of the kernel.
+	center := numTaps / 2
+	switch {
+	case !antisymmetric && odd:
+		h[center] = alpha[0]
+		for k := 1; k < numCosines; k++ {
+			h[center-k] = alpha[k] / 2
+			h[center+k] = alpha[k] / 2
+		}
+	case !antisymmetric:
+		for n := 0; n < numCosines; n++ {
+			b := (at(n) + at(n+1)) / 2
+			if n == 0 {
+				b += alpha[0] / 2
+			}
+			h[center-1-n] = b / 2
+			h[center+n] = b / 2
+		}
+	case odd:
+		h[center] = 0
+		for n := 1; n <= numCosines; n++ {
+			c := (at(n-1) - at(n+1)) / 2
+			if n == 1 {
+				c += alpha[0] / 2
+			}
+			h[center-n] = c / 2
+			h[center+n] = -c / 2
+		}
+	default:
+		for n := 0; n < numCosines; n++ {
+			c := (at(n) - at(n+1)) / 2
+			if n == 0 {
+				c += alpha[0] / 2
+			}
+			h[center-1-n] = c / 2
+			h[center+n] = -c / 2
+		}
+	}
+
+	// The kernels above shift the phase by +90°; a Hilbert transformer shifts
+	// it by -90°.
+	if kind == Hilbert {
+		for i := range h {
+			h[i] = -h[i]
+		}
+	}
+	return nil
+}
+
+func validateBands(bands []Band, kind EquirippleType) error {
+	if kind < Multiband || kind > Hilbert {
+		return fmt.Errorf("unknown equiripple filter type %d", kind)
+	}
+	if len(bands) == 0 {
+		return errors.New("at least one band is required")
+	}
+	for i, b := range bands {
+		if b.Low < 0 || b.High > 0.5 || b.Low >= b.High {
+			return fmt.Errorf("band %d (%g-%g) must have edges between 0 and 0.5, lowest first", i, b.Low, b.High)
+		}
+		if i > 0 && b.Low < bands[i-1].High {
+			return fmt.Errorf("band %d overlaps band %d", i, i-1)
+		}
+		if b.Weight < 0 {
+			return fmt.Errorf("band %d has a negative weight", i)
+		}
+	}
+	return nil
+}
+
+// grid is the dense set of frequencies the exchange evaluates the error at.
+type grid struct {
+	freqs, desired, weights []float64
+	x                       []float64 // cos(2πf)
+	band                    []int
+}
+
+// newGrid spreads frequencies across the bands. Where the kernel's gain is
+// necessarily zero (at DC for antisymmetric kernels, and at Nyquist for the
+// kernels that aren't odd and symmetric) the frequency is left out.
+func newGrid(bands []Band, kind EquirippleType, numCosines int, nyquist, dc bool) *grid {
+	var (
+		g     = &grid{}
+		delta = 0.5 / float64(equirippleDensity*numCosines)
+	)
+	for i, b := range bands {
+		weight := b.Weight
+		if weight == 0 {
+			weight = 1
+		}
+
+		low := b.Low
+		if kind != Multiband && low == 0 {
+			low = math.Min(delta, b.High)
+		}
+		n := int(math.Ceil((b.High - low) / delta))
+		for j := 0; j <= n; j++ {
+			f := low + float64(j)*delta
+			if j == n {
+				f = b.High
+			}
+			if f >= 0.5 && !nyquist || f == 0 && dc {
+				continue
+			}
+
+			desired, w := b.Gain, weight
+			if kind == Differentiator {
+				desired = b.Gain * f
+				if b.Gain != 0 {
+					w /= f
+				}
+			}
+			g.freqs = append(g.freqs, f)
+			g.desired = append(g.desired, desired)
+			g.weights = append(g.weights, w)
+			g.x = append(g.x, math.Cos(2*math.Pi*f))
+			g.band = append(g.band, i)
+		}
+	}
+	return g
+}
+
+// exchange runs the Remez exchange algorithm, finding the polynomial in
+// x = cos(2πf) of degree numCosines-1 that minimizes the largest weighted
+// error on the grid. It returns a function that evaluates the polynomial.
+func exchange(g *grid, numCosines int) (func(x float64) float64, error) {
+	var (
+		numExtremal = numCosines + 1
+		extremal    = make([]int, numExtremal)
+		err         = make([]float64, len(g.x))
+		p           func(x float64) float64
+	)
+	for i := range extremal {
+		extremal[i] = i * (len(g.x) - 1) / numCosines
+	}
+
+	for iteration := 0; iteration < equirippleIterations; iteration++ {
+		var delta float64
+		p, delta = interpolate(g, extremal)
+		for i, x := range g.x {
+			err[i] = g.weights[i] * (g.desired[i] - p(x))
+		}
+
+		next, ok := extrema(g, err, numExtremal)
+		if !ok {
+			return nil, errors.New("equiripple design failed: the error doesn't alternate")
+		}
+
+		converged := true
+		for i := range next {
+			if next[i] != extremal[i] {
+				converged = false
+				break
+			}
+		}
+		if !converged {
+			var largest float64
+			for _, i := range next {
+				largest = math.Max(largest, math.Abs(err[i]))
+			}
+			converged = largest-math.Abs(delta) <= 1e-9*largest
+		}
+		extremal = next
+		if converged {
+			p, _ = interpolate(g, extremal)
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("equiripple design failed to converge in %d iterations", equirippleIterations)
+}
+
+// interpolate finds the polynomial whose weighted error alternates with equal
+// height δ at the extremal frequencies, returning it and δ.
+func interpolate(g *grid, extremal []int) (func(x float64) float64, float64) {
+	var (
+		n      = len(extremal)
+		x      = make([]float64, n)
+		coeffs = make([]float64, n)
+	)
+	for i, e := range extremal {
+		x[i] = g.x[e]
+	}
+
+	// Barycentric weights over all the extremal frequencies. Differences are
+	// doubled to keep the products from underflowing.
+	var num, den float64
+	for i := range x {
+		c := 1.0
+		for j := range x {
+			if j != i {
+				c *= 2 * (x[i] - x[j])
+			}
+		}
+		e := extremal[i]
+		sign := 1.0
+		if i%2 == 1 {
+			sign = -1
+		}
+		num += g.desired[e] / c
+		den += sign / (c * g.weights[e])
+	}
+	delta := num / den
+
+	// The polynomial through the first n-1 extremal frequencies, which passes
+	// through the last by the choice of δ.
+	y := make([]float64, n-1)
+	for i := 0; i < n-1; i++ {
+		e := extremal[i]
+		sign := 1.0
+		if i%2 == 1 {
+			sign = -1
+		}
+		y[i] = g.desired[e] - sign*delta/g.weights[e]
+
+		c := 1.0
+		for j := 0; j < n-1; j++ {
+			if j != i {
+				c *= 2 * (x[i] - x[j])
+			}
+		}
+		coeffs[i] = 1 / c
+	}
+
+	p := func(v float64) float64 {
+		var num, den float64
+		for i := 0; i < n-1; i++ {
+			d := v - x[i]
+			if math.Abs(d) < 1e-14 {
+				return y[i]
+			}
+			num += coeffs[i] * y[i] / d
+			den += coeffs[i] / d
+		}
+		return num / den
+	}
+	return p, delta
+}
+
+// extrema chooses n grid frequencies where the error is locally largest and
+// alternates in sign, preferring the largest errors. It reports false if fewer
+// than n alternating extrema exist.
+func extrema(g *grid, err []float64, n int) ([]int, bool) {
+	var candidates []int
+	for i, e := range err {
+		var (
+			left  = i > 0 && g.band[i-1] == g.band[i]
+			right = i < len(err)-1 && g.band[i+1] == g.band[i]
+		)
+		switch {
+		case e >= 0 && (!left || e >= err[i-1]) && (!right || e >= err[i+1]):
+		case e < 0 && (!left || e <= err[i-1]) && (!right || e <= err[i+1]):
+		default:
+			continue
+		}
+		candidates = append(candidates, i)
+	}
+
+	// Neighbours of the same sign are merged into the larger.
+	merge := func(c []int) []int {
+		merged := c[:0]
+		for _, i := range c {
+			if last := len(merged) - 1; last >= 0 && (err[i] >= 0) == (err[merged[last]] >= 0) {
+				if math.Abs(err[i]) > math.Abs(err[merged[last]]) {
+					merged[last] = i
+				}
+				continue
+			}
+			merged = append(merged, i)
+		}
+		return merged
+	}
+	candidates = merge(candidates)
+
+	// Drop the smallest extrema until n remain, keeping the signs alternating.
+	for len(candidates) > n {
+		if len(candidates) == n+1 {
+			if math.Abs(err[candidates[0]]) < math.Abs(err[candidates[n]]) {
+				candidates = candidates[1:]
+			} else {
+				candidates = candidates[:n]
+			}
+			break
+		}
+		smallest := 0
+		for j, i := range candidates {
+			if math.Abs(err[i]) < math.Abs(err[candidates[smallest]]) {
+				smallest = j
+			}
+		}
+		candidates = merge(append(candidates[:smallest], candidates[smallest+1:]...))
+	}
+	return candidates, len(candidates) == n
+}
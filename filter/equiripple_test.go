@@ -0,0 +1,129 @@
+package filter
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEquiripple_Published(t *testing.T) {
+	// Example 1 from McClellan, Parks and Rabiner (1973): a 32-tap band-pass
+	// filter with the stop bands weighted 10 times the pass band.
+	h := make([]float64, 32)
+	err := MakeEquiripple(h, []Band{
+		{Low: 0, High: 0.1, Gain: 0, Weight: 10},
+		{Low: 0.2, High: 0.35, Gain: 1, Weight: 1},
+		{Low: 0.425, High: 0.5, Gain: 0, Weight: 10},
+	}, Multiband)
+	require.NoError(t, err)
+
+	expected := []float64{
+		-0.57534026e-02, 0.99026691e-03, 0.75733471e-02, -0.65141204e-02,
+		0.13960509e-01, 0.22951644e-02, -0.19994041e-01, 0.71369656e-02,
+		-0.39657373e-01, 0.11260066e-01, 0.66233635e-01, -0.10497202e-01,
+		0.85136160e-01, -0.12024988e+00, -0.29678580e+00, 0.30410913e+00,
+	}
+	for i, v := range expected {
+		require.InDelta(t, v, h[i], 1e-6)
+		require.Equal(t, h[i], h[len(h)-1-i])
+	}
+
+	// The stop band error is a tenth of the pass band error, and the error
+	// ripples with the same height throughout each band.
+	r, err := NewResponse(h, nil, 8192)
+	require.NoError(t, err)
+	var (
+		magnitude = r.Magnitude()
+		pass      = maxError(r.Frequencies, magnitude, 0.2, 0.35, 1)
+		stop      = maxError(r.Frequencies, magnitude, 0, 0.1, 0)
+	)
+	require.InEpsilon(t, pass/10, stop, 0.02)
+	require.InEpsilon(t, stop, maxError(r.Frequencies, magnitude, 0.425, 0.5, 0), 0.02)
+}
+
+func TestEquiripple_Types(t *testing.T) {
+	lowPass := []Band{
+		{Low: 0, High: 0.2, Gain: 1},
+		{Low: 0.25, High: 0.5, Gain: 0},
+	}
+
+	for _, numTaps := range []int{61, 62} {
+		h := make([]float64, numTaps)
+		require.NoError(t, MakeEquiripple(h, lowPass, Multiband))
+		for i := range h {
+			require.Equal(t, h[i], h[numTaps-1-i])
+		}
+
+		r, err := NewResponse(h, nil, 4096)
+		require.NoError(t, err)
+		require.True(t, r.Ripple(0, 0.2) < 0.2)
+		require.True(t, r.Attenuation(0.25, 0.5) > 35)
+	}
+
+	// A Hilbert transformer shifts the phase by -90° across the band.
+	for _, numTaps := range []int{31, 32} {
+		h := make([]float64, numTaps)
+		require.NoError(t, MakeEquiripple(h, []Band{{Low: 0.05, High: 0.45, Gain: 1}}, Hilbert))
+		for i := range h {
+			require.Equal(t, h[i], -h[numTaps-1-i])
+		}
+
+		freqs := []float64{0.05, 0.1, 0.2, 0.3, 0.4, 0.45}
+		r, err := ResponseAt(h, nil, freqs)
+		require.NoError(t, err)
+		for k, f := range freqs {
+			// Remove the delay of the kernel, leaving the phase shift.
+			v := r.Values[k] * cmplx.Rect(1, 2*math.Pi*f*float64(numTaps-1)/2)
+			require.InDelta(t, 1, cmplx.Abs(v), 0.01)
+			require.InDelta(t, -math.Pi/2, cmplx.Phase(v), 1e-9)
+		}
+	}
+
+	// A differentiator's gain is 2πf with a phase shift of +90°.
+	for _, numTaps := range []int{31, 32} {
+		h := make([]float64, numTaps)
+		require.NoError(t, MakeEquiripple(h, []Band{{Low: 0, High: 0.4, Gain: 2 * math.Pi}}, Differentiator))
+
+		freqs := []float64{0.01, 0.1, 0.2, 0.3, 0.4}
+		r, err := ResponseAt(h, nil, freqs)
+		require.NoError(t, err)
+		for k, f := range freqs {
+			v := r.Values[k] * cmplx.Rect(1, 2*math.Pi*f*float64(numTaps-1)/2)
+			require.InEpsilon(t, 2*math.Pi*f, cmplx.Abs(v), 0.01)
+			require.InDelta(t, math.Pi/2, cmplx.Phase(v), 1e-9)
+		}
+	}
+}
+
+func TestEquiripple_Errors(t *testing.T) {
+	var (
+		h    = make([]float64, 31)
+		band = []Band{{Low: 0, High: 0.5, Gain: 1}}
+	)
+	for _, bands := range [][]Band{
+		nil,
+		{{Low: -0.1, High: 0.2}},
+		{{Low: 0.2, High: 0.6}},
+		{{Low: 0.2, High: 0.1}},
+		{{Low: 0, High: 0.2}, {Low: 0.1, High: 0.3}},
+		{{Low: 0, High: 0.2, Weight: -1}},
+	} {
+		require.Error(t, MakeEquiripple(h, bands, Multiband))
+	}
+	require.Error(t, MakeEquiripple(h, band, EquirippleType(10)))
+	require.Error(t, MakeEquiripple(make([]float64, 1), band, Hilbert))
+}
+
+// maxError returns the largest difference between a magnitude response and
+// the desired gain between two frequencies.
+func maxError(freqs, magnitude []float64, low, high, gain float64) float64 {
+	var largest float64
+	for k, f := range freqs {
+		if f >= low && f <= high {
+			largest = math.Max(largest, math.Abs(magnitude[k]-gain))
+		}
+	}
+	return largest
+}
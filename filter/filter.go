@@ -1,66 +1,323 @@
 // Package filter provides builders for designing kernels for common filter
 // types.
+//
+// Every kernel designed here has linear phase: it's symmetric or antisymmetric
+// about its center, so it delays every frequency by (len-1)/2 samples. The
+// symmetry and parity of a kernel decide which responses it can have:
+//
+//	Type I:   symmetric, odd length      any response
+//	Type II:  symmetric, even length     zero gain at Nyquist
+//	Type III: antisymmetric, odd length  zero gain at DC and Nyquist
+//	Type IV:  antisymmetric, even length zero gain at DC
+//
+// So high-pass and band-reject filters need an odd length, and differentiators
+// and Hilbert transformers are antisymmetric. SymmetryOf reports the type of a
+// kernel.
 package filter
 
 import (
+	"errors"
+	"fmt"
 	"math"
 
 	"github.com/brettbuddin/fourier/window"
 )
 
-// MakeLowPass creates a low-pass filter impulse response. It filters
-// frequencies higher than the cutoff frequency.
-func MakeLowPass(h []float64, wf window.Func, cutoff float64) {
+// Type is a kind of frequency-selective filter.
+type Type int
+
+// Types of filter.
+const (
+	LowPass Type = iota
+	HighPass
+	BandPass
+	BandReject
+)
+
+func (t Type) String() string {
+	switch t {
+	case LowPass:
+		return "low-pass"
+	case HighPass:
+		return "high-pass"
+	case BandPass:
+		return "band-pass"
+	case BandReject:
+		return "band-reject"
+	default:
+		return fmt.Sprintf("Type(%d)", int(t))
+	}
+}
+
+// numCutoffs returns the number of cutoff frequencies a type of filter takes,
+// or zero if the type is unknown.
+func (t Type) numCutoffs() int {
+	switch t {
+	case LowPass, HighPass:
+		return 1
+	case BandPass, BandReject:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// Spec describes a windowed-sinc filter.
+type Spec struct {
+	Type Type
+
+	// SampleRate is the sample rate in Hz.
+	SampleRate float64
+
+	// Cutoffs are the band edges in Hz: one for LowPass and HighPass, and
+	// two, lowest first, for BandPass and BandReject. They must be between 0
+	// and Nyquist.
+	Cutoffs []float64
+
+	// Window is the windowing function applied to the sinc. If it's nil,
+	// window.Blackman is used.
+	Window window.Func
+}
+
+// Design designs the filter described by the spec, returning a newly allocated
+// kernel of numTaps taps. The kernel is symmetric, and it's scaled for unity
+// gain at DC (LowPass and BandReject), Nyquist (HighPass) or the center of the
+// pass band (BandPass).
+//
+// It returns an error if the spec is invalid, or if numTaps is even for a
+// HighPass or BandReject filter, which can't pass Nyquist.
+func Design(s Spec, numTaps int) ([]float64, error) {
+	if numTaps <= 0 {
+		return nil, errors.New("number of taps must be positive")
+	}
+	h := make([]float64, numTaps)
+	if err := DesignInto(h, s); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// DesignInto is like Design but fills h with the kernel instead of allocating
+// one.
+func DesignInto(h []float64, s Spec) error {
+	if err := s.validate(len(h)); err != nil {
+		return err
+	}
+	cutoffs := make([]float64, len(s.Cutoffs))
+	for i, c := range s.Cutoffs {
+		cutoffs[i] = c / s.SampleRate
+	}
+	wf := s.Window
+	if wf == nil {
+		wf = window.Blackman
+	}
+	makeWindowed(h, s.Type, wf, cutoffs)
+	return nil
+}
+
+func (s Spec) validate(numTaps int) error {
+	n := s.Type.numCutoffs()
+	if n == 0 {
+		return fmt.Errorf("unknown filter type %v", s.Type)
+	}
+	if s.SampleRate <= 0 {
+		return errors.New("sample rate must be positive")
+	}
+	if len(s.Cutoffs) != n {
+		return fmt.Errorf("a %v filter takes %d cutoff frequencies", s.Type, n)
+	}
+	nyquist := s.SampleRate / 2
+	for i, c := range s.Cutoffs {
+		if c <= 0 || c >= nyquist {
+			return fmt.Errorf("cutoff %g Hz must be between 0 and %g Hz (Nyquist)", c, nyquist)
+		}
+		if i > 0 && c <= s.Cutoffs[i-1] {
+			return fmt.Errorf("cutoffs %g Hz and %g Hz must be in increasing order", s.Cutoffs[i-1], c)
+		}
+	}
+	if numTaps == 0 {
+		return errors.New("number of taps must be positive")
+	}
+	if numTaps%2 == 0 && (s.Type == HighPass || s.Type == BandReject) {
+		return fmt.Errorf("a %v filter needs an odd number of taps; with %d the gain at Nyquist is zero", s.Type, numTaps)
+	}
+	return nil
+}
+
+// makeWindowed fills h with a windowed-sinc kernel with cutoffs given as
+// fractions of the sample rate, scaled for unity gain in the pass band.
+func makeWindowed(h []float64, t Type, wf window.Func, cutoffs []float64) {
+	idealKernel(h, t, cutoffs)
+
 	n := len(h)
 	for i := range h {
-		x := 2 * math.Pi * cutoff
-		if i == n/2 {
-			h[i] = x
-		} else {
-			y := float64(i) - float64(n)/2
-			h[i] = (math.Sin(x*y) / y) * wf(float64(i), n)
+		if n > 1 {
+			h[i] *= wf(float64(i), n-1)
 		}
 	}
-	normalize(h)
+
+	// The frequency at which the gain should be unity.
+	var f float64
+	switch t {
+	case HighPass:
+		f = 0.5
+	case BandPass:
+		f = (cutoffs[0] + cutoffs[1]) / 2
+	}
+	var (
+		center = float64(n-1) / 2
+		gain   float64
+	)
+	for i, v := range h {
+		gain += v * math.Cos(2*math.Pi*f*(float64(i)-center))
+	}
+	if gain == 0 {
+		return
+	}
+	for i := range h {
+		h[i] /= gain
+	}
 }
 
-// MakeHighPass creates a high-pass filter impulse response. It filters
-// frequencies lower than the cutoff frequency.
-func MakeHighPass(h []float64, wf window.Func, cutoff float64) {
-	MakeLowPass(h, wf, cutoff)
+// idealKernel fills h with the ideal (brick-wall) impulse response of a filter,
+// truncated symmetrically about the center of h.
+func idealKernel(h []float64, t Type, cutoffs []float64) {
+	var (
+		center = float64(len(h)-1) / 2
+		odd    = len(h)%2 == 1
+	)
+	// lowPass is the response of an ideal low-pass filter m samples from the
+	// center.
+	lowPass := func(cutoff, m float64) float64 {
+		return 2 * cutoff * window.Sinc(2*cutoff*m)
+	}
 	for i := range h {
-		h[i] = -h[i]
+		m := float64(i) - center
+		switch t {
+		case LowPass:
+			h[i] = lowPass(cutoffs[0], m)
+		case HighPass:
+			h[i] = -lowPass(cutoffs[0], m)
+		case BandPass:
+			h[i] = lowPass(cutoffs[1], m) - lowPass(cutoffs[0], m)
+		case BandReject:
+			h[i] = lowPass(cutoffs[0], m) - lowPass(cutoffs[1], m)
+		}
+	}
+	if (t == HighPass || t == BandReject) && odd {
+		h[len(h)/2]++
 	}
 }
 
+// MakeLowPass creates a low-pass filter impulse response. It filters
+// frequencies higher than the cutoff frequency, given as a fraction of the
+// sample rate. The kernel is symmetric and scaled for unity gain at DC.
+//
+// The cutoff isn't checked; use Design for validation and cutoffs in Hz.
+func MakeLowPass(h []float64, wf window.Func, cutoff float64) {
+	makeWindowed(h, LowPass, wf, []float64{cutoff})
+}
+
+// MakeHighPass creates a high-pass filter impulse response. It filters
+// frequencies lower than the cutoff frequency, given as a fraction of the
+// sample rate. The kernel is symmetric and scaled for unity gain at Nyquist;
+// it should have an odd length, since an even-length kernel has zero gain
+// there.
+//
+// The cutoff isn't checked; use Design for validation and cutoffs in Hz.
+func MakeHighPass(h []float64, wf window.Func, cutoff float64) {
+	makeWindowed(h, HighPass, wf, []float64{cutoff})
+}
+
 // MakeBandReject creates a band-reject filter impulse response. It filters out
-// frequencies between the two stop frequencies.
+// frequencies between the two stop frequencies, given as fractions of the
+// sample rate, lowest first. The kernel is symmetric and scaled for unity gain
+// at DC; it should have an odd length, since an even-length kernel has zero
+// gain at Nyquist.
+//
+// The frequencies aren't checked; use Design for validation and frequencies
+// in Hz.
 func MakeBandReject(h []float64, wf window.Func, stop1, stop2 float64) {
-	a := make([]float64, len(h))
-	b := make([]float64, len(h))
-	MakeLowPass(a, wf, stop1)
-	MakeHighPass(b, wf, stop2)
-	for i := range h {
-		h[i] = a[i] + b[i]
-	}
+	makeWindowed(h, BandReject, wf, []float64{stop1, stop2})
 }
 
 // MakeBandPass creates a band-pass filter impulse response. It allows
-// frequences between the two stop frequencies.
+// frequencies between the two stop frequencies, given as fractions of the
+// sample rate, lowest first. The kernel is symmetric and scaled for unity gain
+// in the middle of the pass band.
+//
+// The frequencies aren't checked; use Design for validation and frequencies
+// in Hz.
 func MakeBandPass(h []float64, wf window.Func, stop1, stop2 float64) {
-	MakeBandReject(h, wf, stop1, stop2)
-	for i := range h {
-		h[i] = -h[i]
+	makeWindowed(h, BandPass, wf, []float64{stop1, stop2})
+}
+
+// Symmetry is the type of a linear-phase kernel.
+type Symmetry int
+
+// Kernel symmetries. See the package documentation for the responses each
+// allows.
+const (
+	// Asymmetric is a kernel without linear phase.
+	Asymmetric Symmetry = iota
+	TypeI
+	TypeII
+	TypeIII
+	TypeIV
+)
+
+func (s Symmetry) String() string {
+	switch s {
+	case Asymmetric:
+		return "asymmetric"
+	case TypeI:
+		return "type I"
+	case TypeII:
+		return "type II"
+	case TypeIII:
+		return "type III"
+	case TypeIV:
+		return "type IV"
+	default:
+		return fmt.Sprintf("Symmetry(%d)", int(s))
 	}
 }
 
-func normalize(w []float64) {
-	var sum float64
-	for i := range w {
-		sum += w[i]
+// SymmetryOf returns the type of linear-phase kernel h is, allowing for
+// rounding errors. A kernel of zeros is taken as symmetric.
+func SymmetryOf(h []float64) Symmetry {
+	var largest float64
+	for _, v := range h {
+		largest = math.Max(largest, math.Abs(v))
+	}
+	var (
+		tolerance     = 1e-12 * largest
+		symmetric     = true
+		antisymmetric = true
+	)
+	for i := range h {
+		a, b := h[i], h[len(h)-1-i]
+		if math.Abs(a-b) > tolerance {
+			symmetric = false
+		}
+		if math.Abs(a+b) > tolerance {
+			antisymmetric = false
+		}
 	}
-	scale := 1.0 / sum
-	for i := range w {
-		w[i] *= scale
+
+	odd := len(h)%2 == 1
+	switch {
+	case len(h) == 0:
+		return Asymmetric
+	case symmetric && odd:
+		return TypeI
+	case symmetric:
+		return TypeII
+	case antisymmetric && odd:
+		return TypeIII
+	case antisymmetric:
+		return TypeIV
+	default:
+		return Asymmetric
 	}
 }
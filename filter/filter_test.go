@@ -8,77 +8,118 @@ import (
 )
 
 func TestLowPass(t *testing.T) {
-	kernel := make([]float64, 10)
-	MakeLowPass(kernel, window.Blackman, 0.5)
+	kernel := make([]float64, 11)
+	MakeLowPass(kernel, window.Blackman, 0.25)
 
 	expected := []float64{
-		-5.409800153010306e-34,
-		-1.5675664736656203e-18,
-		7.826365172768749e-18,
-		-1.9872378605190914e-17,
-		3.310443906868464e-17,
-		1,
-		3.310443906868464e-17,
-		-1.9872378605190917e-17,
-		7.826365172768752e-18,
-		-1.567566473665619e-18,
+		-8.85229746863994e-19,
+		-7.853289444678119e-19,
+		-0.021344384465231653,
+		9.95578457198385e-18,
+		0.27085135668587784,
+		0.5009860555587077,
+		0.27085135668587784,
+		9.955784571983852e-18,
+		-0.021344384465231663,
+		-7.853289444678113e-19,
+		-8.85229746863994e-19,
 	}
-	require.InEpsilonSlice(t, expected, kernel, 1e-10)
+	require.InDeltaSlice(t, expected, kernel, 1e-12)
+	requireGains(t, kernel, []float64{0}, []float64{1})
 }
 
 func TestHighPass(t *testing.T) {
-	kernel := make([]float64, 10)
-	MakeHighPass(kernel, window.Blackman, 0.5)
-
-	expected := []float64{
-		5.409800153010306e-34,
-		1.5675664736656203e-18,
-		-7.826365172768749e-18,
-		1.9872378605190914e-17,
-		-3.310443906868464e-17,
-		-1,
-		-3.310443906868464e-17,
-		1.9872378605190917e-17,
-		-7.826365172768752e-18,
-		1.567566473665619e-18,
-	}
-	require.InEpsilonSlice(t, expected, kernel, 1e-10)
+	kernel := make([]float64, 51)
+	MakeHighPass(kernel, window.Blackman, 0.25)
+	require.Equal(t, TypeI, SymmetryOf(kernel))
+	requireGains(t, kernel, []float64{0, 0.1, 0.4, 0.5}, []float64{0, 0, 1, 1})
 }
 
 func TestBandPass(t *testing.T) {
-	kernel := make([]float64, 10)
-	MakeBandPass(kernel, window.Blackman, 0.25, 0.5)
+	kernel := make([]float64, 51)
+	MakeBandPass(kernel, window.Blackman, 0.1, 0.3)
+	require.Equal(t, TypeI, SymmetryOf(kernel))
+	requireGains(t, kernel, []float64{0, 0.02, 0.2, 0.38, 0.5}, []float64{0, 0, 1, 0, 0})
+}
 
-	expected := []float64{
-		8.852297468639933e-19,
-		-7.822375291978086e-19,
-		0.02134438446523165,
-		-2.982816317717476e-17,
-		-0.27085135668587773,
-		0.49901394444129243,
-		-0.27085135668587773,
-		-2.982816317717477e-17,
-		0.021344384465231663,
-		-7.822375291978078e-19,
+func TestBandReject(t *testing.T) {
+	kernel := make([]float64, 51)
+	MakeBandReject(kernel, window.Blackman, 0.1, 0.3)
+	require.Equal(t, TypeI, SymmetryOf(kernel))
+	requireGains(t, kernel, []float64{0, 0.02, 0.2, 0.38, 0.5}, []float64{1, 1, 0, 1, 1})
+}
+
+func TestDesign(t *testing.T) {
+	h, err := Design(Spec{
+		Type:       LowPass,
+		SampleRate: 48000,
+		Cutoffs:    []float64{12000},
+		Window:     window.Blackman,
+	}, 11)
+	require.NoError(t, err)
+	expected := make([]float64, 11)
+	MakeLowPass(expected, window.Blackman, 0.25)
+	require.Equal(t, expected, h)
+
+	// Even lengths are symmetric about the middle of the kernel.
+	h, err = Design(Spec{Type: BandPass, SampleRate: 48000, Cutoffs: []float64{4800, 14400}}, 50)
+	require.NoError(t, err)
+	require.Equal(t, TypeII, SymmetryOf(h))
+	requireGains(t, h, []float64{0, 0.2, 0.5}, []float64{0, 1, 0})
+
+	// DesignInto fills the kernel it's given.
+	h = make([]float64, 31)
+	require.NoError(t, DesignInto(h, Spec{Type: HighPass, SampleRate: 44100, Cutoffs: []float64{11025}}))
+	require.Equal(t, TypeI, SymmetryOf(h))
+	requireGains(t, h, []float64{0, 0.5}, []float64{0, 1})
+}
+
+func TestDesign_Errors(t *testing.T) {
+	for _, v := range []struct {
+		spec    Spec
+		numTaps int
+	}{
+		{Spec{Type: LowPass, SampleRate: 48000, Cutoffs: []float64{1000}}, 0},
+		{Spec{Type: Type(10), SampleRate: 48000, Cutoffs: []float64{1000}}, 31},
+		{Spec{Type: LowPass, SampleRate: 0, Cutoffs: []float64{1000}}, 31},
+		{Spec{Type: LowPass, SampleRate: 48000, Cutoffs: []float64{24000}}, 31},
+		{Spec{Type: LowPass, SampleRate: 48000, Cutoffs: []float64{0}}, 31},
+		{Spec{Type: LowPass, SampleRate: 48000, Cutoffs: []float64{1000, 2000}}, 31},
+		{Spec{Type: BandPass, SampleRate: 48000, Cutoffs: []float64{2000, 1000}}, 31},
+		{Spec{Type: BandPass, SampleRate: 48000, Cutoffs: []float64{1000}}, 31},
+		{Spec{Type: HighPass, SampleRate: 48000, Cutoffs: []float64{1000}}, 32},
+		{Spec{Type: BandReject, SampleRate: 48000, Cutoffs: []float64{1000, 2000}}, 32},
+	} {
+		_, err := Design(v.spec, v.numTaps)
+		require.Error(t, err, "%+v, %d taps", v.spec, v.numTaps)
 	}
-	require.InEpsilonSlice(t, expected, kernel, 1e-10)
+	require.Error(t, DesignInto(nil, Spec{Type: LowPass, SampleRate: 48000, Cutoffs: []float64{1000}}))
 }
 
-func TestBandReject(t *testing.T) {
-	kernel := make([]float64, 10)
-	MakeBandReject(kernel, window.Blackman, 0.25, 0.5)
+func TestSymmetryOf(t *testing.T) {
+	for _, v := range []struct {
+		h        []float64
+		expected Symmetry
+	}{
+		{[]float64{1, 2, 1}, TypeI},
+		{[]float64{1, 2, 2, 1}, TypeII},
+		{[]float64{1, 0, -1}, TypeIII},
+		{[]float64{1, 2, -2, -1}, TypeIV},
+		{[]float64{1, 2, 3}, Asymmetric},
+		{[]float64{1, 2, -1}, Asymmetric},
+		{nil, Asymmetric},
+		{[]float64{1, 1 + 1e-15, 1}, TypeI},
+	} {
+		require.Equal(t, v.expected, SymmetryOf(v.h), "%v", v.h)
+	}
+}
 
-	expected := []float64{
-		-8.852297468639933e-19,
-		7.822375291978086e-19,
-		-0.02134438446523165,
-		2.982816317717476e-17,
-		0.27085135668587773,
-		-0.49901394444129243,
-		0.27085135668587773,
-		2.982816317717477e-17,
-		-0.021344384465231663,
-		7.822375291978078e-19,
+// requireGains checks the magnitude response of a kernel at frequencies given
+// as fractions of the sample rate.
+func requireGains(t *testing.T, h, freqs, gains []float64) {
+	r, err := ResponseAt(h, nil, freqs)
+	require.NoError(t, err)
+	for k, m := range r.Magnitude() {
+		require.InDelta(t, gains[k], m, 1e-3, "gain at %g", freqs[k])
 	}
-	require.InEpsilonSlice(t, expected, kernel, 1e-10)
 }
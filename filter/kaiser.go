@@ -0,0 +1,135 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/brettbuddin/fourier/window"
+)
+
+// KaiserSpec specifies a filter by its tolerances, for design with a Kaiser
+// window.
+type KaiserSpec struct {
+	Type Type
+
+	// SampleRate is the sample rate in Hz.
+	SampleRate float64
+
+	// Cutoffs are the band edges in Hz: one for LowPass and HighPass, and two,
+	// lowest first, for BandPass and BandReject. Each is in the middle of its
+	// transition band, where the gain is -6 dB.
+	Cutoffs []float64
+
+	// TransitionWidth is the width in Hz of each transition band, between the
+	// edge of the pass band and the edge of the stop band.
+	TransitionWidth float64
+
+	// Attenuation is the least attenuation in the stop bands, in decibels.
+	Attenuation float64
+
+	// Ripple is the most peak-to-peak ripple in the pass bands, in decibels.
+	// Zero leaves it to follow from the attenuation.
+	Ripple float64
+}
+
+// Parameters returns the number of taps and the Kaiser window beta needed to
+// meet the spec, by Kaiser's formulas. The number of taps is odd for HighPass
+// and BandReject, which need gain at Nyquist.
+//
+// The window's ripple is the same in the pass and stop bands, so the tighter
+// of Attenuation and Ripple determines the design.
+//
+// Reference: J. F. Kaiser, "Nonrecursive Digital Filter Design Using the I0-sinh
+// Window Function", Proceedings of the IEEE International Symposium on Circuits
+// and Systems, 1974.
+func (s KaiserSpec) Parameters() (int, float64, error) {
+	if err := s.validate(); err != nil {
+		return 0, 0, err
+	}
+
+	delta := math.Pow(10, -s.Attenuation/20)
+	if s.Ripple > 0 {
+		r := math.Pow(10, s.Ripple/20)
+		delta = math.Min(delta, (r-1)/(r+1))
+	}
+	attenuation := -20 * math.Log10(delta)
+
+	var beta float64
+	switch {
+	case attenuation > 50:
+		beta = 0.1102 * (attenuation - 8.7)
+	case attenuation >= 21:
+		beta = 0.5842*math.Pow(attenuation-21, 0.4) + 0.07886*(attenuation-21)
+	}
+
+	width := s.TransitionWidth / s.SampleRate
+	order := int(math.Ceil((attenuation - 8) / (2.285 * 2 * math.Pi * width)))
+	if order < 1 {
+		order = 1
+	}
+	numTaps := order + 1
+	if (s.Type == HighPass || s.Type == BandReject) && numTaps%2 == 0 {
+		numTaps++
+	}
+	return numTaps, beta, nil
+}
+
+func (s KaiserSpec) validate() error {
+	n := s.Type.numCutoffs()
+	if n == 0 {
+		return fmt.Errorf("unknown filter type %v", s.Type)
+	}
+	if s.SampleRate <= 0 {
+		return errors.New("sample rate must be positive")
+	}
+	if len(s.Cutoffs) != n {
+		return fmt.Errorf("a %v filter takes %d cutoff frequencies", s.Type, n)
+	}
+	if s.TransitionWidth <= 0 {
+		return errors.New("transition width must be positive")
+	}
+	if s.Attenuation <= 0 {
+		return errors.New("attenuation must be positive")
+	}
+	if s.Ripple < 0 {
+		return errors.New("ripple cannot be negative")
+	}
+
+	var (
+		half    = s.TransitionWidth / 2
+		nyquist = s.SampleRate / 2
+	)
+	for i, c := range s.Cutoffs {
+		if c-half < 0 || c+half > nyquist {
+			return fmt.Errorf("transition band around cutoff %g Hz must lie between 0 and %g Hz (Nyquist)", c, nyquist)
+		}
+		if i > 0 && c-half < s.Cutoffs[i-1]+half {
+			return fmt.Errorf("cutoffs %g Hz and %g Hz must be at least one transition width apart, lowest first", s.Cutoffs[i-1], c)
+		}
+	}
+	return nil
+}
+
+// DesignKaiser designs a filter that meets the spec with a Kaiser-windowed
+// sinc kernel, returning a newly allocated kernel of the length given by
+// Parameters. The kernel is symmetric, so the filter delays every frequency
+// by (len-1)/2 samples.
+func DesignKaiser(s KaiserSpec) ([]float64, error) {
+	numTaps, beta, err := s.Parameters()
+	if err != nil {
+		return nil, err
+	}
+	cutoffs := make([]float64, len(s.Cutoffs))
+	for i, c := range s.Cutoffs {
+		cutoffs[i] = c / s.SampleRate
+	}
+	h := make([]float64, numTaps)
+	idealKernel(h, s.Type, cutoffs)
+
+	wf := window.Kaiser(beta)
+	for i := range h {
+		h[i] *= wf(float64(i), numTaps-1)
+	}
+	return h, nil
+}
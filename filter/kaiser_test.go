@@ -0,0 +1,137 @@
+package filter
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKaiserSpec_Parameters(t *testing.T) {
+	// Example 7.8 from Oppenheim and Schafer, Discrete-Time Signal Processing
+	// (3rd ed.): δ = 0.001 and a transition band of 0.2π.
+	numTaps, beta, err := KaiserSpec{
+		Type:            LowPass,
+		SampleRate:      48000,
+		Cutoffs:         []float64{12000},
+		TransitionWidth: 4800,
+		Attenuation:     60,
+	}.Parameters()
+	require.NoError(t, err)
+	require.Equal(t, 38, numTaps)
+	require.InDelta(t, 5.653, beta, 1e-3)
+
+	// High-pass filters have an odd number of taps.
+	numTaps, _, err = KaiserSpec{
+		Type:            HighPass,
+		SampleRate:      48000,
+		Cutoffs:         []float64{12000},
+		TransitionWidth: 4800,
+		Attenuation:     60,
+	}.Parameters()
+	require.NoError(t, err)
+	require.Equal(t, 39, numTaps)
+
+	// A tighter ripple raises the attenuation to match.
+	_, beta, err = KaiserSpec{
+		Type:            LowPass,
+		SampleRate:      48000,
+		Cutoffs:         []float64{12000},
+		TransitionWidth: 4800,
+		Attenuation:     20,
+		Ripple:          20 * math.Log10(1.001/0.999),
+	}.Parameters()
+	require.NoError(t, err)
+	require.InDelta(t, 5.653, beta, 1e-3)
+
+	// Below 21 dB the window is rectangular.
+	_, beta, err = KaiserSpec{
+		Type:            LowPass,
+		SampleRate:      48000,
+		Cutoffs:         []float64{12000},
+		TransitionWidth: 4800,
+		Attenuation:     20,
+	}.Parameters()
+	require.NoError(t, err)
+	require.Equal(t, 0.0, beta)
+}
+
+func TestDesignKaiser(t *testing.T) {
+	const (
+		sampleRate  = 1000
+		width       = 20
+		attenuation = 60
+	)
+	for _, v := range []struct {
+		spec        KaiserSpec
+		pass, stops [][2]float64
+	}{
+		{
+			spec:  KaiserSpec{Type: LowPass, Cutoffs: []float64{100}},
+			pass:  [][2]float64{{0, 0.09}},
+			stops: [][2]float64{{0.11, 0.5}},
+		},
+		{
+			spec:  KaiserSpec{Type: HighPass, Cutoffs: []float64{100}},
+			pass:  [][2]float64{{0.11, 0.5}},
+			stops: [][2]float64{{0, 0.09}},
+		},
+		{
+			spec:  KaiserSpec{Type: BandPass, Cutoffs: []float64{100, 300}},
+			pass:  [][2]float64{{0.11, 0.29}},
+			stops: [][2]float64{{0, 0.09}, {0.31, 0.5}},
+		},
+		{
+			spec:  KaiserSpec{Type: BandReject, Cutoffs: []float64{100, 300}},
+			pass:  [][2]float64{{0, 0.09}, {0.31, 0.5}},
+			stops: [][2]float64{{0.11, 0.29}},
+		},
+	} {
+		spec := v.spec
+		spec.SampleRate = sampleRate
+		spec.TransitionWidth = width
+		spec.Attenuation = attenuation
+
+		h, err := DesignKaiser(spec)
+		require.NoError(t, err)
+		numTaps, _, err := spec.Parameters()
+		require.NoError(t, err)
+		require.Len(t, h, numTaps)
+		for i := range h {
+			require.InDelta(t, h[i], h[len(h)-1-i], 1e-15)
+		}
+
+		r, err := NewResponse(h, nil, 8192)
+		require.NoError(t, err)
+		for _, band := range v.stops {
+			require.True(t, r.Attenuation(band[0], band[1]) > attenuation-1, "%v stop band %v", spec.Type, band)
+		}
+		for _, band := range v.pass {
+			require.True(t, r.Ripple(band[0], band[1]) < 0.02, "%v pass band %v", spec.Type, band)
+		}
+		for _, c := range spec.Cutoffs {
+			at, err := ResponseAt(h, nil, []float64{c / sampleRate})
+			require.NoError(t, err)
+			require.InDelta(t, -6, at.MagnitudeDB()[0], 0.1)
+		}
+	}
+}
+
+func TestKaiserSpec_Errors(t *testing.T) {
+	for _, spec := range []KaiserSpec{
+		{Type: Type(10), SampleRate: 1000, Cutoffs: []float64{100}, TransitionWidth: 10, Attenuation: 60},
+		{Type: LowPass, Cutoffs: []float64{100}, TransitionWidth: 10, Attenuation: 60},
+		{Type: LowPass, SampleRate: 1000, Cutoffs: []float64{100, 200}, TransitionWidth: 10, Attenuation: 60},
+		{Type: BandPass, SampleRate: 1000, Cutoffs: []float64{100}, TransitionWidth: 10, Attenuation: 60},
+		{Type: BandPass, SampleRate: 1000, Cutoffs: []float64{200, 100}, TransitionWidth: 10, Attenuation: 60},
+		{Type: BandPass, SampleRate: 1000, Cutoffs: []float64{100, 105}, TransitionWidth: 10, Attenuation: 60},
+		{Type: LowPass, SampleRate: 1000, Cutoffs: []float64{600}, TransitionWidth: 10, Attenuation: 60},
+		{Type: LowPass, SampleRate: 1000, Cutoffs: []float64{2}, TransitionWidth: 10, Attenuation: 60},
+		{Type: LowPass, SampleRate: 1000, Cutoffs: []float64{100}, TransitionWidth: 0, Attenuation: 60},
+		{Type: LowPass, SampleRate: 1000, Cutoffs: []float64{100}, TransitionWidth: 10, Attenuation: 0},
+		{Type: LowPass, SampleRate: 1000, Cutoffs: []float64{100}, TransitionWidth: 10, Attenuation: 60, Ripple: -1},
+	} {
+		_, err := DesignKaiser(spec)
+		require.Error(t, err, "%+v", spec)
+	}
+}
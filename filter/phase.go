@@ -0,0 +1,161 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+
+	"github.com/brettbuddin/fourier"
+	"github.com/brettbuddin/fourier/window"
+)
+
+// minimumPhaseOversampling is how many times longer than the kernel
+// MinimumPhase makes its FFT by default. The cepstrum of a kernel is
+// infinitely long, so a longer FFT leaves less of it to alias.
+const minimumPhaseOversampling = 32
+
+// minimumPhaseFloor is the lowest magnitude, relative to the peak, that
+// MinimumPhase takes the logarithm of. Zeros of the response are raised to it.
+const minimumPhaseFloor = 1e-10
+
+// MinimumPhase returns a kernel, as long as h, with the same magnitude response
+// as h but minimum phase: of all the kernels with that magnitude response, it
+// has the least delay, concentrating its energy at the start with no
+// pre-ringing. The delay is no longer the same at every frequency.
+//
+// The kernel is computed by the homomorphic method: the real cepstrum of h is
+// folded onto positive quefrencies, which makes it the complex cepstrum of the
+// minimum-phase kernel. fftSize is the length of the FFTs used, a power of two
+// at least twice the length of h; zero picks 32 times the length of h. Zeros
+// of the response on the unit circle are raised to -200 dB relative to the
+// peak, which puts a floor under the stop band.
+//
+// Reference: A. V. Oppenheim and R. W. Schafer, "Discrete-Time Signal
+// Processing", 3rd ed., Section 13.8.
+func MinimumPhase(h []float64, fftSize int) ([]float64, error) {
+	if len(h) == 0 {
+		return nil, errors.New("kernel cannot be empty")
+	}
+	if fftSize == 0 {
+		fftSize = 1
+		for fftSize < minimumPhaseOversampling*len(h) {
+			fftSize *= 2
+		}
+	}
+	if fftSize < 2*len(h) || fftSize&(fftSize-1) != 0 {
+		return nil, fmt.Errorf("FFT size must be a power of two of at least %d", 2*len(h))
+	}
+
+	buf := make([]complex128, fftSize)
+	for i, v := range h {
+		buf[i] = complex(v, 0)
+	}
+	if err := fourier.Forward(buf); err != nil {
+		return nil, err
+	}
+
+	var peak float64
+	for _, v := range buf {
+		peak = math.Max(peak, cmplx.Abs(v))
+	}
+	if peak == 0 {
+		return nil, errors.New("kernel has no energy")
+	}
+	floor := peak * minimumPhaseFloor
+
+	// The real cepstrum: the inverse transform of the log magnitude.
+	for i, v := range buf {
+		buf[i] = complex(math.Log(math.Max(cmplx.Abs(v), floor)), 0)
+	}
+	if err := fourier.Inverse(buf); err != nil {
+		return nil, err
+	}
+
+	// Fold the negative quefrencies onto the positive ones.
+	half := fftSize / 2
+	for i := 1; i < half; i++ {
+		buf[i] *= 2
+	}
+	for i := half + 1; i < fftSize; i++ {
+		buf[i] = 0
+	}
+
+	if err := fourier.Forward(buf); err != nil {
+		return nil, err
+	}
+	for i, v := range buf {
+		buf[i] = cmplx.Exp(v)
+	}
+	if err := fourier.Inverse(buf); err != nil {
+		return nil, err
+	}
+
+	out := make([]float64, len(h))
+	for i := range out {
+		out[i] = real(buf[i])
+	}
+	return out, nil
+}
+
+// LinearPhase designs a numTaps-long linear-phase kernel with the given
+// magnitude response, sampled at len(magnitude) frequencies evenly spaced from
+// DC up to (but not including) Nyquist, as returned by Response.Magnitude for
+// NewResponse. len(magnitude) must be a power of two, and numTaps no more than
+// twice that.
+//
+// The kernel is designed by frequency sampling: the magnitude, given a delay
+// of (numTaps-1)/2 samples, is transformed back to an impulse response and
+// tapered with wf, or the Blackman window if wf is nil. An odd numTaps gives a
+// Type I kernel; an even one gives a Type II kernel, which can't have gain at
+// Nyquist. The finer the sampling and the longer the kernel, the closer the
+// result follows the magnitude.
+func LinearPhase(magnitude []float64, numTaps int, wf window.Func) ([]float64, error) {
+	n := len(magnitude)
+	if n < 2 || n&(n-1) != 0 {
+		return nil, errors.New("number of magnitudes must be a power of two of at least 2")
+	}
+	if numTaps < 1 || numTaps > 2*n {
+		return nil, fmt.Errorf("number of taps must be between 1 and %d", 2*n)
+	}
+	for _, m := range magnitude {
+		if m < 0 || math.IsNaN(m) || math.IsInf(m, 0) {
+			return nil, errors.New("magnitudes must be finite and non-negative")
+		}
+	}
+	if wf == nil {
+		wf = window.Blackman
+	}
+
+	var (
+		size  = 2 * n
+		delay = float64(numTaps-1) / 2
+		buf   = make([]complex128, size)
+	)
+	for k, m := range magnitude {
+		v := cmplx.Rect(m, -2*math.Pi*float64(k)*delay/float64(size))
+		buf[k] = v
+		if k > 0 {
+			buf[size-k] = cmplx.Conj(v)
+		}
+	}
+	if numTaps%2 == 1 {
+		// The delay is a whole number of samples, so the response at Nyquist
+		// is real. The magnitude there is estimated from the two below it,
+		// as the vertex of a parabola symmetric about Nyquist.
+		m := math.Max(0, (4*magnitude[n-1]-magnitude[n-2])/3)
+		buf[n] = complex(m*math.Cos(math.Pi*delay), 0)
+	}
+	if err := fourier.Inverse(buf); err != nil {
+		return nil, err
+	}
+
+	h := make([]float64, numTaps)
+	for i := range h {
+		h[i] = real(buf[i])
+		if numTaps > 1 {
+			h[i] *= wf(float64(i), numTaps-1)
+		}
+	}
+	return h, nil
+}
@@ -0,0 +1,145 @@
+package filter
+
+import (
+	"math"
+	"testing"
+
+	"github.com/brettbuddin/fourier/window"
+	"github.com/stretchr/testify/require"
+)
+
+// centroid returns the time at which the energy of h is centered.
+func centroid(h []float64) float64 {
+	var sum, energy float64
+	for i, v := range h {
+		sum += float64(i) * v * v
+		energy += v * v
+	}
+	return sum / energy
+}
+
+func TestMinimumPhase(t *testing.T) {
+	h, err := DesignKaiser(KaiserSpec{
+		Type:            LowPass,
+		SampleRate:      1000,
+		Cutoffs:         []float64{200},
+		TransitionWidth: 50,
+		Attenuation:     70,
+	})
+	require.NoError(t, err)
+
+	min, err := MinimumPhase(h, 0)
+	require.NoError(t, err)
+	require.Equal(t, len(h), len(min))
+
+	a, err := NewResponse(h, nil, 512)
+	require.NoError(t, err)
+	b, err := NewResponse(min, nil, 512)
+	require.NoError(t, err)
+
+	// The magnitude is the same: to within 0.01 dB in the pass band and
+	// below the designed attenuation in the stop band.
+	for i, f := range a.Frequencies {
+		switch {
+		case f < 0.17:
+			require.InDelta(t, a.MagnitudeDB()[i], b.MagnitudeDB()[i], 0.01, "at %g", f)
+		case f > 0.23:
+			require.True(t, b.MagnitudeDB()[i] < -65, "at %g: %g dB", f, b.MagnitudeDB()[i])
+		}
+	}
+
+	// The energy moves to the start of the kernel, and the delay drops.
+	require.True(t, centroid(min) < centroid(h)/4)
+	require.True(t, b.GroupDelay()[0] < a.GroupDelay()[0]/2)
+}
+
+func TestMinimumPhaseSimple(t *testing.T) {
+	// A kernel with its zero inside the unit circle is already minimum phase,
+	// and its time reversal has the same magnitude.
+	for _, h := range [][]float64{{1, -0.5}, {-0.5, 1}} {
+		min, err := MinimumPhase(h, 1024)
+		require.NoError(t, err)
+		require.InDelta(t, 1, min[0], 1e-9)
+		require.InDelta(t, -0.5, min[1], 1e-9)
+	}
+
+	_, err := MinimumPhase(nil, 0)
+	require.Error(t, err)
+	_, err = MinimumPhase([]float64{1, 2, 3}, 4)
+	require.Error(t, err)
+	_, err = MinimumPhase([]float64{1, 2, 3}, 12)
+	require.Error(t, err)
+	_, err = MinimumPhase([]float64{0, 0}, 0)
+	require.Error(t, err)
+}
+
+func TestLinearPhase(t *testing.T) {
+	// A kernel whose amplitude never goes negative comes back from its
+	// magnitude, up to the estimate of the magnitude at Nyquist.
+	h := []float64{0.25, 0.5, 0.25}
+	r, err := NewResponse(h, nil, 64)
+	require.NoError(t, err)
+	out, err := LinearPhase(r.Magnitude(), 3, window.Rectangular)
+	require.NoError(t, err)
+	for i := range h {
+		require.InDelta(t, h[i], out[i], 1e-8)
+	}
+
+	for _, numTaps := range []int{63, 64} {
+		out, err := LinearPhase(r.Magnitude(), numTaps, nil)
+		require.NoError(t, err)
+		expect := TypeI
+		if numTaps%2 == 0 {
+			expect = TypeII
+		}
+		require.Equal(t, expect, SymmetryOf(out))
+	}
+}
+
+func TestLinearPhaseFromMinimumPhase(t *testing.T) {
+	h, err := DesignKaiser(KaiserSpec{
+		Type:            LowPass,
+		SampleRate:      1000,
+		Cutoffs:         []float64{150},
+		TransitionWidth: 50,
+		Attenuation:     60,
+	})
+	require.NoError(t, err)
+	min, err := MinimumPhase(h, 0)
+	require.NoError(t, err)
+
+	// Designing a linear-phase kernel from the minimum-phase kernel's
+	// magnitude gets back to the original response, within tolerance.
+	m, err := NewResponse(min, nil, 1024)
+	require.NoError(t, err)
+	lin, err := LinearPhase(m.Magnitude(), 2*len(h)+1, window.Kaiser(6))
+	require.NoError(t, err)
+	require.Equal(t, TypeI, SymmetryOf(lin))
+
+	a, err := NewResponse(h, nil, 512)
+	require.NoError(t, err)
+	b, err := NewResponse(lin, nil, 512)
+	require.NoError(t, err)
+	for i, f := range a.Frequencies {
+		switch {
+		case f < 0.12:
+			require.InDelta(t, a.MagnitudeDB()[i], b.MagnitudeDB()[i], 0.05, "at %g", f)
+		case f > 0.18:
+			require.True(t, b.MagnitudeDB()[i] < -55, "at %g: %g dB", f, b.MagnitudeDB()[i])
+		}
+	}
+	require.InDelta(t, float64(len(lin)-1)/2, b.GroupDelay()[10], 1e-9)
+}
+
+func TestLinearPhaseErrors(t *testing.T) {
+	_, err := LinearPhase(make([]float64, 6), 5, nil)
+	require.Error(t, err)
+	_, err = LinearPhase(make([]float64, 8), 17, nil)
+	require.Error(t, err)
+	_, err = LinearPhase(make([]float64, 8), 0, nil)
+	require.Error(t, err)
+	_, err = LinearPhase([]float64{1, -1}, 3, nil)
+	require.Error(t, err)
+	_, err = LinearPhase([]float64{1, math.NaN()}, 3, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,313 @@
+package filter
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+
+	"github.com/brettbuddin/fourier"
+)
+
+// Response is the frequency response of a filter, sampled at a set of
+// frequencies. Frequencies are fractions of the sample rate, so Nyquist is 0.5.
+//
+// Levels in decibels are relative to unity gain.
+type Response struct {
+	// Frequencies are the frequencies the response is sampled at.
+	Frequencies []float64
+
+	// Values are the complex responses at each frequency.
+	Values []complex128
+
+	groupDelay []float64
+}
+
+// NewResponse evaluates the frequency response of a filter with numerator
+// coefficients b and denominator coefficients a at n frequencies evenly spaced
+// from DC up to (but not including) Nyquist. For an FIR filter, b is the
+// kernel and a is nil.
+//
+// The response is computed with the FFT when n is a power of two, and directly
+// otherwise.
+func NewResponse(b, a []float64, n int) (*Response, error) {
+	if err := validateCoefficients(b, a); err != nil {
+		return nil, err
+	}
+	if n <= 0 {
+		return nil, errors.New("number of frequencies must be positive")
+	}
+
+	freqs := make([]float64, n)
+	for k := range freqs {
+		freqs[k] = float64(k) / float64(2*n)
+	}
+
+	eval := evaluate
+	if n >= 2 && n&(n-1) == 0 {
+		eval = func(p []float64, _ []float64) []complex128 {
+			return evaluateFFT(p, 2*n)
+		}
+	}
+	return newResponse(b, a, freqs, eval), nil
+}
+
+// ResponseAt evaluates the frequency response of a filter with numerator
+// coefficients b and denominator coefficients a at the given frequencies. For
+// an FIR filter, b is the kernel and a is nil.
+func ResponseAt(b, a []float64, freqs []float64) (*Response, error) {
+	if err := validateCoefficients(b, a); err != nil {
+		return nil, err
+	}
+	f := make([]float64, len(freqs))
+	copy(f, freqs)
+	return newResponse(b, a, f, evaluate), nil
+}
+
+func validateCoefficients(b, a []float64) error {
+	if len(b) == 0 {
+		return errors.New("numerator cannot be empty")
+	}
+	if a != nil && (len(a) == 0 || a[0] == 0) {
+		return errors.New("denominator must have a non-zero leading coefficient")
+	}
+	return nil
+}
+
+// newResponse computes a response and its group delay with an evaluator of
+// polynomials in z⁻¹.
+func newResponse(b, a, freqs []float64, eval func(p, freqs []float64) []complex128) *Response {
+	var (
+		r = &Response{Frequencies: freqs, groupDelay: make([]float64, len(freqs))}
+		B = eval(b, freqs)
+		D = eval(ramp(b), freqs)
+	)
+	r.Values = B
+	for k := range B {
+		r.groupDelay[k] = delay(D[k], B[k])
+	}
+
+	if a != nil {
+		var (
+			A  = eval(a, freqs)
+			DA = eval(ramp(a), freqs)
+		)
+		for k := range B {
+			r.Values[k] = B[k] / A[k]
+			r.groupDelay[k] -= delay(DA[k], A[k])
+		}
+	}
+	return r
+}
+
+// ramp returns n·p[n], whose transform divided by that of p gives the group
+// delay.
+func ramp(p []float64) []float64 {
+	r := make([]float64, len(p))
+	for n, v := range p {
+		r[n] = float64(n) * v
+	}
+	return r
+}
+
+// delay returns the group delay from the transforms of n·p[n] and p[n]. It's
+// undefined (NaN) where the transform of p is zero.
+func delay(d, p complex128) float64 {
+	if cmplx.Abs(p) < 1e-12 {
+		return math.NaN()
+	}
+	return real(d / p)
+}
+
+// evaluate evaluates the polynomial Σ p[n]·z⁻ⁿ on the unit circle at each
+// frequency.
+func evaluate(p []float64, freqs []float64) []complex128 {
+	values := make([]complex128, len(freqs))
+	for k, f := range freqs {
+		var (
+			z   = cmplx.Rect(1, -2*math.Pi*f)
+			acc complex128
+		)
+		for n := len(p) - 1; n >= 0; n-- {
+			acc = acc*z + complex(p[n], 0)
+		}
+		values[k] = acc
+	}
+	return values
+}
+
+// evaluateFFT evaluates the polynomial Σ p[n]·z⁻ⁿ at the first size/2
+// frequencies of a size-point DFT. Coefficients beyond size are folded back,
+// which leaves the DFT samples exact.
+func evaluateFFT(p []float64, size int) []complex128 {
+	buf := make([]complex128, size)
+	for n, v := range p {
+		buf[n%size] += complex(v, 0)
+	}
+	fourier.Forward(buf)
+	return buf[:size/2]
+}
+
+// Magnitude returns the magnitude of the response at each frequency.
+func (r *Response) Magnitude() []float64 {
+	m := make([]float64, len(r.Values))
+	for k, v := range r.Values {
+		m[k] = cmplx.Abs(v)
+	}
+	return m
+}
+
+// MagnitudeDB returns the magnitude of the response at each frequency in
+// decibels.
+func (r *Response) MagnitudeDB() []float64 {
+	m := r.Magnitude()
+	for k, v := range m {
+		m[k] = 20 * math.Log10(v)
+	}
+	return m
+}
+
+// Phase returns the phase of the response at each frequency in radians,
+// wrapped to [-π, π].
+func (r *Response) Phase() []float64 {
+	p := make([]float64, len(r.Values))
+	for k, v := range r.Values {
+		p[k] = cmplx.Phase(v)
+	}
+	return p
+}
+
+// UnwrappedPhase returns the phase of the response at each frequency in
+// radians, with jumps of more than π between successive frequencies removed
+// by adding multiples of 2π.
+func (r *Response) UnwrappedPhase() []float64 {
+	var (
+		p      = r.Phase()
+		offset float64
+	)
+	for k := 1; k < len(p); k++ {
+		wrapped := p[k] + offset
+		for wrapped-p[k-1] > math.Pi {
+			wrapped -= 2 * math.Pi
+			offset -= 2 * math.Pi
+		}
+		for wrapped-p[k-1] < -math.Pi {
+			wrapped += 2 * math.Pi
+			offset += 2 * math.Pi
+		}
+		p[k] = wrapped
+	}
+	return p
+}
+
+// GroupDelay returns the group delay of the filter at each frequency in
+// samples. It's NaN where the response is zero.
+func (r *Response) GroupDelay() []float64 {
+	d := make([]float64, len(r.groupDelay))
+	copy(d, r.groupDelay)
+	return d
+}
+
+// Edges returns the frequencies at which the magnitude crosses the given level
+// in decibels, interpolating between samples. Edges(-3) gives the -3 dB
+// points.
+func (r *Response) Edges(db float64) []float64 {
+	var (
+		m     = r.levels()
+		edges []float64
+	)
+	for k := 1; k < len(m); k++ {
+		var (
+			prev = m[k-1] - db
+			cur  = m[k] - db
+		)
+		if prev == 0 {
+			edges = append(edges, r.Frequencies[k-1])
+			continue
+		}
+		if (prev < 0) != (cur < 0) && cur != 0 {
+			t := prev / (prev - cur)
+			edges = append(edges, r.Frequencies[k-1]+t*(r.Frequencies[k]-r.Frequencies[k-1]))
+		}
+	}
+	if n := len(m); n > 0 && m[n-1] == db {
+		edges = append(edges, r.Frequencies[n-1])
+	}
+	return edges
+}
+
+// TransitionWidth returns the width of the widest transition band: the
+// greatest distance from a frequency where the magnitude crosses passDB to
+// the nearest frequency where it crosses stopDB. For a low-pass filter with
+// passDB of -1 and stopDB of -60, it's the distance from the end of the
+// passband to the start of the stopband. It returns NaN if the magnitude
+// doesn't cross both levels.
+func (r *Response) TransitionWidth(passDB, stopDB float64) float64 {
+	var (
+		pass  = r.Edges(passDB)
+		stop  = r.Edges(stopDB)
+		width = math.NaN()
+	)
+	if len(pass) == 0 || len(stop) == 0 {
+		return width
+	}
+	for _, p := range pass {
+		nearest := math.Inf(1)
+		for _, s := range stop {
+			nearest = math.Min(nearest, math.Abs(s-p))
+		}
+		if math.IsNaN(width) || nearest > width {
+			width = nearest
+		}
+	}
+	return width
+}
+
+// Ripple returns the difference in decibels between the largest and smallest
+// magnitudes between the frequencies low and high (inclusive), typically a
+// passband. It returns NaN if no frequencies fall in the band.
+func (r *Response) Ripple(low, high float64) float64 {
+	min, max := r.bandLevels(low, high)
+	return max - min
+}
+
+// Attenuation returns the minimum attenuation in decibels between the
+// frequencies low and high (inclusive), typically a stopband: the negated
+// largest magnitude in the band. It returns NaN if no frequencies fall in the
+// band.
+func (r *Response) Attenuation(low, high float64) float64 {
+	_, max := r.bandLevels(low, high)
+	return -max
+}
+
+// bandLevels returns the smallest and largest magnitudes in decibels between
+// two frequencies.
+func (r *Response) bandLevels(low, high float64) (float64, float64) {
+	var (
+		min = math.NaN()
+		max = math.NaN()
+	)
+	for k, v := range r.levels() {
+		if f := r.Frequencies[k]; f < low || f > high {
+			continue
+		}
+		if math.IsNaN(min) || v < min {
+			min = v
+		}
+		if math.IsNaN(max) || v > max {
+			max = v
+		}
+	}
+	return min, max
+}
+
+// minDB is the lowest level the metrics consider, in place of silence.
+const minDB = -400
+
+// levels returns the magnitude in decibels, bounded below by minDB.
+func (r *Response) levels() []float64 {
+	m := r.MagnitudeDB()
+	for k, v := range m {
+		m[k] = math.Max(v, minDB)
+	}
+	return m
+}
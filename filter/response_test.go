@@ -0,0 +1,135 @@
+package filter
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/brettbuddin/fourier/window"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponse_FFTMatchesDirect(t *testing.T) {
+	h := make([]float64, 101)
+	MakeLowPass(h, window.Blackman, 0.1)
+
+	// The kernel is longer than the FFT for 32 frequencies, so it's folded.
+	for _, n := range []int{32, 512} {
+		fft, err := NewResponse(h, []float64{1, -0.3, 0.1}, n)
+		require.NoError(t, err)
+		direct, err := ResponseAt(h, []float64{1, -0.3, 0.1}, fft.Frequencies)
+		require.NoError(t, err)
+
+		for k := range fft.Values {
+			require.InDelta(t, 0, cmplx.Abs(fft.Values[k]-direct.Values[k]), 1e-9)
+
+			// Group delay is ill-conditioned near zeros of the response.
+			if cmplx.Abs(direct.Values[k]) > 1e-3 {
+				require.InDelta(t, direct.GroupDelay()[k], fft.GroupDelay()[k], 1e-6)
+			}
+		}
+	}
+
+	// Not a power of two
+	r, err := NewResponse(h, nil, 100)
+	require.NoError(t, err)
+	require.Len(t, r.Values, 100)
+	require.Equal(t, 0.495, r.Frequencies[99])
+}
+
+func TestResponse_MovingAverage(t *testing.T) {
+	r, err := NewResponse([]float64{0.5, 0.5}, nil, 256)
+	require.NoError(t, err)
+
+	var (
+		magnitude = r.Magnitude()
+		db        = r.MagnitudeDB()
+		phase     = r.Phase()
+		delay     = r.GroupDelay()
+	)
+	for k, f := range r.Frequencies {
+		require.InDelta(t, math.Cos(math.Pi*f), magnitude[k], 1e-12)
+		require.InDelta(t, 20*math.Log10(math.Cos(math.Pi*f)), db[k], 1e-9)
+		require.InDelta(t, -math.Pi*f, phase[k], 1e-12)
+		require.InDelta(t, 0.5, delay[k], 1e-9)
+	}
+
+	edges := r.Edges(-3)
+	require.Len(t, edges, 1)
+	require.InDelta(t, math.Acos(math.Pow(10, -3.0/20))/math.Pi, edges[0], 1e-4)
+
+	require.InDelta(t, -20*math.Log10(math.Cos(0.1*math.Pi)), r.Ripple(0, 0.1), 1e-2)
+	require.InDelta(t, -20*math.Log10(math.Cos(math.Pi*205/512)), r.Attenuation(0.4, 0.5), 1e-9)
+	require.True(t, math.IsNaN(r.Ripple(0.6, 0.7)))
+}
+
+func TestResponse_OnePole(t *testing.T) {
+	var (
+		pole     = 0.5
+		freqs    = []float64{0, 0.05, 0.1, 0.25, 0.4}
+		r, err   = ResponseAt([]float64{1}, []float64{1, -pole}, freqs)
+		expected = func(f float64) float64 {
+			w := 2 * math.Pi * f
+			return (pole*math.Cos(w) - pole*pole) / (1 - 2*pole*math.Cos(w) + pole*pole)
+		}
+	)
+	require.NoError(t, err)
+	require.InDelta(t, 2, r.Magnitude()[0], 1e-12)
+	for k, f := range freqs {
+		require.InDelta(t, expected(f), r.GroupDelay()[k], 1e-9)
+	}
+}
+
+func TestResponse_LinearPhase(t *testing.T) {
+	h := make([]float64, 65)
+	MakeLowPass(h, window.Hann, 0.1)
+
+	r, err := NewResponse(h, nil, 1024)
+	require.NoError(t, err)
+
+	// A symmetric kernel delays every frequency in the passband by the same
+	// amount, so its unwrapped phase is a straight line there.
+	var (
+		phase = r.UnwrappedPhase()
+		delay = r.GroupDelay()
+		slope = (phase[100] - phase[10]) / (2 * math.Pi * (r.Frequencies[100] - r.Frequencies[10]))
+	)
+	for k := 0; k < 150; k++ {
+		require.InDelta(t, 32.5, delay[k], 0.6)
+	}
+	require.InDelta(t, -delay[50], slope, 1e-6)
+	for _, p := range r.Phase() {
+		require.True(t, p >= -math.Pi && p <= math.Pi)
+	}
+}
+
+func TestResponse_Metrics(t *testing.T) {
+	h := make([]float64, 101)
+	MakeLowPass(h, window.Blackman, 0.1)
+
+	r, err := NewResponse(h, nil, 4096)
+	require.NoError(t, err)
+
+	require.True(t, r.Attenuation(0.15, 0.5) > 70)
+	require.True(t, r.Ripple(0, 0.05) < 0.01)
+
+	edges := r.Edges(-3)
+	require.Len(t, edges, 1)
+	require.InDelta(t, 0.1, edges[0], 0.01)
+
+	// Blackman's transition band is about 5.5/N wide.
+	width := r.TransitionWidth(-0.1, -70)
+	require.InDelta(t, 5.5/101, width, 0.01)
+	require.True(t, math.IsNaN(r.TransitionWidth(-0.1, -500)))
+}
+
+func TestResponse_Errors(t *testing.T) {
+	_, err := NewResponse(nil, nil, 8)
+	require.Error(t, err)
+	_, err = NewResponse([]float64{1}, nil, 0)
+	require.Error(t, err)
+	_, err = NewResponse([]float64{1}, []float64{0, 1}, 8)
+	require.Error(t, err)
+	_, err = ResponseAt([]float64{1}, []float64{}, []float64{0.1})
+	require.Error(t, err)
+}
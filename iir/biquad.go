@@ -0,0 +1,224 @@
+// Package iir provides recursive (infinite impulse response) filters built
+// from cascaded second-order sections.
+package iir
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Biquad holds the coefficients of a second-order section, normalized so that
+// the leading denominator coefficient is 1:
+//
+//	H(z) = (B0 + B1·z⁻¹ + B2·z⁻²) / (1 + A1·z⁻¹ + A2·z⁻²)
+type Biquad struct {
+	B0, B1, B2 float64
+	A1, A2     float64
+}
+
+// Identity is a section that passes its input unchanged.
+var Identity = Biquad{B0: 1}
+
+// Coefficients returns the numerator and denominator of the section's transfer
+// function as polynomials in z⁻¹, for use with filter.NewResponse.
+func (b Biquad) Coefficients() (num, den []float64) {
+	return []float64{b.B0, b.B1, b.B2}, []float64{1, b.A1, b.A2}
+}
+
+// Stable reports whether the section's poles lie inside the unit circle.
+func (b Biquad) Stable() bool {
+	return math.Abs(b.A2) < 1 && math.Abs(b.A1) < 1+b.A2
+}
+
+// Coefficients returns the numerator and denominator of the transfer function
+// of sections in cascade as polynomials in z⁻¹, for use with
+// filter.NewResponse.
+func Coefficients(sections []Biquad) (num, den []float64) {
+	num, den = []float64{1}, []float64{1}
+	for _, s := range sections {
+		b, a := s.Coefficients()
+		num = multiply(num, b)
+		den = multiply(den, a)
+	}
+	return num, den
+}
+
+// multiply multiplies two polynomials.
+func multiply(p, q []float64) []float64 {
+	r := make([]float64, len(p)+len(q)-1)
+	for i, u := range p {
+		for j, v := range q {
+			r[i+j] += u * v
+		}
+	}
+	return r
+}
+
+// The designers below follow Robert Bristow-Johnson's Audio EQ Cookbook.
+// Frequencies are in Hz, and must lie between 0 and Nyquist. The quality
+// factor q sets the bandwidth; a q of 1/√2 gives the flattest pass band for
+// low- and high-pass sections.
+//
+// Reference: https://www.w3.org/TR/audio-eq-cookbook/
+
+// LowPass returns a second-order low-pass section with the given cutoff.
+func LowPass(sampleRate, freq, q float64) (Biquad, error) {
+	c, err := newCookbook(sampleRate, freq, q)
+	if err != nil {
+		return Biquad{}, err
+	}
+	return c.normalize(
+		(1-c.cos)/2, 1-c.cos, (1-c.cos)/2,
+		1+c.alpha, -2*c.cos, 1-c.alpha,
+	), nil
+}
+
+// HighPass returns a second-order high-pass section with the given cutoff.
+func HighPass(sampleRate, freq, q float64) (Biquad, error) {
+	c, err := newCookbook(sampleRate, freq, q)
+	if err != nil {
+		return Biquad{}, err
+	}
+	return c.normalize(
+		(1+c.cos)/2, -(1 + c.cos), (1+c.cos)/2,
+		1+c.alpha, -2*c.cos, 1-c.alpha,
+	), nil
+}
+
+// BandPass returns a second-order band-pass section with unity gain at the
+// center frequency.
+func BandPass(sampleRate, freq, q float64) (Biquad, error) {
+	c, err := newCookbook(sampleRate, freq, q)
+	if err != nil {
+		return Biquad{}, err
+	}
+	return c.normalize(
+		c.alpha, 0, -c.alpha,
+		1+c.alpha, -2*c.cos, 1-c.alpha,
+	), nil
+}
+
+// Notch returns a second-order section that rejects the center frequency.
+func Notch(sampleRate, freq, q float64) (Biquad, error) {
+	c, err := newCookbook(sampleRate, freq, q)
+	if err != nil {
+		return Biquad{}, err
+	}
+	return c.normalize(
+		1, -2*c.cos, 1,
+		1+c.alpha, -2*c.cos, 1-c.alpha,
+	), nil
+}
+
+// AllPass returns a second-order all-pass section: its gain is unity at every
+// frequency, and its phase shift passes through -180° at the center frequency.
+func AllPass(sampleRate, freq, q float64) (Biquad, error) {
+	c, err := newCookbook(sampleRate, freq, q)
+	if err != nil {
+		return Biquad{}, err
+	}
+	return c.normalize(
+		1-c.alpha, -2*c.cos, 1+c.alpha,
+		1+c.alpha, -2*c.cos, 1-c.alpha,
+	), nil
+}
+
+// Peaking returns a second-order peaking equalizer section that boosts or
+// cuts the center frequency by gainDB decibels.
+func Peaking(sampleRate, freq, q, gainDB float64) (Biquad, error) {
+	c, err := newCookbook(sampleRate, freq, q)
+	if err != nil {
+		return Biquad{}, err
+	}
+	a := math.Pow(10, gainDB/40)
+	return c.normalize(
+		1+c.alpha*a, -2*c.cos, 1-c.alpha*a,
+		1+c.alpha/a, -2*c.cos, 1-c.alpha/a,
+	), nil
+}
+
+// LowShelf returns a second-order section that boosts or cuts frequencies
+// below freq by gainDB decibels. The slope sets the steepness of the
+// transition; a slope of 1 is as steep as it can be without overshoot.
+func LowShelf(sampleRate, freq, slope, gainDB float64) (Biquad, error) {
+	c, a, err := newShelf(sampleRate, freq, slope, gainDB)
+	if err != nil {
+		return Biquad{}, err
+	}
+	k := 2 * math.Sqrt(a) * c.alpha
+	return c.normalize(
+		a*((a+1)-(a-1)*c.cos+k), 2*a*((a-1)-(a+1)*c.cos), a*((a+1)-(a-1)*c.cos-k),
+		(a+1)+(a-1)*c.cos+k, -2*((a-1)+(a+1)*c.cos), (a+1)+(a-1)*c.cos-k,
+	), nil
+}
+
+// HighShelf returns a second-order section that boosts or cuts frequencies
+// above freq by gainDB decibels. The slope sets the steepness of the
+// transition; a slope of 1 is as steep as it can be without overshoot.
+func HighShelf(sampleRate, freq, slope, gainDB float64) (Biquad, error) {
+	c, a, err := newShelf(sampleRate, freq, slope, gainDB)
+	if err != nil {
+		return Biquad{}, err
+	}
+	k := 2 * math.Sqrt(a) * c.alpha
+	return c.normalize(
+		a*((a+1)+(a-1)*c.cos+k), -2*a*((a-1)+(a+1)*c.cos), a*((a+1)+(a-1)*c.cos-k),
+		(a+1)-(a-1)*c.cos+k, 2*((a-1)-(a+1)*c.cos), (a+1)-(a-1)*c.cos-k,
+	), nil
+}
+
+// cookbook holds the intermediate values shared by the cookbook designs.
+type cookbook struct {
+	cos, alpha float64
+}
+
+func newCookbook(sampleRate, freq, q float64) (cookbook, error) {
+	if err := validateFrequency(sampleRate, freq); err != nil {
+		return cookbook{}, err
+	}
+	if q <= 0 {
+		return cookbook{}, errors.New("q must be positive")
+	}
+	w := 2 * math.Pi * freq / sampleRate
+	return cookbook{cos: math.Cos(w), alpha: math.Sin(w) / (2 * q)}, nil
+}
+
+func newShelf(sampleRate, freq, slope, gainDB float64) (cookbook, float64, error) {
+	if err := validateFrequency(sampleRate, freq); err != nil {
+		return cookbook{}, 0, err
+	}
+	if slope <= 0 {
+		return cookbook{}, 0, errors.New("slope must be positive")
+	}
+	var (
+		a = math.Pow(10, gainDB/40)
+		v = (a+1/a)*(1/slope-1) + 2
+	)
+	if v < 0 {
+		return cookbook{}, 0, fmt.Errorf("slope %g is too steep for a gain of %g dB", slope, gainDB)
+	}
+	w := 2 * math.Pi * freq / sampleRate
+	return cookbook{cos: math.Cos(w), alpha: math.Sin(w) / 2 * math.Sqrt(v)}, a, nil
+}
+
+func validateFrequency(sampleRate, freq float64) error {
+	if sampleRate <= 0 {
+		return errors.New("sample rate must be positive")
+	}
+	if freq <= 0 || freq >= sampleRate/2 {
+		return fmt.Errorf("frequency %g Hz must be between 0 and %g Hz (Nyquist)", freq, sampleRate/2)
+	}
+	return nil
+}
+
+// normalize divides the coefficients through by a0.
+func (cookbook) normalize(b0, b1, b2, a0, a1, a2 float64) Biquad {
+	return Biquad{
+		B0: b0 / a0,
+		B1: b1 / a0,
+		B2: b2 / a0,
+		A1: a1 / a0,
+		A2: a2 / a0,
+	}
+}
@@ -0,0 +1,148 @@
+package iir
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/brettbuddin/fourier/filter"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleRate = 48000
+
+// gains returns the gain in decibels of sections in cascade at frequencies in
+// Hz.
+func gains(t *testing.T, sections []Biquad, freqs ...float64) []float64 {
+	b, a := Coefficients(sections)
+	fractions := make([]float64, len(freqs))
+	for i, f := range freqs {
+		fractions[i] = f / sampleRate
+	}
+	r, err := filter.ResponseAt(b, a, fractions)
+	require.NoError(t, err)
+	return r.MagnitudeDB()
+}
+
+func TestDesigners(t *testing.T) {
+	var (
+		q   = 1 / math.Sqrt2
+		dc  = 1e-3
+		nyq = sampleRate/2 - 1e-3
+
+		// The Butterworth response of the analog prototype, with frequencies
+		// warped by the bilinear transform.
+		warp        = func(f float64) float64 { return math.Tan(math.Pi * f / sampleRate) }
+		butterworth = func(ratio float64) float64 { return -10 * math.Log10(1+math.Pow(ratio, 4)) }
+	)
+	for _, v := range []struct {
+		name     string
+		design   func() (Biquad, error)
+		freqs    []float64
+		expected []float64
+	}{
+		{
+			name:     "low-pass",
+			design:   func() (Biquad, error) { return LowPass(sampleRate, 1000, q) },
+			freqs:    []float64{dc, 1000, 10000},
+			expected: []float64{0, -3.0103, butterworth(warp(10000) / warp(1000))},
+		},
+		{
+			name:     "high-pass",
+			design:   func() (Biquad, error) { return HighPass(sampleRate, 1000, q) },
+			freqs:    []float64{nyq, 1000, 100},
+			expected: []float64{0, -3.0103, butterworth(warp(1000) / warp(100))},
+		},
+		{
+			name:     "band-pass",
+			design:   func() (Biquad, error) { return BandPass(sampleRate, 1000, 2) },
+			freqs:    []float64{1000},
+			expected: []float64{0},
+		},
+		{
+			name:     "notch",
+			design:   func() (Biquad, error) { return Notch(sampleRate, 1000, 2) },
+			freqs:    []float64{dc, nyq},
+			expected: []float64{0, 0},
+		},
+		{
+			name:     "all-pass",
+			design:   func() (Biquad, error) { return AllPass(sampleRate, 1000, 2) },
+			freqs:    []float64{dc, 100, 1000, 10000, nyq},
+			expected: []float64{0, 0, 0, 0, 0},
+		},
+		{
+			name:     "peaking",
+			design:   func() (Biquad, error) { return Peaking(sampleRate, 1000, 2, 6) },
+			freqs:    []float64{dc, 1000, nyq},
+			expected: []float64{0, 6, 0},
+		},
+		{
+			name:     "low shelf",
+			design:   func() (Biquad, error) { return LowShelf(sampleRate, 1000, 1, -12) },
+			freqs:    []float64{dc, 1000, nyq},
+			expected: []float64{-12, -6, 0},
+		},
+		{
+			name:     "high shelf",
+			design:   func() (Biquad, error) { return HighShelf(sampleRate, 1000, 1, 12) },
+			freqs:    []float64{dc, 1000, nyq},
+			expected: []float64{0, 6, 12},
+		},
+	} {
+		s, err := v.design()
+		require.NoError(t, err, v.name)
+		require.True(t, s.Stable(), v.name)
+		require.InDeltaSlice(t, v.expected, gains(t, []Biquad{s}, v.freqs...), 1e-3, v.name)
+	}
+
+	// The band-pass section rejects DC and Nyquist, and the notch its center.
+	s, err := BandPass(sampleRate, 1000, 2)
+	require.NoError(t, err)
+	for _, g := range gains(t, []Biquad{s}, dc, nyq) {
+		require.True(t, g < -100)
+	}
+	s, err = Notch(sampleRate, 1000, 2)
+	require.NoError(t, err)
+	require.True(t, gains(t, []Biquad{s}, 1000)[0] < -100)
+
+	// The all-pass section shifts its center frequency by 180°.
+	s, err = AllPass(sampleRate, 1000, 2)
+	require.NoError(t, err)
+	b, a := s.Coefficients()
+	r, err := filter.ResponseAt(b, a, []float64{1000.0 / sampleRate})
+	require.NoError(t, err)
+	require.InDelta(t, math.Pi, math.Abs(cmplx.Phase(r.Values[0])), 1e-9)
+}
+
+func TestCoefficients(t *testing.T) {
+	var (
+		s1 = Biquad{B0: 1, B1: 2, B2: 1, A1: -0.5, A2: 0.25}
+		s2 = Biquad{B0: 0.5, B1: -0.5, A1: 0.1}
+	)
+	b, a := Coefficients([]Biquad{s1, s2})
+	require.InDeltaSlice(t, []float64{0.5, 0.5, -0.5, -0.5, 0}, b, 1e-15)
+	require.InDeltaSlice(t, []float64{1, -0.4, 0.2, 0.025, 0}, a, 1e-15)
+
+	b, a = Coefficients(nil)
+	require.Equal(t, []float64{1}, b)
+	require.Equal(t, []float64{1}, a)
+}
+
+func TestDesigners_Errors(t *testing.T) {
+	for _, err := range []error{
+		second(LowPass(0, 1000, 1)),
+		second(LowPass(sampleRate, 0, 1)),
+		second(HighPass(sampleRate, 24000, 1)),
+		second(BandPass(sampleRate, 1000, 0)),
+		second(Peaking(sampleRate, 1000, -1, 6)),
+		second(LowShelf(sampleRate, 1000, 0, 6)),
+		second(HighShelf(sampleRate, 1000, 10, 24)),
+	} {
+		require.Error(t, err)
+	}
+}
+
+func second(_ Biquad, err error) error {
+	return err
+}
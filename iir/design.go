@@ -0,0 +1,609 @@
+package iir
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"sort"
+
+	"github.com/brettbuddin/fourier/filter"
+)
+
+// Spec describes a filter designed from an analog prototype.
+type Spec struct {
+	Type filter.Type
+
+	// Order is the order of the prototype. Band-pass and band-reject filters
+	// have twice as many poles.
+	Order int
+
+	// SampleRate is the sample rate in Hz.
+	SampleRate float64
+
+	// Cutoffs are the band edges in Hz: one for filter.LowPass and
+	// filter.HighPass, and two, lowest first, for filter.BandPass and
+	// filter.BandReject. What the gain is at each edge depends on the design.
+	Cutoffs []float64
+
+	// Ripple is the most ripple in the pass band, in decibels, for Chebyshev
+	// type I and elliptic designs.
+	Ripple float64
+
+	// Attenuation is the least attenuation in the stop band, in decibels, for
+	// Chebyshev type II and elliptic designs.
+	Attenuation float64
+}
+
+// maxOrder is the highest prototype order the designers accept. Beyond it the
+// poles crowd too closely for the sections to be accurate.
+const maxOrder = 24
+
+// Butterworth designs a Butterworth filter, whose gain is as flat as possible
+// in the pass band. The gain at each cutoff is -3 dB.
+func Butterworth(s Spec) ([]Biquad, error) {
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	n := s.Order
+	var p zpk
+	p.gain = 1
+	for k := 0; k < n; k++ {
+		p.poles = append(p.poles, cmplx.Exp(complex(0, math.Pi*float64(2*k+n+1)/float64(2*n))))
+	}
+	return s.digitize(p)
+}
+
+// ChebyshevI designs a Chebyshev type I filter, whose gain ripples by Ripple
+// decibels in the pass band and falls monotonically in the stop band. The
+// gain at each cutoff is -Ripple dB.
+func ChebyshevI(s Spec) ([]Biquad, error) {
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	if s.Ripple <= 0 {
+		return nil, errors.New("ripple must be positive")
+	}
+	var (
+		n   = s.Order
+		eps = math.Sqrt(math.Pow(10, s.Ripple/10) - 1)
+		mu  = math.Asinh(1/eps) / float64(n)
+		p   zpk
+	)
+	p.gain = 1
+	for k := 0; k < n; k++ {
+		theta := math.Pi * float64(2*k+1) / float64(2*n)
+		pole := complex(-math.Sinh(mu)*math.Sin(theta), math.Cosh(mu)*math.Cos(theta))
+		p.poles = append(p.poles, pole)
+		p.gain *= -pole
+	}
+	if n%2 == 0 {
+		p.gain /= complex(math.Sqrt(1+eps*eps), 0)
+	}
+	return s.digitize(p)
+}
+
+// ChebyshevII designs a Chebyshev type II (inverse Chebyshev) filter, whose
+// gain is flat in the pass band and ripples below -Attenuation dB in the stop
+// band. Each cutoff is the edge of the stop band, where the gain is
+// -Attenuation dB.
+func ChebyshevII(s Spec) ([]Biquad, error) {
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	if s.Attenuation <= 0 {
+		return nil, errors.New("attenuation must be positive")
+	}
+	var (
+		n   = s.Order
+		eps = 1 / math.Sqrt(math.Pow(10, s.Attenuation/10)-1)
+		mu  = math.Asinh(1/eps) / float64(n)
+		p   zpk
+	)
+	p.gain = 1
+	for k := 0; k < n; k++ {
+		theta := math.Pi * float64(2*k+1) / float64(2*n)
+		if 2*k+1 != n {
+			zero := complex(0, 1/math.Cos(theta))
+			p.zeros = append(p.zeros, zero)
+			p.gain /= -zero
+		}
+		pole := 1 / complex(-math.Sinh(mu)*math.Sin(theta), math.Cosh(mu)*math.Cos(theta))
+		p.poles = append(p.poles, pole)
+		p.gain *= -pole
+	}
+	return s.digitize(p)
+}
+
+// Elliptic designs an elliptic (Cauer) filter, whose gain ripples by Ripple
+// decibels in the pass band and stays below -Attenuation dB in the stop band.
+// It has the narrowest transition band of any design of its order. The gain
+// at each cutoff is -Ripple dB.
+//
+// Reference: S. J. Orfanidis, "Lecture Notes on Elliptic Filter Design", 2006.
+func Elliptic(s Spec) ([]Biquad, error) {
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	if s.Ripple <= 0 || s.Attenuation <= 0 {
+		return nil, errors.New("ripple and attenuation must be positive")
+	}
+	if s.Attenuation <= s.Ripple {
+		return nil, errors.New("attenuation must exceed ripple")
+	}
+	var (
+		n   = s.Order
+		ep  = math.Sqrt(math.Pow(10, s.Ripple/10) - 1)
+		es  = math.Sqrt(math.Pow(10, s.Attenuation/10) - 1)
+		k1  = ep / es
+		k   = ellipticDegree(n, k1)
+		v0  = imag(asn(complex(0, 1/ep), k1)) / float64(n)
+		p   zpk
+		odd = n%2 == 1
+	)
+	p.gain = 1
+	if !odd {
+		p.gain = complex(math.Pow(10, -s.Ripple/20), 0)
+	}
+	for i := 1; i <= n/2; i++ {
+		u := float64(2*i-1) / float64(n)
+		zeta := cd(complex(u, 0), k)
+		zero := complex(0, 1) / (complex(k, 0) * zeta)
+		pole := complex(0, 1) * cd(complex(u, -v0), k)
+		p.zeros = append(p.zeros, zero, cmplx.Conj(zero))
+		p.poles = append(p.poles, pole, cmplx.Conj(pole))
+		p.gain *= (pole * cmplx.Conj(pole)) / (zero * cmplx.Conj(zero))
+	}
+	if odd {
+		pole := complex(0, 1) * sn(complex(0, v0), k)
+		p.poles = append(p.poles, pole)
+		p.gain *= -pole
+	}
+	return s.digitize(p)
+}
+
+// Bessel designs a Bessel (Thomson) filter, whose group delay is as flat as
+// possible in the pass band, preserving the shape of waveforms. The gain at
+// each cutoff is -3 dB.
+func Bessel(s Spec) ([]Biquad, error) {
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	n := s.Order
+
+	// The poles are the roots of the reverse Bessel polynomial, whose
+	// coefficients are (2n-k)! / (2^(n-k) k! (n-k)!).
+	coeffs := make([]float64, n+1)
+	coeffs[n] = 1
+	for k := n - 1; k >= 0; k-- {
+		coeffs[k] = coeffs[k+1] * float64(2*n-k) * float64(k+1) / (2 * float64(n-k))
+	}
+	poles := roots(coeffs)
+
+	// Scale the poles so that the gain is -3 dB at 1 rad/s.
+	gain := func(w float64) float64 {
+		g := 1.0
+		for _, p := range poles {
+			g *= cmplx.Abs(p) / cmplx.Abs(complex(0, w)-p)
+		}
+		return g
+	}
+	low, high := 0.0, 1.0
+	for gain(high) > math.Sqrt(0.5) {
+		high *= 2
+	}
+	for i := 0; i < 100; i++ {
+		mid := (low + high) / 2
+		if gain(mid) > math.Sqrt(0.5) {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	w := (low + high) / 2
+
+	var p zpk
+	p.gain = 1
+	for _, pole := range poles {
+		pole /= complex(w, 0)
+		p.poles = append(p.poles, pole)
+		p.gain *= -pole
+	}
+	return s.digitize(p)
+}
+
+func (s Spec) validate() error {
+	var numCutoffs int
+	switch s.Type {
+	case filter.LowPass, filter.HighPass:
+		numCutoffs = 1
+	case filter.BandPass, filter.BandReject:
+		numCutoffs = 2
+	default:
+		return fmt.Errorf("unknown filter type %v", s.Type)
+	}
+	if s.Order < 1 || s.Order > maxOrder {
+		return fmt.Errorf("order must be between 1 and %d", maxOrder)
+	}
+	if s.SampleRate <= 0 {
+		return errors.New("sample rate must be positive")
+	}
+	if len(s.Cutoffs) != numCutoffs {
+		return fmt.Errorf("a %v filter takes %d cutoff frequencies", s.Type, numCutoffs)
+	}
+	for i, c := range s.Cutoffs {
+		if err := validateFrequency(s.SampleRate, c); err != nil {
+			return err
+		}
+		if i > 0 && c <= s.Cutoffs[i-1] {
+			return fmt.Errorf("cutoffs %g Hz and %g Hz must be in increasing order", s.Cutoffs[i-1], c)
+		}
+	}
+	return nil
+}
+
+// zpk is an analog transfer function as its zeros, poles and gain:
+//
+//	H(s) = gain · Π(s - zeros) / Π(s - poles)
+//
+// Complex zeros and poles come in conjugate pairs.
+type zpk struct {
+	zeros, poles []complex128
+	gain         complex128
+}
+
+// digitize transforms a low-pass prototype with a cutoff of 1 rad/s into the
+// type of filter in the spec, discretizes it with the bilinear transform and
+// factors it into second-order sections.
+func (s Spec) digitize(p zpk) ([]Biquad, error) {
+	// Pre-warp the cutoffs so that they land where they should after the
+	// bilinear transform, which uses 2·SampleRate as its constant.
+	fs2 := 2 * s.SampleRate
+	warped := make([]float64, len(s.Cutoffs))
+	for i, c := range s.Cutoffs {
+		warped[i] = fs2 * math.Tan(math.Pi*c/s.SampleRate)
+	}
+
+	switch s.Type {
+	case filter.LowPass:
+		p = p.lowPass(warped[0])
+	case filter.HighPass:
+		p = p.highPass(warped[0])
+	case filter.BandPass:
+		p = p.bandPass(math.Sqrt(warped[0]*warped[1]), warped[1]-warped[0])
+	case filter.BandReject:
+		p = p.bandReject(math.Sqrt(warped[0]*warped[1]), warped[1]-warped[0])
+	}
+	return p.bilinear(fs2).sections()
+}
+
+// lowPass scales the cutoff of a low-pass prototype to w rad/s.
+func (p zpk) lowPass(w float64) zpk {
+	r := zpk{gain: p.gain}
+	for _, z := range p.zeros {
+		r.zeros = append(r.zeros, z*complex(w, 0))
+	}
+	for _, q := range p.poles {
+		r.poles = append(r.poles, q*complex(w, 0))
+	}
+	r.gain *= complex(math.Pow(w, float64(len(p.poles)-len(p.zeros))), 0)
+	return r
+}
+
+// highPass turns a low-pass prototype into a high-pass filter with a cutoff
+// of w rad/s, substituting w/s for s.
+func (p zpk) highPass(w float64) zpk {
+	r := zpk{gain: p.gain}
+	for _, z := range p.zeros {
+		r.zeros = append(r.zeros, complex(w, 0)/z)
+		r.gain *= -z
+	}
+	for _, q := range p.poles {
+		r.poles = append(r.poles, complex(w, 0)/q)
+		r.gain /= -q
+	}
+	for i := len(p.zeros); i < len(p.poles); i++ {
+		r.zeros = append(r.zeros, 0)
+	}
+	return r
+}
+
+// bandPass turns a low-pass prototype into a band-pass filter centered on w
+// rad/s with a bandwidth of bw rad/s, substituting (s² + w²)/(s·bw) for s.
+func (p zpk) bandPass(w, bw float64) zpk {
+	r := zpk{gain: p.gain * complex(math.Pow(bw, float64(len(p.poles)-len(p.zeros))), 0)}
+	split := func(v complex128) (complex128, complex128) {
+		v *= complex(bw/2, 0)
+		d := cmplx.Sqrt(v*v - complex(w*w, 0))
+		return v + d, v - d
+	}
+	for _, z := range p.zeros {
+		a, b := split(z)
+		r.zeros = append(r.zeros, a, b)
+	}
+	for _, q := range p.poles {
+		a, b := split(q)
+		r.poles = append(r.poles, a, b)
+	}
+	for i := len(p.zeros); i < len(p.poles); i++ {
+		r.zeros = append(r.zeros, 0)
+	}
+	return r
+}
+
+// bandReject turns a low-pass prototype into a band-reject filter centered on
+// w rad/s with a bandwidth of bw rad/s, substituting s·bw/(s² + w²) for s.
+func (p zpk) bandReject(w, bw float64) zpk {
+	r := zpk{gain: p.gain}
+	split := func(v complex128) (complex128, complex128) {
+		v = complex(bw/2, 0) / v
+		d := cmplx.Sqrt(v*v - complex(w*w, 0))
+		return v + d, v - d
+	}
+	for _, z := range p.zeros {
+		a, b := split(z)
+		r.zeros = append(r.zeros, a, b)
+		r.gain *= -z
+	}
+	for _, q := range p.poles {
+		a, b := split(q)
+		r.poles = append(r.poles, a, b)
+		r.gain /= -q
+	}
+	for i := len(p.zeros); i < len(p.poles); i++ {
+		r.zeros = append(r.zeros, complex(0, w), complex(0, -w))
+	}
+	return r
+}
+
+// bilinear maps an analog filter to a digital one with the bilinear transform
+// s = k·(1 - z⁻¹)/(1 + z⁻¹). Zeros at infinity move to Nyquist.
+func (p zpk) bilinear(k float64) zpk {
+	var (
+		r  = zpk{gain: p.gain}
+		kc = complex(k, 0)
+	)
+	for _, z := range p.zeros {
+		r.zeros = append(r.zeros, (kc+z)/(kc-z))
+		r.gain *= kc - z
+	}
+	for _, q := range p.poles {
+		r.poles = append(r.poles, (kc+q)/(kc-q))
+		r.gain /= kc - q
+	}
+	for i := len(p.zeros); i < len(p.poles); i++ {
+		r.zeros = append(r.zeros, -1)
+	}
+	return r
+}
+
+// factor is a real first- or second-order factor of a polynomial in z⁻¹,
+// 1 + c1·z⁻¹ + c2·z⁻², and one of its roots.
+type factor struct {
+	c1, c2 float64
+	root   complex128
+	order  int
+}
+
+// factors groups roots in z into real factors: conjugate pairs, then real
+// roots in pairs, leaving a single first-order factor if there's an odd number
+// of real roots.
+func factors(roots []complex128) ([]factor, error) {
+	var (
+		fs    []factor
+		reals []float64
+		used  = make([]bool, len(roots))
+	)
+	for i, r := range roots {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		if math.Abs(imag(r)) <= 1e-9*math.Max(1, cmplx.Abs(r)) {
+			reals = append(reals, real(r))
+			continue
+		}
+
+		// Find the conjugate.
+		match := -1
+		for j := i + 1; j < len(roots); j++ {
+			if !used[j] && cmplx.Abs(roots[j]-cmplx.Conj(r)) <= 1e-6*math.Max(1, cmplx.Abs(r)) {
+				match = j
+				break
+			}
+		}
+		if match < 0 {
+			return nil, errors.New("complex root without a conjugate")
+		}
+		used[match] = true
+		fs = append(fs, factor{c1: -2 * real(r), c2: real(r)*real(r) + imag(r)*imag(r), root: r, order: 2})
+	}
+
+	sort.Float64s(reals)
+	for i := 0; i+1 < len(reals); i += 2 {
+		a, b := reals[i], reals[i+1]
+		root := a
+		if math.Abs(b) > math.Abs(a) {
+			root = b
+		}
+		fs = append(fs, factor{c1: -(a + b), c2: a * b, root: complex(root, 0), order: 2})
+	}
+	if len(reals)%2 == 1 {
+		r := reals[len(reals)-1]
+		fs = append(fs, factor{c1: -r, root: complex(r, 0), order: 1})
+	}
+	return fs, nil
+}
+
+// sections factors a digital filter into second-order sections. Each pair of
+// poles is matched with the nearest zeros, and sections are ordered with the
+// poles furthest from the unit circle first, which keeps the gain of the
+// early sections from peaking.
+func (p zpk) sections() ([]Biquad, error) {
+	poles, err := factors(p.poles)
+	if err != nil {
+		return nil, err
+	}
+	zeros, err := factors(p.zeros)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(poles, func(i, j int) bool {
+		return cmplx.Abs(poles[i].root) < cmplx.Abs(poles[j].root)
+	})
+
+	var (
+		sections = make([]Biquad, len(poles))
+		used     = make([]bool, len(zeros))
+	)
+	// Match the poles closest to the unit circle first, since their zeros
+	// matter most.
+	for i := len(poles) - 1; i >= 0; i-- {
+		pole := poles[i]
+		best := -1
+		for j, z := range zeros {
+			if used[j] || z.order > pole.order {
+				continue
+			}
+			if best < 0 || z.order > zeros[best].order ||
+				z.order == zeros[best].order && cmplx.Abs(z.root-pole.root) < cmplx.Abs(zeros[best].root-pole.root) {
+				best = j
+			}
+		}
+
+		s := Biquad{B0: 1, A1: pole.c1, A2: pole.c2}
+		if best >= 0 {
+			used[best] = true
+			s.B1, s.B2 = zeros[best].c1, zeros[best].c2
+		}
+		sections[i] = s
+	}
+	for j := range zeros {
+		if !used[j] {
+			return nil, errors.New("more zeros than poles")
+		}
+	}
+
+	// The gain goes in the first section.
+	g := real(p.gain)
+	sections[0].B0 *= g
+	sections[0].B1 *= g
+	sections[0].B2 *= g
+
+	for i, s := range sections {
+		if !s.Stable() {
+			return nil, fmt.Errorf("section %d is unstable", i)
+		}
+	}
+	return sections, nil
+}
+
+// roots returns the roots of the polynomial with the given coefficients,
+// lowest order first and with a leading coefficient of 1, by the
+// Durand-Kerner method.
+func roots(coeffs []float64) []complex128 {
+	var (
+		n    = len(coeffs) - 1
+		r    = make([]complex128, n)
+		eval = func(x complex128) complex128 {
+			var v complex128
+			for k := n; k >= 0; k-- {
+				v = v*x + complex(coeffs[k], 0)
+			}
+			return v
+		}
+	)
+
+	// Start on a circle whose radius bounds the roots.
+	radius := 1.0
+	for _, c := range coeffs[:n] {
+		radius = math.Max(radius, math.Pow(math.Abs(c), 1/float64(n)))
+	}
+	for i := range r {
+		r[i] = cmplx.Rect(radius, 2*math.Pi*float64(i)/float64(n)+0.4)
+	}
+
+	for iteration := 0; iteration < 1000; iteration++ {
+		var change float64
+		for i := range r {
+			d := complex(1, 0)
+			for j := range r {
+				if j != i {
+					d *= r[i] - r[j]
+				}
+			}
+			step := eval(r[i]) / d
+			r[i] -= step
+			change = math.Max(change, cmplx.Abs(step)/math.Max(1, cmplx.Abs(r[i])))
+		}
+		if change < 1e-15 {
+			break
+		}
+	}
+
+	// Conjugate pairs are made exact.
+	for i := range r {
+		if math.Abs(imag(r[i])) < 1e-12*cmplx.Abs(r[i]) {
+			r[i] = complex(real(r[i]), 0)
+		}
+	}
+	return r
+}
+
+// landen returns the descending Landen sequence of moduli starting from k,
+// down to where it's negligible.
+func landen(k float64) []float64 {
+	var v []float64
+	for k > 1e-15 && len(v) < 10 {
+		kp := math.Sqrt(1 - k*k)
+		k = (1 - kp) / (1 + kp)
+		v = append(v, k)
+	}
+	return v
+}
+
+// cd returns the Jacobi elliptic function cd(u·K, k), where K is the complete
+// elliptic integral of modulus k.
+func cd(u complex128, k float64) complex128 {
+	return ascend(cmplx.Cos(u*math.Pi/2), k)
+}
+
+// sn returns the Jacobi elliptic function sn(u·K, k).
+func sn(u complex128, k float64) complex128 {
+	return ascend(cmplx.Sin(u*math.Pi/2), k)
+}
+
+// ascend applies the ascending Landen transformation to a value of cos or sin,
+// giving the corresponding elliptic function.
+func ascend(w complex128, k float64) complex128 {
+	v := landen(k)
+	for i := len(v) - 1; i >= 0; i-- {
+		c := complex(v[i], 0)
+		w = (1 + c) * w / (1 + c*w*w)
+	}
+	return w
+}
+
+// asn returns the inverse of sn: the u for which sn(u·K, k) = w.
+func asn(w complex128, k float64) complex128 {
+	v := landen(k)
+	prev := k
+	for _, vn := range v {
+		w = w / (1 + cmplx.Sqrt(1-w*w*complex(prev*prev, 0))) * complex(2/(1+vn), 0)
+		prev = vn
+	}
+	return 1 - 2/math.Pi*cmplx.Acos(w)
+}
+
+// ellipticDegree solves the degree equation of an elliptic filter of order n
+// for the modulus k that sets the width of the transition band, given the
+// discrimination k1 = εp/εs.
+func ellipticDegree(n int, k1 float64) float64 {
+	k1p := math.Sqrt(1 - k1*k1)
+	kp := math.Pow(k1p, float64(n))
+	for i := 1; i <= n/2; i++ {
+		s := real(sn(complex(float64(2*i-1)/float64(n), 0), k1p))
+		kp *= s * s * s * s
+	}
+	return math.Sqrt(1 - kp*kp)
+}
@@ -0,0 +1,246 @@
+package iir
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/brettbuddin/fourier/filter"
+	"github.com/stretchr/testify/require"
+)
+
+// response returns the frequency response of sections in cascade at n
+// frequencies from DC to Nyquist, computed section by section with the FFT.
+func response(t *testing.T, sections []Biquad, n int) (freqs []float64, values []complex128) {
+	for i, s := range sections {
+		b, a := s.Coefficients()
+		r, err := filter.NewResponse(b, a, n)
+		require.NoError(t, err)
+		if i == 0 {
+			freqs, values = r.Frequencies, r.Values
+			continue
+		}
+		for k, v := range r.Values {
+			values[k] *= v
+		}
+	}
+	return freqs, values
+}
+
+// prototype maps a frequency, as a fraction of the sample rate, to the
+// frequency at which a low-pass prototype with a cutoff of 1 has the same gain
+// as the digital filter.
+func prototype(spec Spec, f float64) float64 {
+	var (
+		warp = func(f float64) float64 { return math.Tan(math.Pi * f / spec.SampleRate) }
+		w    = math.Tan(math.Pi * f)
+	)
+	switch spec.Type {
+	case filter.LowPass:
+		return w / warp(spec.Cutoffs[0])
+	case filter.HighPass:
+		return warp(spec.Cutoffs[0]) / w
+	}
+	var (
+		low, high = warp(spec.Cutoffs[0]), warp(spec.Cutoffs[1])
+		center    = low * high
+		bw        = high - low
+		x         = math.Abs((w*w - center) / (w * bw))
+	)
+	if spec.Type == filter.BandReject {
+		return 1 / x
+	}
+	return x
+}
+
+// chebyshev is the Chebyshev polynomial of the first kind of order n.
+func chebyshev(n int, x float64) float64 {
+	if math.Abs(x) <= 1 {
+		return math.Cos(float64(n) * math.Acos(x))
+	}
+	return math.Cosh(float64(n) * math.Acosh(math.Abs(x)))
+}
+
+func specs(order int) []Spec {
+	return []Spec{
+		{Type: filter.LowPass, Order: order, SampleRate: 48000, Cutoffs: []float64{1000}},
+		{Type: filter.HighPass, Order: order, SampleRate: 48000, Cutoffs: []float64{5000}},
+		{Type: filter.BandPass, Order: order, SampleRate: 48000, Cutoffs: []float64{2000, 8000}},
+		{Type: filter.BandReject, Order: order, SampleRate: 48000, Cutoffs: []float64{2000, 8000}},
+	}
+}
+
+// requireMagnitude compares the magnitude response of sections to an analytic
+// magnitude response of the low-pass prototype.
+func requireMagnitude(t *testing.T, spec Spec, sections []Biquad, prototypeDB func(x float64) float64) {
+	freqs, values := response(t, sections, 4096)
+	for k, f := range freqs[1:] {
+		var (
+			x        = prototype(spec, f)
+			expected = prototypeDB(x)
+			actual   = 20 * math.Log10(cmplx.Abs(values[k+1]))
+		)
+		if expected < -120 {
+			continue
+		}
+		require.InDelta(t, expected, actual, 1e-6, "%v order %d at %g", spec.Type, spec.Order, f*spec.SampleRate)
+	}
+}
+
+func TestButterworth(t *testing.T) {
+	for _, order := range []int{1, 2, 3, 4, 7, 10} {
+		for _, spec := range specs(order) {
+			sections, err := Butterworth(spec)
+			require.NoError(t, err)
+			requireMagnitude(t, spec, sections, func(x float64) float64 {
+				return -10 * math.Log10(1+math.Pow(x, float64(2*order)))
+			})
+		}
+	}
+}
+
+func TestChebyshevI(t *testing.T) {
+	for _, order := range []int{1, 2, 3, 4, 7, 10} {
+		for _, spec := range specs(order) {
+			spec.Ripple = 0.5
+			sections, err := ChebyshevI(spec)
+			require.NoError(t, err)
+
+			eps2 := math.Pow(10, spec.Ripple/10) - 1
+			requireMagnitude(t, spec, sections, func(x float64) float64 {
+				c := chebyshev(order, x)
+				return -10 * math.Log10(1+eps2*c*c)
+			})
+		}
+	}
+}
+
+func TestChebyshevII(t *testing.T) {
+	for _, order := range []int{1, 2, 3, 4, 7, 10} {
+		for _, spec := range specs(order) {
+			spec.Attenuation = 60
+			sections, err := ChebyshevII(spec)
+			require.NoError(t, err)
+
+			eps2 := 1 / (math.Pow(10, spec.Attenuation/10) - 1)
+			requireMagnitude(t, spec, sections, func(x float64) float64 {
+				if x == 0 {
+					return 0
+				}
+				c := chebyshev(order, 1/x)
+				return -10 * math.Log10(1+1/(eps2*c*c))
+			})
+		}
+	}
+}
+
+func TestElliptic(t *testing.T) {
+	for _, order := range []int{1, 2, 3, 4, 5, 8} {
+		for _, spec := range specs(order) {
+			spec.Ripple = 0.5
+			spec.Attenuation = 60
+			sections, err := Elliptic(spec)
+			require.NoError(t, err)
+
+			// The gain ripples between 0 and -Ripple dB in the pass band, and
+			// stays below -Attenuation dB in the stop band, which starts where
+			// the prototype reaches 1/k.
+			var (
+				ep       = math.Sqrt(math.Pow(10, spec.Ripple/10) - 1)
+				es       = math.Sqrt(math.Pow(10, spec.Attenuation/10) - 1)
+				stop     = 1 / ellipticDegree(order, ep/es)
+				freqs, v = response(t, sections, 4096)
+				min, max = math.Inf(1), math.Inf(-1)
+				stopMax  = math.Inf(-1)
+			)
+			for k, f := range freqs {
+				var (
+					x  = prototype(spec, f)
+					db = 20 * math.Log10(cmplx.Abs(v[k]))
+				)
+				switch {
+				case x <= 1:
+					min, max = math.Min(min, db), math.Max(max, db)
+				case x >= stop:
+					stopMax = math.Max(stopMax, db)
+				}
+			}
+			require.InDelta(t, 0, max, 1e-3, "%v order %d", spec.Type, order)
+			require.True(t, max <= 1e-9)
+			if order > 1 {
+				require.InDelta(t, -spec.Ripple, min, 1e-3, "%v order %d", spec.Type, order)
+			}
+			require.True(t, stopMax <= -spec.Attenuation+1e-6, "%v order %d: %g dB", spec.Type, order, stopMax)
+			if order > 1 {
+				require.InDelta(t, -spec.Attenuation, stopMax, 0.1, "%v order %d", spec.Type, order)
+			}
+		}
+	}
+}
+
+func TestBessel(t *testing.T) {
+	for _, order := range []int{1, 2, 4, 8} {
+		for _, spec := range specs(order) {
+			sections, err := Bessel(spec)
+			require.NoError(t, err)
+
+			for _, c := range spec.Cutoffs {
+				b, a := Coefficients(sections)
+				r, err := filter.ResponseAt(b, a, []float64{c / spec.SampleRate})
+				require.NoError(t, err)
+				require.InDelta(t, -3.0103, r.MagnitudeDB()[0], 1e-3, "%v order %d", spec.Type, order)
+			}
+		}
+	}
+
+	// The group delay of a low-pass Bessel filter is nearly constant across
+	// the pass band.
+	sections, err := Bessel(Spec{Type: filter.LowPass, Order: 6, SampleRate: 48000, Cutoffs: []float64{1000}})
+	require.NoError(t, err)
+	b, a := Coefficients(sections)
+	r, err := filter.ResponseAt(b, a, []float64{1e-6, 100.0 / 48000, 300.0 / 48000, 500.0 / 48000})
+	require.NoError(t, err)
+	delay := r.GroupDelay()
+	for _, d := range delay[1:] {
+		require.InEpsilon(t, delay[0], d, 0.005)
+	}
+}
+
+func TestDesign_Errors(t *testing.T) {
+	valid := Spec{Type: filter.LowPass, Order: 4, SampleRate: 48000, Cutoffs: []float64{1000}, Ripple: 1, Attenuation: 60}
+	for _, modify := range []func(s *Spec){
+		func(s *Spec) { s.Type = filter.Type(10) },
+		func(s *Spec) { s.Order = 0 },
+		func(s *Spec) { s.Order = maxOrder + 1 },
+		func(s *Spec) { s.SampleRate = 0 },
+		func(s *Spec) { s.Cutoffs = []float64{30000} },
+		func(s *Spec) { s.Cutoffs = []float64{1000, 2000} },
+		func(s *Spec) { s.Type, s.Cutoffs = filter.BandPass, []float64{2000, 1000} },
+	} {
+		s := valid
+		modify(&s)
+		for _, design := range []func(Spec) ([]Biquad, error){Butterworth, ChebyshevI, ChebyshevII, Elliptic, Bessel} {
+			_, err := design(s)
+			require.Error(t, err, "%+v", s)
+		}
+	}
+
+	s := valid
+	s.Ripple = 0
+	_, err := ChebyshevI(s)
+	require.Error(t, err)
+	_, err = Elliptic(s)
+	require.Error(t, err)
+
+	s = valid
+	s.Attenuation = 0
+	_, err = ChebyshevII(s)
+	require.Error(t, err)
+	_, err = Elliptic(s)
+	require.Error(t, err)
+
+	s = valid
+	s.Attenuation = 0.5
+	_, err = Elliptic(s)
+	require.Error(t, err)
+}
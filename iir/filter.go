@@ -0,0 +1,245 @@
+package iir
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync/atomic"
+)
+
+// defaultSmoothing is the default time constant, in samples, of the smoothing
+// applied to changes in coefficients.
+const defaultSmoothing = 256
+
+// Filter runs a stream through second-order sections in cascade, in Direct
+// Form II Transposed. Each channel it processes has its own state.
+type Filter struct {
+	// Coefficients scheduled by SetSections, as a *[]Biquad
+	target atomic.Value
+	loaded *[]Biquad
+
+	// Current (smoothed) coefficients and the ones they're moving towards
+	sections, goal []Biquad
+	settling       bool
+	smoothing      int
+	coef           float64
+
+	// Channels processed: every one if all is set, otherwise just channel
+	channel, numChannels int
+	all                  bool
+
+	// Two state variables per section per processed channel
+	state []float64
+}
+
+// NewFilter returns a Filter that runs its input through the sections in
+// order. By default it processes a single channel.
+func NewFilter(sections []Biquad, opts ...Option) (*Filter, error) {
+	if err := validateSections(sections); err != nil {
+		return nil, err
+	}
+	f := &Filter{
+		numChannels: 1,
+		smoothing:   defaultSmoothing,
+	}
+	for _, opt := range opts {
+		if err := opt(f); err != nil {
+			return nil, err
+		}
+	}
+
+	f.sections = make([]Biquad, len(sections))
+	copy(f.sections, sections)
+	f.goal = make([]Biquad, len(sections))
+	copy(f.goal, sections)
+	f.loaded = &f.goal
+	f.target.Store(f.loaded)
+
+	f.coef = 1
+	if f.smoothing > 0 {
+		f.coef = 1 - math.Exp(-1/float64(f.smoothing))
+	}
+	f.state = make([]float64, 2*len(sections)*f.numProcessed())
+	return f, nil
+}
+
+func validateSections(sections []Biquad) error {
+	if len(sections) == 0 {
+		return errors.New("at least one section is required")
+	}
+	for i, s := range sections {
+		if !s.Stable() {
+			return fmt.Errorf("section %d is unstable", i)
+		}
+	}
+	return nil
+}
+
+// numProcessed returns the number of channels the Filter processes.
+func (f *Filter) numProcessed() int {
+	if f.all {
+		return f.numChannels
+	}
+	return 1
+}
+
+// SetSections schedules new coefficients for the sections. There must be as
+// many sections as the Filter was created with. Changes are smoothed to avoid
+// zipper noise; the sections move gradually from their current coefficients
+// to the new ones, staying stable throughout. It's safe to call concurrently
+// with Process.
+func (f *Filter) SetSections(sections []Biquad) error {
+	if err := validateSections(sections); err != nil {
+		return err
+	}
+	if len(sections) != len(f.sections) {
+		return fmt.Errorf("expected %d sections, got %d", len(f.sections), len(sections))
+	}
+	s := make([]Biquad, len(sections))
+	copy(s, sections)
+	f.target.Store(&s)
+	return nil
+}
+
+// Reset clears the Filter's state and moves the coefficients to those last
+// set with SetSections.
+func (f *Filter) Reset() {
+	f.load()
+	copy(f.sections, f.goal)
+	f.settling = false
+	for i := range f.state {
+		f.state[i] = 0
+	}
+}
+
+// load reads the coefficients scheduled by SetSections.
+func (f *Filter) load() {
+	target := f.target.Load().(*[]Biquad)
+	if target == f.loaded {
+		return
+	}
+	f.loaded = target
+	copy(f.goal, *target)
+	f.settling = true
+}
+
+// Process filters numSamples samples of each channel of in into out. Both
+// buffers are interleaved with the number of channels given by ForChannel or
+// Channels; with ForChannel, the other channels of out are left untouched. in
+// and out may be the same buffer.
+func (f *Filter) Process(out, in []float64, numSamples int) error {
+	if numSamples < 0 {
+		return errors.New("number of samples cannot be negative")
+	}
+	if numSamples == 0 {
+		return nil
+	}
+	first, last := f.channel, f.channel
+	if f.all {
+		first, last = 0, f.numChannels-1
+	}
+	if need := (numSamples-1)*f.numChannels + last + 1; len(in) < need || len(out) < need {
+		return fmt.Errorf("buffers must hold %d samples", need)
+	}
+
+	f.load()
+	numSections := len(f.sections)
+	for i := 0; i < numSamples; i++ {
+		if f.settling {
+			f.settle()
+		}
+		for ch := first; ch <= last; ch++ {
+			var (
+				idx   = i*f.numChannels + ch
+				state = f.state[2*numSections*(ch-first):]
+				v     = in[idx]
+			)
+			for j, s := range f.sections {
+				s1, s2 := state[2*j], state[2*j+1]
+				y := s.B0*v + s1
+				state[2*j] = s.B1*v - s.A1*y + s2
+				state[2*j+1] = s.B2*v - s.A2*y
+				v = y
+			}
+			out[idx] = v
+		}
+	}
+	return nil
+}
+
+// settle moves the coefficients a step towards their goal. Each step is a
+// weighted average of the previous coefficients and the goal, which keeps the
+// sections stable: the coefficients of stable sections form a convex set.
+func (f *Filter) settle() {
+	settled := true
+	step := func(v, target float64) float64 {
+		if v == target {
+			return v
+		}
+		v += (target - v) * f.coef
+		if math.Abs(target-v) < 1e-9 {
+			return target
+		}
+		settled = false
+		return v
+	}
+	for i := range f.sections {
+		s, g := &f.sections[i], f.goal[i]
+		s.B0 = step(s.B0, g.B0)
+		s.B1 = step(s.B1, g.B1)
+		s.B2 = step(s.B2, g.B2)
+		s.A1 = step(s.A1, g.A1)
+		s.A2 = step(s.A2, g.A2)
+	}
+	f.settling = !settled
+}
+
+// Option is a configuration option for Filter.
+type Option func(*Filter) error
+
+// ForChannel configures a Filter to process a specific channel when the
+// buffers contain multiple interleaved channels.
+func ForChannel(channel, numChannels int) Option {
+	return func(f *Filter) error {
+		if channel < 0 {
+			return errors.New("channel cannot be negative")
+		}
+		if numChannels < 1 {
+			return errors.New("number of channels cannot be less than 1")
+		}
+		if channel >= numChannels {
+			return errors.New("channel out of range of total number of channels")
+		}
+		f.channel = channel
+		f.numChannels = numChannels
+		f.all = false
+		return nil
+	}
+}
+
+// Channels configures a Filter to process every channel of buffers that
+// contain numChannels interleaved channels, each with its own state.
+func Channels(numChannels int) Option {
+	return func(f *Filter) error {
+		if numChannels < 1 {
+			return errors.New("number of channels cannot be less than 1")
+		}
+		f.channel = 0
+		f.numChannels = numChannels
+		f.all = true
+		return nil
+	}
+}
+
+// Smoothing sets the time constant, in samples, of the smoothing applied to
+// coefficients set with SetSections. Zero applies changes immediately. The
+// default is 256 samples.
+func Smoothing(samples int) Option {
+	return func(f *Filter) error {
+		if samples < 0 {
+			return errors.New("smoothing cannot be negative")
+		}
+		f.smoothing = samples
+		return nil
+	}
+}
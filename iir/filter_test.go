@@ -0,0 +1,184 @@
+package iir
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// difference runs x through a filter with numerator b and denominator a by
+// its difference equation.
+func difference(b, a, x []float64) []float64 {
+	y := make([]float64, len(x))
+	for n := range x {
+		var v float64
+		for k, c := range b {
+			if n-k >= 0 {
+				v += c * x[n-k]
+			}
+		}
+		for k := 1; k < len(a); k++ {
+			if n-k >= 0 {
+				v -= a[k] * y[n-k]
+			}
+		}
+		y[n] = v / a[0]
+	}
+	return y
+}
+
+func noise(n int) []float64 {
+	r := rand.New(rand.NewSource(1))
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = r.Float64()*2 - 1
+	}
+	return x
+}
+
+func cascade(t *testing.T) []Biquad {
+	low, err := LowPass(sampleRate, 2000, 0.9)
+	require.NoError(t, err)
+	peak, err := Peaking(sampleRate, 500, 1.5, 9)
+	require.NoError(t, err)
+	return []Biquad{low, peak}
+}
+
+func TestFilter(t *testing.T) {
+	var (
+		sections = cascade(t)
+		in       = noise(1000)
+		out      = make([]float64, len(in))
+	)
+	f, err := NewFilter(sections)
+	require.NoError(t, err)
+
+	// Process in uneven chunks.
+	for i := 0; i < len(in); {
+		n := 1 + i%97
+		if i+n > len(in) {
+			n = len(in) - i
+		}
+		require.NoError(t, f.Process(out[i:], in[i:], n))
+		i += n
+	}
+	b, a := Coefficients(sections)
+	require.InDeltaSlice(t, difference(b, a, in), out, 1e-9)
+
+	// After a reset, in place.
+	f.Reset()
+	require.NoError(t, f.Process(in, in, len(in)))
+	require.InDeltaSlice(t, out, in, 1e-12)
+}
+
+func TestFilter_Channels(t *testing.T) {
+	var (
+		sections    = cascade(t)
+		numChannels = 3
+		numSamples  = 500
+		channels    = make([][]float64, numChannels)
+		in          = make([]float64, numChannels*numSamples)
+	)
+	for c := range channels {
+		channels[c] = noise(numSamples + c)[c:]
+		for i, v := range channels[c] {
+			in[i*numChannels+c] = v
+		}
+	}
+
+	// Every channel at once
+	f, err := NewFilter(sections, Channels(numChannels))
+	require.NoError(t, err)
+	out := make([]float64, len(in))
+	require.NoError(t, f.Process(out, in, numSamples))
+
+	b, a := Coefficients(sections)
+	for c, x := range channels {
+		expected := difference(b, a, x)
+		for i := range expected {
+			require.InDelta(t, expected[i], out[i*numChannels+c], 1e-9)
+		}
+	}
+
+	// One channel, leaving the others untouched
+	f, err = NewFilter(sections, ForChannel(1, numChannels))
+	require.NoError(t, err)
+	single := make([]float64, len(in))
+	require.NoError(t, f.Process(single, in, numSamples))
+	for i := range single {
+		if i%numChannels == 1 {
+			require.Equal(t, out[i], single[i])
+		} else {
+			require.Equal(t, 0.0, single[i])
+		}
+	}
+}
+
+func TestFilter_SetSections(t *testing.T) {
+	sine := func(n int, freq float64) []float64 {
+		x := make([]float64, n)
+		for i := range x {
+			x[i] = math.Sin(2 * math.Pi * freq * float64(i) / sampleRate)
+		}
+		return x
+	}
+	peak := func(x []float64) float64 {
+		var p float64
+		for _, v := range x {
+			p = math.Max(p, math.Abs(v))
+		}
+		return p
+	}
+
+	var (
+		in       = sine(20000, 1000)
+		out      = make([]float64, len(in))
+		boost, _ = Peaking(sampleRate, 1000, 1, 12)
+		cut, _   = Peaking(sampleRate, 1000, 1, -12)
+	)
+	f, err := NewFilter([]Biquad{boost})
+	require.NoError(t, err)
+	require.NoError(t, f.Process(out, in, 10000))
+	require.InDelta(t, math.Pow(10, 12.0/20), peak(out[5000:10000]), 0.01)
+
+	// The change is gradual, and the output settles at the new gain.
+	require.NoError(t, f.SetSections([]Biquad{cut}))
+	require.NoError(t, f.Process(out[10000:], in[10000:], 10000))
+	for i := 10001; i < 20000; i++ {
+		require.True(t, math.Abs(out[i]-out[i-1]) < 0.6)
+	}
+	require.InDelta(t, math.Pow(10, -12.0/20), peak(out[15000:]), 0.01)
+
+	// Without smoothing, the change is immediate.
+	f, err = NewFilter([]Biquad{boost}, Smoothing(0))
+	require.NoError(t, err)
+	require.NoError(t, f.SetSections([]Biquad{cut}))
+	require.NoError(t, f.Process(out, in, 10000))
+	b, a := cut.Coefficients()
+	require.InDeltaSlice(t, difference(b, a, in[:10000]), out[:10000], 1e-9)
+}
+
+func TestFilter_Errors(t *testing.T) {
+	sections := []Biquad{Identity}
+
+	_, err := NewFilter(nil)
+	require.Error(t, err)
+	_, err = NewFilter([]Biquad{{B0: 1, A1: -2, A2: 1}})
+	require.Error(t, err)
+	_, err = NewFilter(sections, ForChannel(2, 2))
+	require.Error(t, err)
+	_, err = NewFilter(sections, Channels(0))
+	require.Error(t, err)
+	_, err = NewFilter(sections, Smoothing(-1))
+	require.Error(t, err)
+
+	f, err := NewFilter(sections, ForChannel(1, 2))
+	require.NoError(t, err)
+	require.Error(t, f.Process(make([]float64, 8), make([]float64, 7), 4))
+	require.NoError(t, f.Process(make([]float64, 8), make([]float64, 8), 4))
+	require.Error(t, f.Process(nil, nil, -1))
+	require.Error(t, f.SetSections([]Biquad{Identity, Identity}))
+	require.Error(t, f.SetSections([]Biquad{{A2: 2}}))
+}
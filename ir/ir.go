@@ -0,0 +1,206 @@
+// Package ir provides utilities for preparing impulse responses before they're
+// loaded into a Convolver: normalization, trimming, fading, reversal and
+// stretching.
+//
+// Functions that preserve the length of an impulse response modify it in
+// place. Functions that change its length return a new slice, or a subslice of
+// the original where possible.
+package ir
+
+import (
+	"errors"
+	"math"
+
+	"github.com/brettbuddin/fourier/window"
+)
+
+// Peak returns the largest absolute value of the impulse response.
+func Peak(ir []float64) float64 {
+	var peak float64
+	for _, v := range ir {
+		peak = math.Max(peak, math.Abs(v))
+	}
+	return peak
+}
+
+// Energy returns the sum of the squares of the impulse response.
+func Energy(ir []float64) float64 {
+	var sum float64
+	for _, v := range ir {
+		sum += v * v
+	}
+	return sum
+}
+
+// RMS returns the root mean square of the impulse response.
+func RMS(ir []float64) float64 {
+	if len(ir) == 0 {
+		return 0
+	}
+	return math.Sqrt(Energy(ir) / float64(len(ir)))
+}
+
+// NormalizePeak scales the impulse response so that its largest absolute
+// value is target. A silent impulse response is left untouched.
+func NormalizePeak(ir []float64, target float64) {
+	scale(ir, target, Peak(ir))
+}
+
+// NormalizeRMS scales the impulse response so that its root mean square is
+// target. A silent impulse response is left untouched.
+func NormalizeRMS(ir []float64, target float64) {
+	scale(ir, target, RMS(ir))
+}
+
+// NormalizeEnergy scales the impulse response so that its energy (the sum of
+// its squares) is target. Normalizing several impulse responses to the same
+// energy makes them equally loud on broadband material. A silent impulse
+// response is left untouched.
+func NormalizeEnergy(ir []float64, target float64) {
+	scale(ir, math.Sqrt(target), math.Sqrt(Energy(ir)))
+}
+
+// scale multiplies the impulse response by target/current.
+func scale(ir []float64, target, current float64) {
+	if current == 0 {
+		return
+	}
+	s := target / current
+	for i := range ir {
+		ir[i] *= s
+	}
+}
+
+// TrimOnset removes the silence before the onset of the impulse response. The
+// onset is the first sample whose absolute value is within thresholdDB
+// (negative) of the peak. It returns a subslice of ir.
+func TrimOnset(ir []float64, thresholdDB float64) []float64 {
+	threshold := Peak(ir) * fromDB(thresholdDB)
+	for i, v := range ir {
+		if math.Abs(v) >= threshold && v != 0 {
+			return ir[i:]
+		}
+	}
+	return ir[:0]
+}
+
+// Decay returns the energy decay curve of the impulse response in dB,
+// computed by Schroeder backward integration. The curve starts at 0 dB and
+// falls monotonically; samples after the last non-zero sample are -Inf.
+func Decay(ir []float64) []float64 {
+	var (
+		curve = make([]float64, len(ir))
+		sum   float64
+	)
+	for i := len(ir) - 1; i >= 0; i-- {
+		sum += ir[i] * ir[i]
+		curve[i] = sum
+	}
+	if len(ir) == 0 || sum == 0 {
+		for i := range curve {
+			curve[i] = math.Inf(-1)
+		}
+		return curve
+	}
+	total := sum
+	for i, v := range curve {
+		curve[i] = toDB(v / total)
+	}
+	return curve
+}
+
+// TruncateDecay truncates the impulse response at the point where its energy
+// decay curve (see Decay) falls below decayDB (negative, e.g. -60). It returns
+// a subslice of ir. Fade out the end of the result to avoid a discontinuity.
+func TruncateDecay(ir []float64, decayDB float64) []float64 {
+	for i, v := range Decay(ir) {
+		if v < decayDB {
+			return ir[:i]
+		}
+	}
+	return ir
+}
+
+// FadeIn applies the rising half of a window function to the first n samples
+// of the impulse response.
+func FadeIn(ir []float64, n int, wf window.Func) {
+	n = min(n, len(ir))
+	for i := 0; i < n; i++ {
+		ir[i] *= wf(float64(i), 2*n)
+	}
+}
+
+// FadeOut applies the falling half of a window function to the last n samples
+// of the impulse response, ending at zero for windows that do.
+func FadeOut(ir []float64, n int, wf window.Func) {
+	n = min(n, len(ir))
+	offset := len(ir) - n
+	for i := 0; i < n; i++ {
+		ir[offset+i] *= wf(float64(n+i+1), 2*n)
+	}
+}
+
+// Reverse reverses the impulse response.
+func Reverse(ir []float64) {
+	for i, j := 0, len(ir)-1; i < j; i, j = i+1, j-1 {
+		ir[i], ir[j] = ir[j], ir[i]
+	}
+}
+
+// stretchTaps is the number of zero crossings of the interpolation kernel used
+// by Stretch on each side of its center.
+const stretchTaps = 16
+
+// Stretch resamples the impulse response to factor times its length using
+// windowed-sinc interpolation, which lengthens (factor > 1) or shortens
+// (factor < 1) its decay. When shortening, the impulse response is low-pass
+// filtered to avoid aliasing. Stretch also changes the spectrum of the
+// response: every frequency is scaled by 1/factor.
+func Stretch(ir []float64, factor float64) ([]float64, error) {
+	if factor <= 0 || math.IsInf(factor, 0) || math.IsNaN(factor) {
+		return nil, errors.New("stretch factor must be positive")
+	}
+
+	var (
+		n      = int(math.Round(float64(len(ir)) * factor))
+		out    = make([]float64, n)
+		cutoff = math.Min(1, factor)
+		width  = float64(stretchTaps) / cutoff
+	)
+	for i := range out {
+		var (
+			center = float64(i) / factor
+			lo     = max(0, int(math.Ceil(center-width)))
+			hi     = min(len(ir)-1, int(math.Floor(center+width)))
+			sum    float64
+		)
+		for j := lo; j <= hi; j++ {
+			x := float64(j) - center
+			sum += ir[j] * cutoff * window.Sinc(cutoff*x) * window.Sinc(x/width)
+		}
+		out[i] = sum
+	}
+	return out, nil
+}
+
+func toDB(v float64) float64 {
+	return 10 * math.Log10(v)
+}
+
+func fromDB(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
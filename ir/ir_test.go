@@ -0,0 +1,111 @@
+package ir
+
+import (
+	"math"
+	"testing"
+
+	"github.com/brettbuddin/fourier/window"
+	"github.com/stretchr/testify/require"
+)
+
+var epsilon = 1e-9
+
+func TestNormalize(t *testing.T) {
+	ir := []float64{0.5, -2, 1}
+	NormalizePeak(ir, 1)
+	require.InDeltaSlice(t, []float64{0.25, -1, 0.5}, ir, epsilon)
+	require.InDelta(t, 1, Peak(ir), epsilon)
+
+	NormalizeRMS(ir, 0.5)
+	require.InDelta(t, 0.5, RMS(ir), epsilon)
+
+	NormalizeEnergy(ir, 2)
+	require.InDelta(t, 2, Energy(ir), epsilon)
+
+	// Silence is left alone rather than producing NaNs.
+	silent := []float64{0, 0}
+	NormalizePeak(silent, 1)
+	NormalizeRMS(silent, 1)
+	NormalizeEnergy(silent, 1)
+	require.Equal(t, []float64{0, 0}, silent)
+}
+
+func TestTrimOnset(t *testing.T) {
+	ir := []float64{0, 0.0001, -0.001, 0.5, 1, 0.5}
+	require.Equal(t, []float64{-0.001, 0.5, 1, 0.5}, TrimOnset(ir, -60))
+	require.Equal(t, []float64{0.5, 1, 0.5}, TrimOnset(ir, -20))
+	require.Empty(t, TrimOnset([]float64{0, 0}, -60))
+}
+
+func TestDecay(t *testing.T) {
+	// An exponential decay of 60dB per 1000 samples has a linear decay curve
+	// with the same slope.
+	ir := make([]float64, 10000)
+	for i := range ir {
+		ir[i] = math.Pow(10, -3*float64(i)/1000)
+	}
+	curve := Decay(ir)
+	require.Equal(t, 0.0, curve[0])
+	require.InDelta(t, -60, curve[1000], 1e-6)
+	require.InDelta(t, -120, curve[2000], 1e-6)
+	for i := 1; i < len(curve); i++ {
+		require.True(t, curve[i] <= curve[i-1])
+	}
+
+	truncated := TruncateDecay(ir, -60)
+	require.Len(t, truncated, 1001)
+
+	require.True(t, math.IsInf(Decay([]float64{1, 0})[1], -1))
+	require.True(t, math.IsInf(Decay([]float64{0})[0], -1))
+}
+
+func TestFade(t *testing.T) {
+	ir := []float64{1, 1, 1, 1, 1, 1, 1, 1}
+	FadeIn(ir, 4, window.Hann)
+	FadeOut(ir, 4, window.Hann)
+	require.InDeltaSlice(t, []float64{
+		0, 0.1464466094067262, 0.5, 0.8535533905932737,
+		0.8535533905932737, 0.5, 0.1464466094067262, 0,
+	}, ir, epsilon)
+
+	// Fades longer than the impulse response are clamped.
+	short := []float64{1, 1}
+	FadeOut(short, 10, window.Hann)
+	require.InDeltaSlice(t, []float64{0.5, 0}, short, epsilon)
+}
+
+func TestReverse(t *testing.T) {
+	ir := []float64{1, 2, 3, 4, 5}
+	Reverse(ir)
+	require.Equal(t, []float64{5, 4, 3, 2, 1}, ir)
+}
+
+func TestStretch(t *testing.T) {
+	// A slow sinusoid is preserved, just stretched in time.
+	var (
+		n      = 1000
+		period = 100.0
+		ir     = make([]float64, n)
+	)
+	for i := range ir {
+		ir[i] = math.Sin(2 * math.Pi * float64(i) / period)
+	}
+
+	for _, factor := range []float64{0.5, 1, 1.5, 2} {
+		out, err := Stretch(ir, factor)
+		require.NoError(t, err)
+		require.Len(t, out, int(math.Round(float64(n)*factor)))
+
+		// Ignore the edges, where the kernel runs off the ends.
+		margin := int(64 * factor)
+		for i := margin; i < len(out)-margin; i++ {
+			expected := math.Sin(2 * math.Pi * float64(i) / (period * factor))
+			require.InDelta(t, expected, out[i], 1e-3, "factor %v sample %d", factor, i)
+		}
+	}
+
+	_, err := Stretch(ir, 0)
+	require.Error(t, err)
+	_, err = Stretch(ir, math.NaN())
+	require.Error(t, err)
+}
@@ -0,0 +1,341 @@
+package fourier
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MatrixConvolver convolves several interleaved input channels against a
+// matrix of impulse responses, one for every path from an input channel to an
+// output channel, and writes the sum for each output channel to an interleaved
+// output buffer. Each input channel is transformed once per call regardless of
+// how many outputs it feeds, and all paths are accumulated in the frequency
+// domain, so only one inverse transform is needed per output channel.
+//
+// It uses uniformly partitioned overlap-save convolution and, like Convolver,
+// has no latency.
+type MatrixConvolver struct {
+	// Sizes
+	blockSize, fftSize    int
+	numInputs, numOutputs int
+
+	// Buffers
+	inputs        [][]float64      // [input] time-domain history window
+	inputSegments [][][]complex128 // [input][segment]
+	paths         [][][][]complex128
+	temp, output  [][]complex128 // [output]
+
+	// Internal state
+	inputSegmentPos, inputPos int
+
+	// Configuration
+	maxIRLength int
+	truncate    bool
+
+	// truncated is the number of samples truncated from each path's impulse
+	// response, indexed like the impulse responses.
+	truncated [][]int
+}
+
+// NewMatrixConvolver returns a new MatrixConvolver. irs is indexed by input
+// channel and then output channel: irs[i][o] is the impulse response of the
+// path from input i to output o. A nil or empty impulse response leaves the
+// path silent.
+//
+// desiredBlockSize has the same meaning as it does for NewConvolver. Impulse
+// responses are limited to DefaultMaxIRLength samples unless configured
+// otherwise with MatrixMaxIRLength. A longer impulse response results in an
+// *IRLengthError, or in truncation if MatrixTruncateIR is given.
+func NewMatrixConvolver(desiredBlockSize int, irs [][][]float64, opts ...MatrixOption) (*MatrixConvolver, error) {
+	if desiredBlockSize == 0 {
+		return nil, errors.New("block size cannot be zero")
+	}
+	m := &MatrixConvolver{maxIRLength: DefaultMaxIRLength}
+	for _, opt := range opts {
+		if err := opt(m); err != nil {
+			return nil, err
+		}
+	}
+	if len(irs) == 0 {
+		return nil, errors.New("number of inputs cannot be zero")
+	}
+	numOutputs := len(irs[0])
+	if numOutputs == 0 {
+		return nil, errors.New("number of outputs cannot be zero")
+	}
+
+	var (
+		blockSize, fftSize  = calcPartitionSize(desiredBlockSize)
+		fillSize            = fftSize - blockSize
+		numResponseSegments = 0
+		truncated           = make([][]int, len(irs))
+		responses           = make([][][]float64, len(irs))
+	)
+	for i, row := range irs {
+		if len(row) != numOutputs {
+			return nil, fmt.Errorf("input %d has %d outputs, expected %d", i, len(row), numOutputs)
+		}
+		truncated[i] = make([]int, numOutputs)
+		responses[i] = make([][]float64, numOutputs)
+		for o, ir := range row {
+			if m.maxIRLength > 0 && len(ir) > m.maxIRLength {
+				if !m.truncate {
+					return nil, &IRLengthError{Length: len(ir), Max: m.maxIRLength}
+				}
+				truncated[i][o] = len(ir) - m.maxIRLength
+				ir = ir[:m.maxIRLength]
+			}
+			responses[i][o] = ir
+			if n := len(ir); n > 0 {
+				numResponseSegments = max(numResponseSegments, n/fillSize+1)
+			}
+		}
+	}
+	if numResponseSegments == 0 {
+		return nil, errors.New("impulse responses cannot all be empty")
+	}
+
+	numInputSegments := numResponseSegments
+	if blockSize <= 128 {
+		numInputSegments = 3 * numResponseSegments
+	}
+
+	m.blockSize = blockSize
+	m.fftSize = fftSize
+	m.numInputs = len(irs)
+	m.numOutputs = numOutputs
+	m.inputs = make([][]float64, len(irs))
+	m.inputSegments = make([][][]complex128, len(irs))
+	m.paths = make([][][][]complex128, len(irs))
+	m.temp = make([][]complex128, numOutputs)
+	m.output = make([][]complex128, numOutputs)
+	m.truncated = truncated
+
+	for i, row := range responses {
+		m.inputs[i] = make([]float64, fftSize)
+		m.inputSegments[i] = make([][]complex128, numInputSegments)
+		for j := range m.inputSegments[i] {
+			m.inputSegments[i][j] = make([]complex128, fftSize)
+		}
+
+		m.paths[i] = make([][][]complex128, numOutputs)
+		for o, ir := range row {
+			if len(ir) == 0 {
+				continue
+			}
+			segments := make([][]complex128, len(ir)/fillSize+1)
+			for j := range segments {
+				segments[j] = make([]complex128, fftSize)
+			}
+			loadIR(segments, ir, fillSize)
+			m.paths[i][o] = segments
+		}
+	}
+
+	for o := range m.output {
+		m.temp[o] = make([]complex128, fftSize)
+		m.output[o] = make([]complex128, fftSize)
+	}
+
+	return m, nil
+}
+
+// NewMonoToStereoConvolver returns a MatrixConvolver with one input channel
+// and two output channels.
+func NewMonoToStereoConvolver(desiredBlockSize int, left, right []float64, opts ...MatrixOption) (*MatrixConvolver, error) {
+	return NewMatrixConvolver(desiredBlockSize, [][][]float64{
+		{left, right},
+	}, opts...)
+}
+
+// NewStereoConvolver returns a MatrixConvolver that convolves each of two
+// channels with its own impulse response. There is no crosstalk between the
+// channels.
+func NewStereoConvolver(desiredBlockSize int, left, right []float64, opts ...MatrixOption) (*MatrixConvolver, error) {
+	return NewMatrixConvolver(desiredBlockSize, [][][]float64{
+		{left, nil},
+		{nil, right},
+	}, opts...)
+}
+
+// NewTrueStereoConvolver returns a MatrixConvolver for a true-stereo impulse
+// response: ll is the path from the left input to the left output, lr from the
+// left input to the right output, rl from the right input to the left output
+// and rr from the right input to the right output.
+func NewTrueStereoConvolver(desiredBlockSize int, ll, lr, rl, rr []float64, opts ...MatrixOption) (*MatrixConvolver, error) {
+	return NewMatrixConvolver(desiredBlockSize, [][][]float64{
+		{ll, lr},
+		{rl, rr},
+	}, opts...)
+}
+
+// Truncated returns the number of samples that were truncated from the end of
+// the impulse response of the path from input channel input to output channel
+// output. It's only ever non-zero when the MatrixConvolver is configured with
+// MatrixTruncateIR.
+func (m *MatrixConvolver) Truncated(input, output int) int {
+	return m.truncated[input][output]
+}
+
+// NumInputs returns the number of interleaved input channels.
+func (m *MatrixConvolver) NumInputs() int {
+	return m.numInputs
+}
+
+// NumOutputs returns the number of interleaved output channels.
+func (m *MatrixConvolver) NumOutputs() int {
+	return m.numOutputs
+}
+
+// Convolve convolves a chunk of interleaved input against the loaded impulse
+// responses and writes interleaved output. numSamples is the number of samples
+// per channel. As with Convolver, missing input is treated as silence and
+// output that doesn't fit is discarded.
+func (m *MatrixConvolver) Convolve(out, in []float64, numSamples int) error {
+	var (
+		numInputs           = m.numInputs
+		numOutputs          = m.numOutputs
+		fftSize             = m.fftSize
+		blockSize           = m.blockSize
+		offset              = fftSize - blockSize
+		numSamplesProcessed = 0
+	)
+
+	for numSamplesProcessed < numSamples {
+		var (
+			numRemaining        = numSamples - numSamplesProcessed
+			blockLimit          = blockSize - m.inputPos
+			numSamplesToProcess = min(numRemaining, blockLimit)
+		)
+
+		// Copy and transform each input channel
+		for c, input := range m.inputs {
+			for i := 0; i < numSamplesToProcess; i++ {
+				inIdx := c + (numSamplesProcessed+i)*numInputs
+				var v float64
+				if inIdx <= len(in)-1 {
+					v = in[inIdx]
+				}
+				input[offset+m.inputPos+i] = v
+			}
+
+			inputSegment := m.inputSegments[c][m.inputSegmentPos]
+			if err := cmplxCopyReal(inputSegment, input); err != nil {
+				return err
+			}
+			if err := Forward(inputSegment); err != nil {
+				return err
+			}
+		}
+
+		if m.inputPos == 0 {
+			if err := m.accumulate(); err != nil {
+				return err
+			}
+		}
+
+		for o, output := range m.output {
+			if err := cmplxCopy(output, m.temp[o]); err != nil {
+				return err
+			}
+			for c := range m.inputs {
+				path := m.paths[c][o]
+				if path == nil {
+					continue
+				}
+				if err := cmplxMultiplyAdd(output, m.inputSegments[c][m.inputSegmentPos], path[0]); err != nil {
+					return err
+				}
+			}
+			if err := Inverse(output); err != nil {
+				return err
+			}
+
+			for i := 0; i < numSamplesToProcess; i++ {
+				outIdx := o + (numSamplesProcessed+i)*numOutputs
+
+				// Guard against stepping outside the bounds of the output buffer
+				if outIdx > len(out)-1 {
+					break
+				}
+				out[outIdx] = real(output[offset+m.inputPos+i])
+			}
+		}
+
+		m.inputPos += numSamplesToProcess
+
+		if m.inputPos == blockSize {
+			m.inputPos = 0
+
+			// Slide the history windows forward by a block
+			for _, input := range m.inputs {
+				copy(input, input[blockSize:])
+				zero(input[offset:])
+			}
+
+			// Step the current segment backwards
+			if m.inputSegmentPos > 0 {
+				m.inputSegmentPos--
+			} else {
+				m.inputSegmentPos = len(m.inputSegments[0]) - 1
+			}
+		}
+
+		numSamplesProcessed += numSamplesToProcess
+	}
+
+	return nil
+}
+
+// accumulate multiplies the previous blocks of every input by all but the
+// first segment of each path's frequency response, summing into each output.
+func (m *MatrixConvolver) accumulate() error {
+	var (
+		numInputSegments = len(m.inputSegments[0])
+		step             = 1
+	)
+	if m.blockSize <= 128 {
+		step = 3
+	}
+
+	for o, temp := range m.temp {
+		cmplxZero(temp)
+		for c, segments := range m.inputSegments {
+			path := m.paths[c][o]
+			index := m.inputSegmentPos
+			for i := 1; i < len(path); i++ {
+				index += step
+				if index >= numInputSegments {
+					index -= numInputSegments
+				}
+				if err := cmplxMultiplyAdd(temp, segments[index], path[i]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// MatrixOption is a configuration option for MatrixConvolver.
+type MatrixOption func(*MatrixConvolver) error
+
+// MatrixMaxIRLength sets the maximum length of impulse response, in samples,
+// the MatrixConvolver accepts for each path. Zero or less removes the limit.
+// The default is DefaultMaxIRLength.
+func MatrixMaxIRLength(n int) MatrixOption {
+	return func(m *MatrixConvolver) error {
+		m.maxIRLength = n
+		return nil
+	}
+}
+
+// MatrixTruncateIR configures a MatrixConvolver to truncate impulse responses
+// longer than the maximum length rather than reject them. The number of samples
+// truncated from each path is reported by Truncated.
+func MatrixTruncateIR() MatrixOption {
+	return func(m *MatrixConvolver) error {
+		m.truncate = true
+		return nil
+	}
+}
@@ -0,0 +1,200 @@
+package fourier
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatrixConvolver(t *testing.T) {
+	for _, blockSize := range []int{64, 256} {
+		t.Run(fmt.Sprintf("block=%d", blockSize), func(t *testing.T) {
+			var (
+				numFrames = 3000
+				numInputs = 2
+				irs       = [][][]float64{
+					{testIR(700, 0.3), testIR(1200, 0.7)},
+					{nil, testIR(90, 1.1)},
+				}
+				numOutputs = len(irs[0])
+				channels   = make([][]float64, numInputs)
+				input      = make([]float64, numInputs*numFrames)
+				output     = make([]float64, numOutputs*numFrames)
+			)
+			for c := range channels {
+				channels[c] = make([]float64, numFrames)
+				for i := range channels[c] {
+					channels[c][i] = math.Cos(float64(i) * 0.05 * float64(c+1))
+					input[i*numInputs+c] = channels[c][i]
+				}
+			}
+
+			conv, err := NewMatrixConvolver(blockSize, irs)
+			require.NoError(t, err)
+			require.Equal(t, numInputs, conv.NumInputs())
+			require.Equal(t, numOutputs, conv.NumOutputs())
+
+			for i, n := 0, 1; i < numFrames; i, n = i+n, n%37+5 {
+				end := min(i+n, numFrames)
+				require.NoError(t, conv.Convolve(output[i*numOutputs:end*numOutputs], input[i*numInputs:end*numInputs], end-i))
+			}
+
+			for o := 0; o < numOutputs; o++ {
+				expected := make([]float64, numFrames)
+				for c := 0; c < numInputs; c++ {
+					if irs[c][o] == nil {
+						continue
+					}
+					for i, v := range directConvolve(channels[c], irs[c][o])[:numFrames] {
+						expected[i] += v
+					}
+				}
+				for i, v := range expected {
+					require.InDelta(t, v, output[i*numOutputs+o], epsilon, "output %d sample %d", o, i)
+				}
+			}
+		})
+	}
+}
+
+func TestMatrixConvolver_Layouts(t *testing.T) {
+	var (
+		a = []float64{1, 1}
+		b = []float64{2}
+		c = []float64{0, 3}
+		d = []float64{-1}
+	)
+
+	mono, err := NewMonoToStereoConvolver(8, a, b)
+	require.NoError(t, err)
+	out := make([]float64, 6)
+	require.NoError(t, mono.Convolve(out, []float64{1, 2, 3}, 3))
+	require.Equal(t, []float64{1, 2, 3, 4, 5, 6}, roundedCopy(out))
+
+	stereo, err := NewStereoConvolver(8, a, b)
+	require.NoError(t, err)
+	out = make([]float64, 6)
+	require.NoError(t, stereo.Convolve(out, []float64{1, 1, 2, 1, 3, 1}, 3))
+	require.Equal(t, []float64{1, 2, 3, 2, 5, 2}, roundedCopy(out))
+
+	trueStereo, err := NewTrueStereoConvolver(8, a, b, c, d)
+	require.NoError(t, err)
+	out = make([]float64, 6)
+	require.NoError(t, trueStereo.Convolve(out, []float64{1, 1, 0, 0, 0, 0}, 3))
+	require.Equal(t, []float64{1, 1, 4, 0, 0, 0}, roundedCopy(out))
+}
+
+func TestMatrixConvolver_ErroneousCreation(t *testing.T) {
+	_, err := NewMatrixConvolver(0, [][][]float64{{{1}}})
+	require.Error(t, err)
+
+	_, err = NewMatrixConvolver(64, nil)
+	require.Error(t, err)
+
+	_, err = NewMatrixConvolver(64, [][][]float64{{}})
+	require.Error(t, err)
+
+	_, err = NewMatrixConvolver(64, [][][]float64{{{1}, {1}}, {{1}}})
+	require.Error(t, err)
+
+	_, err = NewMatrixConvolver(64, [][][]float64{{nil, {}}})
+	require.Error(t, err)
+}
+
+func TestMatrixConvolver_MaxIRLength(t *testing.T) {
+	ir := make([]float64, 1000)
+	for i := range ir {
+		ir[i] = 1 / float64(i+1)
+	}
+
+	_, err := NewMonoToStereoConvolver(64, ir, ir[:10], MatrixMaxIRLength(999))
+	lerr, ok := err.(*IRLengthError)
+	require.True(t, ok)
+	require.Equal(t, 1000, lerr.Length)
+	require.Equal(t, 999, lerr.Max)
+
+	conv, err := NewMonoToStereoConvolver(64, ir, ir[:10], MatrixMaxIRLength(1000))
+	require.NoError(t, err)
+	require.Equal(t, 0, conv.Truncated(0, 0))
+
+	// A truncated path convolves with the truncated impulse response.
+	conv, err = NewMonoToStereoConvolver(64, ir, ir[:10], MatrixMaxIRLength(600), MatrixTruncateIR())
+	require.NoError(t, err)
+	require.Equal(t, 400, conv.Truncated(0, 0))
+	require.Equal(t, 0, conv.Truncated(0, 1))
+
+	in := make([]float64, len(ir))
+	in[0] = 1
+	out := make([]float64, 2*len(ir))
+	require.NoError(t, conv.Convolve(out, in, len(in)))
+	for i := range ir {
+		var expected float64
+		if i < 600 {
+			expected = ir[i]
+		}
+		require.InDelta(t, expected, out[2*i], epsilon, "sample %d", i)
+	}
+
+	// No limit
+	long := make([]float64, DefaultMaxIRLength+1)
+	_, err = NewMatrixConvolver(4096, [][][]float64{{long}})
+	require.IsType(t, &IRLengthError{}, err)
+	_, err = NewMatrixConvolver(4096, [][][]float64{{long}}, MatrixMaxIRLength(0))
+	require.NoError(t, err)
+}
+
+func BenchmarkMatrixConvolver_TrueStereo(b *testing.B) {
+	var (
+		blockSize = 256
+		irSize    = 48000
+		in        = make([]float64, 2*blockSize)
+		out       = make([]float64, 2*blockSize)
+	)
+
+	b.Run("matrix", func(b *testing.B) {
+		conv, _ := NewTrueStereoConvolver(blockSize,
+			make([]float64, irSize), make([]float64, irSize),
+			make([]float64, irSize), make([]float64, irSize))
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			conv.Convolve(out, in, blockSize)
+		}
+	})
+
+	b.Run("convolvers", func(b *testing.B) {
+		var (
+			convs []*Convolver
+			tmp   = make([]float64, 2*blockSize)
+		)
+		for i := 0; i < 2; i++ {
+			for o := 0; o < 2; o++ {
+				conv, _ := NewConvolver(blockSize, make([]float64, irSize), ForChannel(i, 2))
+				convs = append(convs, conv)
+			}
+		}
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			zero(out)
+			for _, conv := range convs {
+				conv.Convolve(tmp, in, blockSize)
+				for j := range out {
+					out[j] += tmp[j]
+				}
+			}
+		}
+	})
+}
+
+func testIR(n int, freq float64) []float64 {
+	ir := make([]float64, n)
+	for i := range ir {
+		ir[i] = math.Sin(float64(i)*freq) * math.Exp(-float64(i)/float64(n))
+	}
+	return ir
+}
@@ -0,0 +1,231 @@
+package fourier
+
+import (
+	"errors"
+	"math"
+	"sync/atomic"
+)
+
+// defaultSmoothing is the default time constant, in samples, of the smoothing
+// applied to changes in gain and pre-delay.
+const defaultSmoothing = 256
+
+// mix holds a Convolver's wet and dry gains and its pre-delay. Targets may be
+// set from any goroutine; the values used for processing follow them smoothly
+// on the goroutine calling Convolve.
+type mix struct {
+	// Targets. These are accessed atomically and are kept first so that
+	// they're 64-bit aligned on 32-bit platforms.
+	wetTarget, dryTarget uint64 // math.Float64bits
+	delayTarget          int64
+
+	// Current (smoothed) values
+	wet, dry, delay float64
+
+	// Targets as of the start of the current call to Convolve
+	wetGoal, dryGoal, delayGoal float64
+
+	coef      float64
+	smoothing int
+
+	// Pre-delay line
+	maxDelay int
+	line     []float64
+	linePos  int
+}
+
+// init sets the default parameters.
+func (m *mix) init() {
+	m.setWet(1)
+	m.setDry(0)
+	m.smoothing = defaultSmoothing
+}
+
+// setWet sets the wet gain immediately, without smoothing.
+func (m *mix) setWet(g float64) {
+	atomic.StoreUint64(&m.wetTarget, math.Float64bits(g))
+	m.wet = g
+}
+
+// setDry sets the dry gain immediately, without smoothing.
+func (m *mix) setDry(g float64) {
+	atomic.StoreUint64(&m.dryTarget, math.Float64bits(g))
+	m.dry = g
+}
+
+// setDelay sets the pre-delay immediately, without smoothing.
+func (m *mix) setDelay(n int) {
+	atomic.StoreInt64(&m.delayTarget, int64(n))
+	m.delay = float64(n)
+}
+
+// allocate allocates the pre-delay line, once the options have been applied.
+func (m *mix) allocate() {
+	if m.maxDelay > 0 {
+		m.line = make([]float64, m.maxDelay+2)
+	}
+	m.coef = 1
+	if m.smoothing > 0 {
+		m.coef = 1 - math.Exp(-1/float64(m.smoothing))
+	}
+}
+
+// load reads the targets for the current call to Convolve.
+func (m *mix) load() {
+	m.wetGoal = math.Float64frombits(atomic.LoadUint64(&m.wetTarget))
+	m.dryGoal = math.Float64frombits(atomic.LoadUint64(&m.dryTarget))
+	m.delayGoal = float64(atomic.LoadInt64(&m.delayTarget))
+}
+
+// reset clears the pre-delay line and moves all parameters to their targets.
+func (m *mix) reset() {
+	m.load()
+	m.wet, m.dry, m.delay = m.wetGoal, m.dryGoal, m.delayGoal
+	zero(m.line)
+	m.linePos = 0
+}
+
+// tail returns the longest the pre-delay may hold input for.
+func (m *mix) tail() int {
+	return int(math.Ceil(math.Max(m.delay, float64(atomic.LoadInt64(&m.delayTarget)))))
+}
+
+// preDelay pushes a sample into the pre-delay line and returns the delayed
+// sample, interpolating linearly while the delay is changing.
+func (m *mix) preDelay(v float64) float64 {
+	n := len(m.line)
+	if n == 0 {
+		return v
+	}
+	m.line[m.linePos] = v
+	m.delay = smooth(m.delay, m.delayGoal, m.coef, 1e-6)
+
+	var (
+		whole = math.Floor(m.delay)
+		frac  = m.delay - whole
+		idx   = m.linePos - int(whole)
+	)
+	if idx < 0 {
+		idx += n
+	}
+	out := m.line[idx]
+	if frac > 0 {
+		prev := idx - 1
+		if prev < 0 {
+			prev += n
+		}
+		out += frac * (m.line[prev] - out)
+	}
+
+	if m.linePos++; m.linePos == n {
+		m.linePos = 0
+	}
+	return out
+}
+
+// mix combines a sample of convolver output with the corresponding dry
+// sample.
+func (m *mix) mix(wet, dry float64) float64 {
+	m.wet = smooth(m.wet, m.wetGoal, m.coef, 1e-9)
+	m.dry = smooth(m.dry, m.dryGoal, m.coef, 1e-9)
+	return m.wet*wet + m.dry*dry
+}
+
+// smooth moves v a step towards target with a one-pole filter, snapping to the
+// target once within epsilon of it.
+func smooth(v, target, coef, epsilon float64) float64 {
+	if v == target {
+		return v
+	}
+	v += (target - v) * coef
+	if math.Abs(target-v) < epsilon {
+		return target
+	}
+	return v
+}
+
+// SetWetGain sets the gain applied to the convolved signal. Changes are
+// smoothed to avoid zipper noise. It's safe to call concurrently with
+// Convolve.
+func (c *Convolver) SetWetGain(g float64) {
+	atomic.StoreUint64(&c.params.wetTarget, math.Float64bits(g))
+}
+
+// SetDryGain sets the gain applied to the unprocessed input signal mixed into
+// the output. Changes are smoothed to avoid zipper noise. It's safe to call
+// concurrently with Convolve.
+func (c *Convolver) SetDryGain(g float64) {
+	atomic.StoreUint64(&c.params.dryTarget, math.Float64bits(g))
+}
+
+// SetPreDelay sets the number of samples the input is delayed before it's
+// convolved. The delay can't exceed the maximum set with PreDelay or
+// MaxPreDelay. Changes glide smoothly to the new delay. It's safe to call
+// concurrently with Convolve.
+func (c *Convolver) SetPreDelay(samples int) error {
+	if samples < 0 {
+		return errors.New("pre-delay cannot be negative")
+	}
+	if samples > c.params.maxDelay {
+		return errors.New("pre-delay exceeds maximum")
+	}
+	atomic.StoreInt64(&c.params.delayTarget, int64(samples))
+	return nil
+}
+
+// WetGain sets the initial gain applied to the convolved signal. The default
+// is 1.
+func WetGain(g float64) ConvolverOption {
+	return func(c *Convolver) error {
+		c.params.setWet(g)
+		return nil
+	}
+}
+
+// DryGain sets the initial gain applied to the unprocessed input signal mixed
+// into the output. The default is 0. The dry signal is delayed by the
+// Convolver's Latency, so that it lines up with the wet signal.
+func DryGain(g float64) ConvolverOption {
+	return func(c *Convolver) error {
+		c.params.setDry(g)
+		return nil
+	}
+}
+
+// PreDelay sets the initial number of samples the input is delayed before
+// it's convolved, raising the maximum pre-delay to match if needed.
+func PreDelay(samples int) ConvolverOption {
+	return func(c *Convolver) error {
+		if samples < 0 {
+			return errors.New("pre-delay cannot be negative")
+		}
+		c.params.maxDelay = max(c.params.maxDelay, samples)
+		c.params.setDelay(samples)
+		return nil
+	}
+}
+
+// MaxPreDelay sets the maximum pre-delay, in samples, that can be set with
+// SetPreDelay. The pre-delay line is allocated up-front.
+func MaxPreDelay(samples int) ConvolverOption {
+	return func(c *Convolver) error {
+		if samples < 0 {
+			return errors.New("maximum pre-delay cannot be negative")
+		}
+		c.params.maxDelay = max(c.params.maxDelay, samples)
+		return nil
+	}
+}
+
+// ParameterSmoothing sets the time constant, in samples, of the smoothing
+// applied when the gains or pre-delay change. Zero disables smoothing. The
+// default is 256 samples.
+func ParameterSmoothing(samples int) ConvolverOption {
+	return func(c *Convolver) error {
+		if samples < 0 {
+			return errors.New("smoothing cannot be negative")
+		}
+		c.params.smoothing = samples
+		return nil
+	}
+}
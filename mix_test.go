@@ -0,0 +1,157 @@
+package fourier
+
+import (
+	"io"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvolver_WetDryPreDelay(t *testing.T) {
+	var (
+		blockSize = 64
+		preDelay  = 100
+		impulse   = testIR(500, 0.3)
+		input     = make([]float64, 1000)
+	)
+	for i := range input {
+		input[i] = math.Cos(float64(i) * 0.05)
+	}
+
+	conv, err := NewConvolver(blockSize, impulse, WetGain(0.5), DryGain(0.25), PreDelay(preDelay))
+	require.NoError(t, err)
+	require.Equal(t, len(impulse)-1+preDelay, conv.TailLength())
+
+	output := make([]float64, len(input))
+	require.NoError(t, conv.Convolve(output, input, len(input)))
+	tail := make([]float64, 2*len(impulse))
+	n, err := conv.Flush(tail)
+	require.Equal(t, io.EOF, err)
+	output = append(output, tail[:n]...)
+
+	wet := directConvolve(input, impulse)
+	require.Len(t, output, len(wet)+preDelay)
+	for i, v := range output {
+		var expected float64
+		if i < len(input) {
+			expected += 0.25 * input[i]
+		}
+		if i >= preDelay {
+			expected += 0.5 * wet[i-preDelay]
+		}
+		require.InDelta(t, expected, v, epsilon, "sample %d", i)
+	}
+}
+
+func TestConvolver_DryLatency(t *testing.T) {
+	const blockSize = 64
+	for name, opts := range map[string][]ConvolverOption{
+		"uniform":     nil,
+		"fixed":       {FixedLatency()},
+		"non-uniform": {NonUniform(false)},
+		"direct-head": {NonUniform(true)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			conv, err := NewConvolver(blockSize, []float64{1}, append(opts, DryGain(1))...)
+			require.NoError(t, err)
+
+			// The dry signal is delayed along with the wet one, so an impulse
+			// comes out once, at twice the level.
+			input := make([]float64, 4*blockSize)
+			input[10] = 1
+			output := make([]float64, len(input))
+			require.NoError(t, conv.Convolve(output, input, len(input)))
+
+			latency := conv.Latency()
+			for i, v := range output {
+				var expected float64
+				if i == 10+latency {
+					expected = 2
+				}
+				require.InDelta(t, expected, v, epsilon, "sample %d", i)
+			}
+		})
+	}
+}
+
+func TestConvolver_GainSmoothing(t *testing.T) {
+	var (
+		blockSize = 64
+		input     = make([]float64, 4096)
+		output    = make([]float64, len(input))
+	)
+	for i := range input {
+		input[i] = 1
+	}
+
+	conv, err := NewConvolver(blockSize, []float64{1}, DryGain(0), ParameterSmoothing(100))
+	require.NoError(t, err)
+
+	require.NoError(t, conv.Convolve(output[:100], input[:100], 100))
+	conv.SetWetGain(0)
+	conv.SetDryGain(0.5)
+	require.NoError(t, conv.Convolve(output[100:], input[100:], len(input)-100))
+
+	for i := 0; i < 100; i++ {
+		require.InDelta(t, 1, output[i], epsilon)
+	}
+
+	// The output glides from 1 to 0.5 without jumps, eventually settling
+	// exactly.
+	for i := 100; i < len(output); i++ {
+		require.True(t, output[i] <= output[i-1]+epsilon, "sample %d", i)
+		require.True(t, output[i-1]-output[i] < 0.01, "sample %d", i)
+	}
+	require.InDelta(t, 0.75, output[169], 0.01)
+	require.Equal(t, 0.5, output[len(output)-1])
+}
+
+func TestConvolver_PreDelaySmoothing(t *testing.T) {
+	var (
+		blockSize = 64
+		input     = make([]float64, 4096)
+		output    = make([]float64, len(input))
+	)
+	for i := range input {
+		input[i] = float64(i)
+	}
+
+	conv, err := NewConvolver(blockSize, []float64{1}, MaxPreDelay(10), ParameterSmoothing(50))
+	require.NoError(t, err)
+	require.Error(t, conv.SetPreDelay(11))
+	require.Error(t, conv.SetPreDelay(-1))
+
+	require.NoError(t, conv.Convolve(output[:100], input[:100], 100))
+	require.NoError(t, conv.SetPreDelay(10))
+	require.NoError(t, conv.Convolve(output[100:], input[100:], len(input)-100))
+
+	for i := 0; i < 100; i++ {
+		require.InDelta(t, input[i], output[i], epsilon)
+	}
+
+	// The delay glides to its new length, so the output never skips.
+	for i := 100; i < len(output); i++ {
+		step := output[i] - output[i-1]
+		require.True(t, step >= 0 && step <= 1+epsilon, "sample %d", i)
+	}
+	require.InDelta(t, input[len(input)-11], output[len(output)-1], 1e-9)
+}
+
+func TestConvolver_MixAllocations(t *testing.T) {
+	var (
+		blockSize = 64
+		in        = make([]float64, blockSize)
+		out       = make([]float64, blockSize)
+	)
+	conv, err := NewConvolver(blockSize, make([]float64, 1000), MaxPreDelay(4800))
+	require.NoError(t, err)
+
+	allocs := testing.AllocsPerRun(20, func() {
+		conv.SetWetGain(0.3)
+		conv.SetDryGain(0.7)
+		conv.SetPreDelay(1200)
+		conv.Convolve(out, in, blockSize)
+	})
+	require.Zero(t, allocs)
+}
@@ -0,0 +1,479 @@
+package fourier
+
+// maxStageBlockSize is the largest block size a non-uniform stage grows to
+// (unless the Convolver's block size is larger).
+const maxStageBlockSize = 8192
+
+// nonUniform is an engine that partitions the impulse response into segments
+// that grow in size along the length of the response (Gardner, 1995; Garcia,
+// 2002). Short partitions at the head keep latency low, while long partitions
+// in the tail keep the number of multiply-accumulates per block small.
+//
+// The first blockSize samples of the response are either applied with a
+// direct-form FIR filter, which yields zero latency, or folded into the first
+// stage, in which case the output is delayed by blockSize samples.
+type nonUniform struct {
+	blockSize, pos, irSize int
+
+	// blocks counts the base blocks processed, for aligning stages.
+	blocks int
+
+	// Direct-form FIR head
+	head, history []float64
+	historyPos    int
+
+	stages []*stage
+}
+
+// stage is a uniformly partitioned overlap-save convolution of one region of
+// the impulse response. The stage consumes its input a full block at a time
+// and plays the result out over the following block.
+//
+// A deferred stage plays each result out a block later still, which leaves a
+// full block period to compute it. The extra block is taken out of the
+// stage's delay, so the output is the same either way.
+type stage struct {
+	// Sizes
+	blockSize, fftSize int
+
+	// delay is the number of blocks the stage's region of the impulse response
+	// is delayed beyond the stage's latency.
+	delay int
+
+	// Buffers
+	inputSegments, responseSegments [][]complex128
+	output                          []complex128
+	input, result, next             []float64
+
+	// Internal state
+	inputSegmentPos, inputPos, jobPos int
+
+	// Deferred computation
+	deferred bool
+	worker   *tailWorker
+	state    int32
+
+	// err is the error from the last job the worker computed, if it failed.
+	// It's written by the worker before the job is marked idle.
+	err error
+}
+
+// stagePlan describes the layout of a single stage.
+type stagePlan struct {
+	blockSize, offset, count, delay int
+}
+
+// newNonUniform returns a non-uniformly partitioned engine for an impulse
+// response. If worker is not nil, every stage after the first is deferred and
+// computed by the worker.
+func newNonUniform(blockSize int, ir []float64, directHead bool, worker *tailWorker) *nonUniform {
+	e := &nonUniform{blockSize: blockSize, irSize: len(ir)}
+
+	headSize := 0
+	if directHead {
+		headSize = min(blockSize, len(ir))
+		e.head = make([]float64, headSize)
+		e.history = make([]float64, headSize)
+		copy(e.head, ir)
+	}
+
+	for _, p := range planStages(blockSize, len(ir)-headSize) {
+		var (
+			start = headSize + p.offset
+			end   = min(start+p.count*p.blockSize, len(ir))
+		)
+		s := newStage(p.blockSize, p.delay, ir[start:end])
+		if worker != nil && p.delay > 0 {
+			s.deferred = true
+			s.worker = worker
+			s.delay--
+		}
+		e.stages = append(e.stages, s)
+	}
+
+	return e
+}
+
+// newBuffered returns an engine with a single, uniformly partitioned stage
+// that covers the whole impulse response. Input is transformed once per
+// completed block and the output is delayed by blockSize samples.
+func newBuffered(blockSize int, ir []float64) *nonUniform {
+	return &nonUniform{
+		blockSize: blockSize,
+		irSize:    len(ir),
+		stages:    []*stage{newStage(blockSize, 0, ir)},
+	}
+}
+
+// planStages lays out the stages for a region of an impulse response of
+// irSize samples. The first stage uses the base block size and each
+// subsequent stage doubles it, up to maxStageBlockSize. Offsets are relative
+// to the start of the region, which is assumed to be delayed by blockSize
+// samples in total (by the direct head or by the engine's latency).
+func planStages(blockSize, irSize int) []stagePlan {
+	var (
+		plans    []stagePlan
+		maxSize  = max(blockSize, maxStageBlockSize)
+		size     = blockSize
+		offset   = 0
+		fillSize = 3
+	)
+	for offset < irSize {
+		var (
+			remaining = irSize - offset
+			count     = fillSize
+		)
+		if size == maxSize || remaining <= count*size {
+			count = (remaining + size - 1) / size
+		}
+		plans = append(plans, stagePlan{
+			blockSize: size,
+			offset:    offset,
+			count:     count,
+			delay:     (offset+blockSize)/size - 1,
+		})
+		offset += count * size
+
+		// The first stage takes three partitions so that the next stage's
+		// offset lands on a multiple of its (doubled) block size. Every stage
+		// after that takes two.
+		fillSize = 2
+		size = min(2*size, maxSize)
+	}
+	return plans
+}
+
+// newStage returns a stage for a region of an impulse response.
+func newStage(blockSize, delay int, ir []float64) *stage {
+	var (
+		fftSize             = 2 * blockSize
+		numResponseSegments = (len(ir) + blockSize - 1) / blockSize
+	)
+
+	inputSegments := make([][]complex128, delay+numResponseSegments)
+	for i := range inputSegments {
+		inputSegments[i] = make([]complex128, fftSize)
+	}
+
+	responseSegments := make([][]complex128, numResponseSegments)
+	for i := range responseSegments {
+		responseSegments[i] = make([]complex128, fftSize)
+	}
+	loadIR(responseSegments, ir, blockSize)
+
+	return &stage{
+		blockSize:        blockSize,
+		fftSize:          fftSize,
+		delay:            delay,
+		inputSegments:    inputSegments,
+		responseSegments: responseSegments,
+		output:           make([]complex128, fftSize),
+		input:            make([]float64, fftSize),
+		result:           make([]float64, blockSize),
+		next:             make([]float64, blockSize),
+	}
+}
+
+// nonUniformSize returns the number of bytes newNonUniform allocates for an
+// impulse response of irSize samples.
+func nonUniformSize(blockSize, irSize int, directHead, deferred bool) int {
+	var size, headSize int
+	if directHead {
+		headSize = min(blockSize, irSize)
+		size += 2 * headSize * sizeofFloat64
+	}
+	for _, p := range planStages(blockSize, irSize-headSize) {
+		delay := p.delay
+		if deferred && delay > 0 {
+			delay--
+		}
+		size += stageSize(p.blockSize, delay, min(p.count*p.blockSize, irSize-headSize-p.offset))
+	}
+	return size
+}
+
+// stageSize returns the number of bytes newStage allocates for a region of an
+// impulse response of irSize samples.
+func stageSize(blockSize, delay, irSize int) int {
+	var (
+		fftSize             = 2 * blockSize
+		numResponseSegments = (irSize + blockSize - 1) / blockSize
+	)
+	return (delay+2*numResponseSegments+1)*fftSize*sizeofComplex128 +
+		(fftSize+2*blockSize)*sizeofFloat64
+}
+
+func (e *nonUniform) process(out, in []float64) error {
+	var (
+		blockSize           = e.blockSize
+		numSamples          = len(in)
+		numSamplesProcessed = 0
+	)
+
+	for numSamplesProcessed < numSamples {
+		// Every stage's block size is a multiple of the base block size, so
+		// stopping at base block boundaries is enough to keep all stages in
+		// step.
+		var (
+			numSamplesToProcess = min(numSamples-numSamplesProcessed, blockSize-e.pos)
+			chunkIn             = in[numSamplesProcessed : numSamplesProcessed+numSamplesToProcess]
+			chunkOut            = out[numSamplesProcessed : numSamplesProcessed+numSamplesToProcess]
+		)
+
+		e.processHead(chunkOut, chunkIn)
+
+		for _, s := range e.stages {
+			if err := s.process(chunkOut, chunkIn); err != nil {
+				return err
+			}
+		}
+
+		e.pos += numSamplesToProcess
+		if e.pos == blockSize {
+			e.pos = 0
+			e.blocks++
+		}
+		numSamplesProcessed += numSamplesToProcess
+	}
+
+	return nil
+}
+
+// processHead applies the direct-form FIR head. It overwrites out.
+func (e *nonUniform) processHead(out, in []float64) {
+	n := len(e.head)
+	if n == 0 {
+		zero(out)
+		return
+	}
+	for i, v := range in {
+		e.history[e.historyPos] = v
+
+		var (
+			sum float64
+			idx = e.historyPos
+		)
+		for _, h := range e.head {
+			sum += h * e.history[idx]
+			if idx--; idx < 0 {
+				idx = n - 1
+			}
+		}
+		out[i] = sum
+
+		if e.historyPos++; e.historyPos == n {
+			e.historyPos = 0
+		}
+	}
+}
+
+func (e *nonUniform) latency() int {
+	if len(e.head) > 0 {
+		return 0
+	}
+	return e.blockSize
+}
+
+func (e *nonUniform) length() int { return e.irSize }
+
+func (e *nonUniform) reset() {
+	zero(e.history)
+	e.historyPos = 0
+	e.pos = 0
+	e.blocks = 0
+	for _, s := range e.stages {
+		s.reset()
+	}
+}
+
+func (e *nonUniform) seed(from engine) error {
+	prev, ok := from.(*nonUniform)
+	if !ok || prev.blockSize != e.blockSize {
+		return nil
+	}
+
+	e.pos = prev.pos
+	e.blocks = prev.blocks
+
+	// Copy the most recent input into the head's history.
+	var (
+		n = len(e.history)
+		m = len(prev.history)
+	)
+	for i := 0; i < min(n, m); i++ {
+		e.history[(e.historyPos-1-i+2*n)%n] = prev.history[(prev.historyPos-1-i+2*m)%m]
+	}
+
+	for i, s := range e.stages {
+		// Line the stage up with the position in its own block.
+		s.inputPos = (e.blocks%(s.blockSize/e.blockSize))*e.blockSize + e.pos
+
+		if i < len(prev.stages) && prev.stages[i].blockSize == s.blockSize {
+			if err := s.seed(prev.stages[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// process accumulates the stage's output into out and loads in into the
+// stage's input buffer. When a full block has been loaded, the block is
+// transformed and the result is queued for output.
+func (s *stage) process(out, in []float64) error {
+	var (
+		blockSize = s.blockSize
+		n         = len(in)
+	)
+
+	copy(s.input[blockSize+s.inputPos:], in)
+	for i := 0; i < n; i++ {
+		out[i] += s.result[s.inputPos+i]
+	}
+
+	s.inputPos += n
+	if s.inputPos < blockSize {
+		return nil
+	}
+	s.inputPos = 0
+
+	return s.step()
+}
+
+// seed copies the input history of another stage of the same size and
+// computes the output that's due from it, so that the stage can continue
+// exactly where the other left off.
+func (s *stage) seed(prev *stage) error {
+	// The previous stage's worker may still be transforming its latest
+	// segment.
+	prev.wait()
+	if prev.err != nil {
+		return prev.err
+	}
+
+	copy(s.input, prev.input)
+	copySegments(s.inputSegments, s.inputSegmentPos, prev.inputSegments, prev.inputSegmentPos)
+
+	// The block currently playing out was computed from the most recently
+	// completed segment. A deferred stage plays one block further behind and
+	// also has the most recent block queued.
+	numInputSegments := len(s.inputSegments)
+	if s.deferred {
+		s.jobPos = (s.inputSegmentPos + 2) % numInputSegments
+		if err := s.convolve(); err != nil {
+			return err
+		}
+		s.result, s.next = s.next, s.result
+	}
+	s.jobPos = (s.inputSegmentPos + 1) % numInputSegments
+	if err := s.convolve(); err != nil {
+		return err
+	}
+	if !s.deferred {
+		s.result, s.next = s.next, s.result
+	}
+	return nil
+}
+
+// reset clears the stage's input history and pending output.
+func (s *stage) reset() {
+	s.wait()
+	s.err = nil
+	for _, seg := range s.inputSegments {
+		cmplxZero(seg)
+	}
+	zero(s.input)
+	zero(s.result)
+	zero(s.next)
+	s.inputSegmentPos = 0
+	s.inputPos = 0
+}
+
+// step queues the most recent block of input and swaps in the next block of
+// output.
+func (s *stage) step() error {
+	if !s.deferred {
+		s.prepare()
+		if err := s.compute(); err != nil {
+			return err
+		}
+		s.result, s.next = s.next, s.result
+		return nil
+	}
+
+	// The block queued at the previous boundary is due now.
+	s.wait()
+	if err := s.err; err != nil {
+		s.err = nil
+		return err
+	}
+	s.result, s.next = s.next, s.result
+
+	s.prepare()
+	if !s.worker.submit(s) {
+		return s.compute()
+	}
+	return nil
+}
+
+// prepare loads the most recent block of input into the current input segment
+// and moves on to the next segment. The segment is transformed by compute.
+func (s *stage) prepare() {
+	var (
+		blockSize        = s.blockSize
+		numInputSegments = len(s.inputSegments)
+	)
+
+	cmplxCopyReal(s.inputSegments[s.inputSegmentPos], s.input)
+	s.jobPos = s.inputSegmentPos
+
+	// Slide the history window forward by a block
+	copy(s.input, s.input[blockSize:])
+	zero(s.input[blockSize:])
+
+	// Step the current segment backwards
+	if s.inputSegmentPos > 0 {
+		s.inputSegmentPos--
+	} else {
+		s.inputSegmentPos = numInputSegments - 1
+	}
+}
+
+// compute transforms the prepared input segment and computes the next block
+// of output.
+func (s *stage) compute() error {
+	if err := Forward(s.inputSegments[s.jobPos]); err != nil {
+		return err
+	}
+	return s.convolve()
+}
+
+// convolve multiplies the input segments, from the prepared one backwards, by
+// the frequency response and computes the next block of output.
+func (s *stage) convolve() error {
+	var (
+		blockSize        = s.blockSize
+		numInputSegments = len(s.inputSegments)
+	)
+
+	cmplxZero(s.output)
+	index := s.jobPos + s.delay
+	for i := range s.responseSegments {
+		if index >= numInputSegments {
+			index -= numInputSegments
+		}
+		if err := cmplxMultiplyAdd(s.output, s.inputSegments[index], s.responseSegments[i]); err != nil {
+			return err
+		}
+		index++
+	}
+
+	if err := Inverse(s.output); err != nil {
+		return err
+	}
+	for i := range s.next {
+		s.next[i] = real(s.output[blockSize+i])
+	}
+
+	return nil
+}
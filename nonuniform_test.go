@@ -0,0 +1,211 @@
+package fourier
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvolution_NonUniform(t *testing.T) {
+	for _, blockSize := range []int{16, 64, 256} {
+		for _, impulseSize := range []int{5, 300, 20000} {
+			for _, directHead := range []bool{true, false} {
+				name := fmt.Sprintf("block=%d/ir=%d/head=%t", blockSize, impulseSize, directHead)
+				t.Run(name, func(t *testing.T) {
+					var (
+						impulse = make([]float64, impulseSize)
+						input   = make([]float64, 700)
+						output  = make([]float64, len(input)+impulseSize+blockSize)
+					)
+					for i := range impulse {
+						impulse[i] = math.Sin(float64(i)*0.3) / float64(i+1)
+					}
+					for i := range input {
+						input[i] = math.Cos(float64(i) * 0.05)
+					}
+
+					conv, err := NewConvolver(blockSize, impulse, NonUniform(directHead))
+					require.NoError(t, err)
+
+					// Feed irregular chunk sizes to exercise partial blocks.
+					for i, n := 0, 1; i < len(output); i, n = i+n, n%37+5 {
+						var (
+							end     = min(i+n, len(output))
+							inBegin = min(i, len(input))
+							inEnd   = min(end, len(input))
+						)
+						require.NoError(t, conv.Convolve(output[i:end], input[inBegin:inEnd], end-i))
+					}
+
+					latency := 0
+					if !directHead {
+						latency = blockSize
+					}
+					expected := directConvolve(input, impulse)
+					for i, v := range expected {
+						require.InDelta(t, v, output[i+latency], epsilon, "sample %d", i)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestPlanStages(t *testing.T) {
+	for _, blockSize := range []int{1, 64, 4096, 16384} {
+		for _, irSize := range []int{1, 1000, 240000} {
+			var (
+				plans  = planStages(blockSize, irSize)
+				offset = 0
+			)
+			require.NotEmpty(t, plans)
+			for i, p := range plans {
+				require.Equal(t, offset, p.offset)
+				require.True(t, isPowerOfTwo(p.blockSize))
+				require.True(t, p.delay >= 0)
+
+				// Each stage's region must begin exactly where its latency
+				// (one block plus the skipped blocks) places it.
+				require.Equal(t, p.offset+blockSize, (p.delay+1)*p.blockSize)
+
+				if i > 0 {
+					require.True(t, p.blockSize >= plans[i-1].blockSize)
+				}
+				offset += p.count * p.blockSize
+			}
+			require.True(t, offset >= irSize)
+		}
+	}
+}
+
+func BenchmarkConvolver_NonUniform(b *testing.B) {
+	var (
+		blockSize = 64
+		ir        = make([]float64, 5*48000)
+		in        = make([]float64, blockSize)
+		out       = make([]float64, blockSize)
+	)
+	for i := range ir {
+		ir[i] = math.Exp(-float64(i) / 48000)
+	}
+
+	for _, bm := range []struct {
+		name string
+		opts []ConvolverOption
+	}{
+		{"uniform", nil},
+		{"non-uniform", []ConvolverOption{NonUniform(false)}},
+		{"non-uniform-head", []ConvolverOption{NonUniform(true)}},
+		{"non-uniform-background", []ConvolverOption{NonUniform(true), BackgroundTail()}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			conv, _ := NewConvolver(blockSize, ir, bm.opts...)
+			defer conv.Close()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				conv.Convolve(out, in, blockSize)
+			}
+		})
+	}
+}
+
+// directConvolve computes the full linear convolution of two signals.
+func directConvolve(x, h []float64) []float64 {
+	y := make([]float64, len(x)+len(h)-1)
+	for i, xv := range x {
+		for j, hv := range h {
+			y[i+j] += xv * hv
+		}
+	}
+	return y
+}
+
+func TestConvolution_BackgroundTail(t *testing.T) {
+	var (
+		blockSize   = 32
+		impulseSize = 30000
+		impulse     = make([]float64, impulseSize)
+		input       = make([]float64, 2000)
+		numSamples  = len(input) + impulseSize
+	)
+	for i := range impulse {
+		impulse[i] = math.Sin(float64(i)*0.3) * math.Exp(-float64(i)/5000)
+	}
+	for i := range input {
+		input[i] = math.Cos(float64(i) * 0.05)
+	}
+
+	render := func(offline bool, opts ...ConvolverOption) []float64 {
+		conv, err := NewConvolver(blockSize, impulse, opts...)
+		require.NoError(t, err)
+		defer conv.Close()
+		conv.SetOffline(offline)
+
+		output := make([]float64, numSamples)
+		for i, n := 0, 1; i < len(output); i, n = i+n, n%37+5 {
+			var (
+				end     = min(i+n, len(output))
+				inBegin = min(i, len(input))
+				inEnd   = min(end, len(input))
+			)
+			require.NoError(t, conv.Convolve(output[i:end], input[inBegin:inEnd], end-i))
+		}
+		return output
+	}
+
+	var (
+		sync       = render(false, NonUniform(true))
+		background = render(false, NonUniform(true), BackgroundTail())
+		offline    = render(true, NonUniform(true), BackgroundTail())
+		expected   = directConvolve(input, impulse)
+	)
+
+	// Deferred processing must be reproducible regardless of scheduling.
+	require.Equal(t, offline, background)
+
+	for i, v := range expected {
+		require.InDelta(t, sync[i], background[i], epsilon, "sample %d", i)
+		require.InDelta(t, v, background[i], epsilon, "sample %d", i)
+	}
+}
+
+func TestConvolver_BackgroundTailRequiresNonUniform(t *testing.T) {
+	_, err := NewConvolver(64, []float64{1}, BackgroundTail())
+	require.Error(t, err)
+}
+
+func TestConvolver_BackgroundTailConstructionError(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for i := 0; i < 10; i++ {
+		_, err := NewConvolver(64, make([]float64, 100), NonUniform(true), BackgroundTail(), MaxIRLength(10))
+		require.Error(t, err)
+	}
+	// The worker of every failed construction is stopped.
+	require.True(t, runtime.NumGoroutine() < before+10)
+}
+
+func TestConvolver_BackgroundTailError(t *testing.T) {
+	conv, err := NewConvolver(32, make([]float64, 4096), NonUniform(true), BackgroundTail())
+	require.NoError(t, err)
+	defer conv.Close()
+
+	// Break the input segments of the last, deferred stage so that the
+	// worker fails to transform them.
+	stages := conv.engine.(*nonUniform).stages
+	last := stages[len(stages)-1]
+	require.True(t, last.deferred)
+	for i := range last.inputSegments {
+		last.inputSegments[i] = make([]complex128, 3)
+	}
+
+	block := make([]float64, 32)
+	for i := 0; i < 1000 && err == nil; i++ {
+		err = conv.Convolve(block, block, len(block))
+	}
+	require.Error(t, err)
+}
@@ -0,0 +1,188 @@
+package resample
+
+import (
+	"errors"
+	"math"
+)
+
+// Arbitrary resamples by any ratio with windowed-sinc interpolation: each
+// output sample is a sum of the input samples around it, weighted by a
+// band-limited kernel evaluated at their distance from it. The kernel is
+// tabulated at a resolution set by the Quality and linearly interpolated.
+type Arbitrary struct {
+	ratio       float64
+	numChannels int
+
+	// table holds the kernel at phases points per sample of the lower of the
+	// two rates, centered on index center.
+	table  []float64
+	phases int
+	center int
+
+	// scale is the kernel's time scale: the ratio when down-sampling, which
+	// stretches it to band-limit to the output rate, and 1 otherwise.
+	scale float64
+	// width is half the kernel's length, in input samples.
+	width float64
+
+	history [][]float64
+	weights []float64
+
+	// pos is the position in history of the next output sample.
+	pos      float64
+	startPos float64
+	prefill  int
+	latency  int
+}
+
+// NewArbitrary returns a resampler that produces ratio samples for every
+// sample of input.
+func NewArbitrary(ratio float64, q Quality, opts ...Option) (*Arbitrary, error) {
+	if ratio <= 0 || math.IsInf(ratio, 0) || math.IsNaN(ratio) {
+		return nil, errors.New("ratio must be positive")
+	}
+	d, err := q.design()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	table, err := d.prototype(d.phases)
+	if err != nil {
+		return nil, err
+	}
+	for i := range table {
+		table[i] *= float64(d.phases)
+	}
+
+	r := &Arbitrary{
+		ratio:       ratio,
+		numChannels: cfg.numChannels,
+		table:       table,
+		phases:      d.phases,
+		center:      len(table) / 2,
+		scale:       math.Min(1, ratio),
+		history:     make([][]float64, cfg.numChannels),
+	}
+	r.width = float64(r.center) / float64(r.phases) / r.scale
+	r.weights = make([]float64, 2*int(math.Ceil(r.width))+2)
+
+	// The history starts with enough silence to center the kernel on the
+	// first output sample. Starting part way in leaves a whole number of
+	// output samples of delay.
+	r.prefill = int(math.Ceil(r.width))
+	r.latency = int(float64(r.prefill) * ratio)
+	r.startPos = float64(r.prefill) - float64(r.latency)/ratio
+	r.Reset()
+	return r, nil
+}
+
+// Ratio returns the number of output samples produced per input sample.
+func (r *Arbitrary) Ratio() float64 {
+	return r.ratio
+}
+
+// MaxOutput returns the most samples per channel that Process produces from
+// numSamples samples.
+func (r *Arbitrary) MaxOutput(numSamples int) int {
+	return int(math.Ceil(float64(numSamples)*r.ratio)) + 1
+}
+
+// Latency returns the number of samples, at the output rate, the output is
+// delayed by.
+func (r *Arbitrary) Latency() int {
+	return r.latency
+}
+
+// Process resamples numSamples samples of each channel of in into out,
+// returning the number of samples written to each channel. out must hold
+// MaxOutput(numSamples) samples per channel.
+func (r *Arbitrary) Process(out, in []float64, numSamples int) (int, error) {
+	if err := validateBuffers(out, in, numSamples, r.MaxOutput(numSamples), r.numChannels); err != nil {
+		return 0, err
+	}
+
+	var (
+		nc   = r.numChannels
+		step = 1 / r.ratio
+		n    = 0
+	)
+	for i := 0; i < numSamples; i++ {
+		for ch := range r.history {
+			r.history[ch] = append(r.history[ch], in[i*nc+ch])
+		}
+		last := float64(len(r.history[0]) - 1)
+		for r.pos+r.width <= last {
+			first, weights := r.interpolate()
+			for ch, hist := range r.history {
+				var sum float64
+				for j, w := range weights {
+					sum += w * hist[first+j]
+				}
+				out[n*nc+ch] = sum
+			}
+			n++
+			r.pos += step
+		}
+	}
+
+	// Drop the history no later output sample reaches back to.
+	if drop := int(r.pos-r.width) - 1; drop > 0 {
+		for ch, hist := range r.history {
+			copy(hist, hist[drop:])
+			r.history[ch] = hist[:len(hist)-drop]
+		}
+		r.pos -= float64(drop)
+	}
+	return n, nil
+}
+
+// interpolate returns the kernel evaluated at the distance of each input
+// sample in reach from the next output sample, and the index in history of the
+// first of them.
+func (r *Arbitrary) interpolate() (int, []float64) {
+	var (
+		first = int(math.Ceil(r.pos - r.width))
+		last  = int(math.Floor(r.pos + r.width))
+		size  = float64(r.phases) * r.scale
+	)
+	if first < 0 {
+		first = 0
+	}
+	weights := r.weights[:last-first+1]
+	for j := range weights {
+		var (
+			u = (r.pos-float64(first+j))*size + float64(r.center)
+			k = int(u)
+			w float64
+		)
+		switch {
+		case k < 0 || k >= len(r.table):
+		case k == len(r.table)-1:
+			w = r.table[k]
+		default:
+			w = r.table[k] + (u-float64(k))*(r.table[k+1]-r.table[k])
+		}
+		weights[j] = w * r.scale
+	}
+	return first, weights
+}
+
+// Reset clears the input history.
+func (r *Arbitrary) Reset() {
+	for ch := range r.history {
+		hist := r.history[ch]
+		if hist == nil {
+			hist = make([]float64, 0, 2*r.prefill+4096)
+		}
+		hist = hist[:r.prefill]
+		for i := range hist {
+			hist[i] = 0
+		}
+		r.history[ch] = hist
+	}
+	r.pos = r.startPos
+}
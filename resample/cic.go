@@ -0,0 +1,369 @@
+package resample
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/brettbuddin/fourier/window"
+)
+
+// CIC describes a cascaded integrator-comb filter: Stages integrators at the
+// high rate and Stages combs, each with a differential delay of Delay
+// samples, at the low rate, on either side of a rate change by Factor. It
+// filters without multiplies, but its response droops across the pass band;
+// see Compensator.
+//
+// Reference: E. B. Hogenauer, "An Economical Class of Digital Filters for
+// Decimation and Interpolation", IEEE Transactions on Acoustics, Speech, and
+// Signal Processing, 1981.
+type CIC struct {
+	Factor int
+	Stages int
+	Delay  int
+}
+
+// headroom is the number of bits CIC stages leave above full scale, so inputs
+// up to 2^headroom in magnitude don't overflow.
+const headroom = 4
+
+// minPrecision is the fewest fractional bits CIC stages quantize their input
+// to.
+const minPrecision = 24
+
+func (c CIC) validate() error {
+	if c.Factor < 1 {
+		return errors.New("CIC factor must be positive")
+	}
+	if c.Stages < 1 {
+		return errors.New("CIC must have at least one stage")
+	}
+	if c.Delay < 1 {
+		return errors.New("CIC differential delay must be positive")
+	}
+	if c.precision() < minPrecision {
+		return fmt.Errorf("CIC gain of 2^%.1f leaves too little precision", c.growth())
+	}
+	return nil
+}
+
+// growth returns the number of bits the signal grows by through the filter.
+func (c CIC) growth() float64 {
+	return float64(c.Stages) * math.Log2(float64(c.Factor*c.Delay))
+}
+
+// precision returns the number of fractional bits the input is quantized to.
+// The integrators wrap around on overflow, which the combs undo, so the
+// arithmetic is exact as long as the output fits.
+func (c CIC) precision() int {
+	return 63 - headroom - int(math.Ceil(c.growth()))
+}
+
+// Magnitude returns the filter's gain, normalized to one at DC, at the
+// frequency f as a fraction of the low sample rate.
+func (c CIC) Magnitude(f float64) float64 {
+	var (
+		rm  = float64(c.Factor * c.Delay)
+		num = math.Sin(math.Pi * float64(c.Delay) * f)
+		den = rm * math.Sin(math.Pi*f/float64(c.Factor))
+	)
+	if den == 0 {
+		return 1
+	}
+	return math.Pow(math.Abs(num/den), float64(c.Stages))
+}
+
+// latency returns the delay of the filter, in samples at the high rate. It's
+// rounded down when the delay isn't a whole number of samples.
+func (c CIC) latency() int {
+	return c.Stages * (c.Factor*c.Delay - 1) / 2
+}
+
+// Compensator designs a numTaps-long linear-phase FIR filter, at the low
+// sample rate, that flattens the filter's droop below cutoff and cuts off
+// above it. cutoff is a fraction of the low sample rate, and must be below
+// both Nyquist and 1/Delay, where the filter's first null is and its inverse
+// is unbounded. The filter is designed by sampling the inverse of the
+// filter's response and windowing with wf, or the Blackman window if wf is
+// nil.
+func (c CIC) Compensator(numTaps int, cutoff float64, wf window.Func) ([]float64, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	if numTaps < 1 {
+		return nil, errors.New("number of taps must be positive")
+	}
+	if cutoff <= 0 || cutoff > 0.5 {
+		return nil, errors.New("cutoff must be between 0 and 0.5")
+	}
+	if cutoff >= 1/float64(c.Delay) {
+		return nil, fmt.Errorf("cutoff must be below the first null of the filter at %g", 1/float64(c.Delay))
+	}
+	if wf == nil {
+		wf = window.Blackman
+	}
+
+	// Integrate the ideal response, the inverse of the droop up to cutoff,
+	// by Simpson's rule.
+	const steps = 2048
+	var (
+		h      = make([]float64, numTaps)
+		center = float64(numTaps-1) / 2
+		df     = cutoff / steps
+	)
+	for i := range h {
+		m := float64(i) - center
+		var sum float64
+		for k := 0; k <= steps; k++ {
+			var (
+				f = float64(k) * df
+				v = math.Cos(2*math.Pi*f*m) / c.Magnitude(f)
+				w = 2.0
+			)
+			switch {
+			case k == 0 || k == steps:
+				w = 1
+			case k%2 == 1:
+				w = 4
+			}
+			sum += w * v
+		}
+		h[i] = 2 * sum * df / 3
+		if numTaps > 1 {
+			h[i] *= wf(float64(i), numTaps-1)
+		}
+	}
+
+	var dc float64
+	for _, v := range h {
+		dc += v
+	}
+	for i := range h {
+		h[i] /= dc
+	}
+	return h, nil
+}
+
+// cicState holds the integrators and combs of one channel.
+type cicState struct {
+	integrators []int64
+	// combs holds the last Delay inputs to each comb.
+	combs []int64
+}
+
+// cicStages holds the state shared by CIC decimators and interpolators.
+type cicStages struct {
+	cic         CIC
+	numChannels int
+	channels    []cicState
+	combPos     int
+	scale       float64
+}
+
+func newCICStages(c CIC, opts []Option) (cicStages, error) {
+	if err := c.validate(); err != nil {
+		return cicStages{}, err
+	}
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return cicStages{}, err
+	}
+	s := cicStages{
+		cic:         c,
+		numChannels: cfg.numChannels,
+		channels:    make([]cicState, cfg.numChannels),
+		scale:       math.Ldexp(1, c.precision()),
+	}
+	for ch := range s.channels {
+		s.channels[ch] = cicState{
+			integrators: make([]int64, c.Stages),
+			combs:       make([]int64, c.Stages*c.Delay),
+		}
+	}
+	return s, nil
+}
+
+func (s *cicStages) integrate(ch int, v int64) int64 {
+	acc := s.channels[ch].integrators
+	for i := range acc {
+		acc[i] += v
+		v = acc[i]
+	}
+	return v
+}
+
+func (s *cicStages) comb(ch int, v int64) int64 {
+	var (
+		delay = s.cic.Delay
+		combs = s.channels[ch].combs
+	)
+	for i := 0; i < s.cic.Stages; i++ {
+		slot := &combs[i*delay+s.combPos]
+		v, *slot = v-*slot, v
+	}
+	return v
+}
+
+// advance moves the combs on by a sample.
+func (s *cicStages) advance() {
+	s.combPos++
+	if s.combPos == s.cic.Delay {
+		s.combPos = 0
+	}
+}
+
+func (s *cicStages) reset() {
+	for _, c := range s.channels {
+		for i := range c.integrators {
+			c.integrators[i] = 0
+		}
+		for i := range c.combs {
+			c.combs[i] = 0
+		}
+	}
+	s.combPos = 0
+}
+
+// CICDecimator reduces the sample rate by a CIC's factor. Its input is
+// quantized to fixed point with at least 24 fractional bits and 4 bits of
+// headroom above full scale.
+type CICDecimator struct {
+	cicStages
+	count, startCount int
+	latency           int
+	gain              float64
+}
+
+// NewCICDecimator returns a decimator with the CIC filter c.
+func NewCICDecimator(c CIC, opts ...Option) (*CICDecimator, error) {
+	s, err := newCICStages(c, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Output samples line up with input samples so as to leave a whole
+	// number of output samples of delay.
+	delay := c.latency()
+	d := &CICDecimator{
+		cicStages:  s,
+		startCount: c.Factor - 1 - delay%c.Factor,
+		latency:    delay / c.Factor,
+		gain:       1 / (s.scale * math.Pow(float64(c.Factor*c.Delay), float64(c.Stages))),
+	}
+	d.count = d.startCount
+	return d, nil
+}
+
+// MaxOutput returns the most samples per channel that Process produces from
+// numSamples samples.
+func (d *CICDecimator) MaxOutput(numSamples int) int {
+	return (numSamples + d.cic.Factor - 1) / d.cic.Factor
+}
+
+// Latency returns the number of samples, at the output rate, the output is
+// delayed by. It's rounded down when the delay isn't a whole number of
+// samples.
+func (d *CICDecimator) Latency() int {
+	return d.latency
+}
+
+// Process decimates numSamples samples of each channel of in into out,
+// returning the number of samples written to each channel. out must hold
+// MaxOutput(numSamples) samples per channel.
+func (d *CICDecimator) Process(out, in []float64, numSamples int) (int, error) {
+	if err := validateBuffers(out, in, numSamples, d.MaxOutput(numSamples), d.numChannels); err != nil {
+		return 0, err
+	}
+
+	var (
+		nc = d.numChannels
+		n  = 0
+	)
+	for i := 0; i < numSamples; i++ {
+		d.count++
+		emit := d.count == d.cic.Factor
+		if emit {
+			d.count = 0
+		}
+		for ch := 0; ch < nc; ch++ {
+			v := d.integrate(ch, int64(math.Round(in[i*nc+ch]*d.scale)))
+			if emit {
+				out[n*nc+ch] = float64(d.comb(ch, v)) * d.gain
+			}
+		}
+		if emit {
+			d.advance()
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Reset clears the filter state.
+func (d *CICDecimator) Reset() {
+	d.reset()
+	d.count = d.startCount
+}
+
+// CICInterpolator raises the sample rate by a CIC's factor. Its input is
+// quantized to fixed point with at least 24 fractional bits and 4 bits of
+// headroom above full scale.
+type CICInterpolator struct {
+	cicStages
+	gain float64
+}
+
+// NewCICInterpolator returns an interpolator with the CIC filter c.
+func NewCICInterpolator(c CIC, opts ...Option) (*CICInterpolator, error) {
+	s, err := newCICStages(c, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &CICInterpolator{
+		cicStages: s,
+		gain:      float64(c.Factor) / (s.scale * math.Pow(float64(c.Factor*c.Delay), float64(c.Stages))),
+	}, nil
+}
+
+// MaxOutput returns the most samples per channel that Process produces from
+// numSamples samples.
+func (u *CICInterpolator) MaxOutput(numSamples int) int {
+	return numSamples * u.cic.Factor
+}
+
+// Latency returns the number of samples, at the output rate, the output is
+// delayed by. It's rounded down when the delay isn't a whole number of
+// samples.
+func (u *CICInterpolator) Latency() int {
+	return u.cic.latency()
+}
+
+// Process interpolates numSamples samples of each channel of in into out,
+// returning the number of samples written to each channel. out must hold
+// MaxOutput(numSamples) samples per channel.
+func (u *CICInterpolator) Process(out, in []float64, numSamples int) (int, error) {
+	if err := validateBuffers(out, in, numSamples, u.MaxOutput(numSamples), u.numChannels); err != nil {
+		return 0, err
+	}
+
+	var (
+		nc     = u.numChannels
+		factor = u.cic.Factor
+	)
+	for i := 0; i < numSamples; i++ {
+		for ch := 0; ch < nc; ch++ {
+			v := u.comb(ch, int64(math.Round(in[i*nc+ch]*u.scale)))
+			for k := 0; k < factor; k++ {
+				out[(i*factor+k)*nc+ch] = float64(u.integrate(ch, v)) * u.gain
+				v = 0
+			}
+		}
+		u.advance()
+	}
+	return numSamples * factor, nil
+}
+
+// Reset clears the filter state.
+func (u *CICInterpolator) Reset() {
+	u.reset()
+}
@@ -0,0 +1,186 @@
+package resample
+
+import (
+	"math"
+	"testing"
+
+	"github.com/brettbuddin/fourier/filter"
+	"github.com/stretchr/testify/require"
+)
+
+// boxcars returns the impulse response of a CIC at its high rate, normalized
+// to unity gain at DC.
+func boxcars(c CIC) []float64 {
+	h := []float64{1}
+	for s := 0; s < c.Stages; s++ {
+		next := make([]float64, len(h)+c.Factor*c.Delay-1)
+		for i, v := range h {
+			for k := 0; k < c.Factor*c.Delay; k++ {
+				next[i+k] += v
+			}
+		}
+		h = next
+	}
+	gain := math.Pow(float64(c.Factor*c.Delay), float64(c.Stages))
+	for i := range h {
+		h[i] /= gain
+	}
+	return h
+}
+
+// convolve returns x filtered by h, as long as x.
+func convolve(x, h []float64) []float64 {
+	y := make([]float64, len(x))
+	for n := range y {
+		for k, v := range h {
+			if n-k >= 0 {
+				y[n] += v * x[n-k]
+			}
+		}
+	}
+	return y
+}
+
+func TestCICDecimator(t *testing.T) {
+	for _, c := range []CIC{
+		{Factor: 8, Stages: 4, Delay: 1},
+		{Factor: 5, Stages: 3, Delay: 2},
+		{Factor: 16, Stages: 5, Delay: 1},
+	} {
+		d, err := NewCICDecimator(c)
+		require.NoError(t, err)
+
+		var (
+			in     = noise(1, 4000)
+			full   = convolve(in, boxcars(c))
+			phase  = c.latency() % c.Factor
+			stream = streaming(t, d, in, 1)
+		)
+		require.Equal(t, len(in)/c.Factor, len(stream))
+		for m, v := range stream {
+			require.InDelta(t, full[m*c.Factor+phase], v, 1e-6, "%+v", c)
+		}
+	}
+}
+
+func TestCICInterpolator(t *testing.T) {
+	for _, c := range []CIC{
+		{Factor: 8, Stages: 4, Delay: 1},
+		{Factor: 3, Stages: 2, Delay: 2},
+	} {
+		u, err := NewCICInterpolator(c)
+		require.NoError(t, err)
+		require.Equal(t, c.latency(), u.Latency())
+
+		var (
+			in      = noise(2, 500)
+			stuffed = make([]float64, len(in)*c.Factor)
+		)
+		for i, v := range in {
+			stuffed[i*c.Factor] = v * float64(c.Factor)
+		}
+		full := convolve(stuffed, boxcars(c))
+
+		out := streaming(t, u, in, 1)
+		require.Equal(t, len(full), len(out))
+		for i := range out {
+			require.InDelta(t, full[i], out[i], 1e-6)
+		}
+	}
+}
+
+func TestCICChannels(t *testing.T) {
+	c := CIC{Factor: 4, Stages: 3, Delay: 1}
+	var (
+		left   = noise(1, 1000)
+		right  = noise(2, 1000)
+		stereo = make([]float64, 2000)
+	)
+	for i := range left {
+		stereo[2*i], stereo[2*i+1] = left[i], right[i]
+	}
+	d, err := NewCICDecimator(c, Channels(2))
+	require.NoError(t, err)
+	out := streaming(t, d, stereo, 2)
+
+	for ch, x := range [][]float64{left, right} {
+		mono, err := NewCICDecimator(c)
+		require.NoError(t, err)
+		expect := streaming(t, mono, x, 1)
+		for i, v := range expect {
+			require.Equal(t, v, out[2*i+ch])
+		}
+	}
+
+	// A reset decimator repeats itself.
+	d.Reset()
+	again := streaming(t, d, stereo, 2)
+	require.Equal(t, out, again)
+}
+
+func TestCICMagnitude(t *testing.T) {
+	c := CIC{Factor: 10, Stages: 4, Delay: 2}
+	freqs := []float64{0, 0.05, 0.1, 0.2, 0.3, 0.45}
+	high := make([]float64, len(freqs))
+	for i, f := range freqs {
+		high[i] = f / float64(c.Factor)
+	}
+	r, err := filter.ResponseAt(boxcars(c), nil, high)
+	require.NoError(t, err)
+	for i, f := range freqs {
+		require.InDelta(t, r.Magnitude()[i], c.Magnitude(f), 1e-12)
+	}
+}
+
+func TestCICCompensator(t *testing.T) {
+	c := CIC{Factor: 16, Stages: 4, Delay: 1}
+	h, err := c.Compensator(63, 0.25, nil)
+	require.NoError(t, err)
+	require.Equal(t, filter.TypeI, filter.SymmetryOf(h))
+
+	r, err := filter.NewResponse(h, nil, 1024)
+	require.NoError(t, err)
+	var (
+		mag          = r.Magnitude()
+		droop, flat  float64
+		lower, upper = math.Inf(1), math.Inf(-1)
+	)
+	for i, f := range r.Frequencies {
+		if f > 0.2 {
+			break
+		}
+		// The droop reaches more than 2 dB; compensated, the response is
+		// flat to within 0.05 dB.
+		droop = math.Min(droop, 20*math.Log10(c.Magnitude(f)))
+		flat = 20 * math.Log10(c.Magnitude(f)*mag[i])
+		lower, upper = math.Min(lower, flat), math.Max(upper, flat)
+	}
+	require.True(t, droop < -2)
+	require.True(t, upper-lower < 0.05, "ripple %g dB", upper-lower)
+
+	_, err = c.Compensator(0, 0.25, nil)
+	require.Error(t, err)
+	_, err = c.Compensator(63, 0.6, nil)
+	require.Error(t, err)
+
+	// With a differential delay of 3 the response has a null at a third of
+	// the low rate.
+	_, err = CIC{Factor: 16, Stages: 4, Delay: 3}.Compensator(63, 0.4, nil)
+	require.Error(t, err)
+	_, err = CIC{Factor: 16, Stages: 4, Delay: 3}.Compensator(63, 0.3, nil)
+	require.NoError(t, err)
+}
+
+func TestCICErrors(t *testing.T) {
+	for _, c := range []CIC{
+		{Factor: 0, Stages: 4, Delay: 1},
+		{Factor: 4, Stages: 0, Delay: 1},
+		{Factor: 4, Stages: 4, Delay: 0},
+		{Factor: 1000, Stages: 6, Delay: 2},
+	} {
+		_, err := NewCICDecimator(c)
+		require.Error(t, err, "%+v", c)
+		_, err = NewCICInterpolator(c)
+		require.Error(t, err, "%+v", c)
+	}
+}
@@ -0,0 +1,278 @@
+package resample
+
+import (
+	"errors"
+
+	"github.com/brettbuddin/fourier/filter"
+	"github.com/brettbuddin/fourier/window"
+)
+
+// DesignHalfBand returns a Kaiser-windowed half-band low-pass kernel: a filter
+// with its cutoff at a quarter of the sample rate, whose gains at f and 0.5-f
+// sum to one. Every other tap but the center one is zero, so the filter costs
+// half as much as another of its length. transition is the width of the
+// transition band, centered on the cutoff, and attenuation is the least
+// attenuation in the stop band, in decibels.
+//
+// The kernel's length is of the form 4K+3, so that its first and last taps
+// are not zero, and its center tap is exactly 0.5.
+func DesignHalfBand(transition, attenuation float64) ([]float64, error) {
+	if transition <= 0 || transition >= 0.5 {
+		return nil, errors.New("transition width must be between 0 and 0.5")
+	}
+	numTaps, beta, err := filter.KaiserSpec{
+		Type:            filter.LowPass,
+		SampleRate:      1, // frequencies are fractions of the rate
+		Cutoffs:         []float64{0.25},
+		TransitionWidth: transition,
+		Attenuation:     attenuation,
+	}.Parameters()
+	if err != nil {
+		return nil, err
+	}
+	numTaps = 4*(numTaps/4) + 3
+
+	var (
+		h      = make([]float64, numTaps)
+		center = numTaps / 2
+		wf     = window.Kaiser(beta)
+	)
+	for i := range h {
+		if m := i - center; m%2 != 0 {
+			h[i] = 0.5 * window.Sinc(float64(m)/2) * wf(float64(i), numTaps-1)
+		}
+	}
+	h[center] = 0.5
+	return h, nil
+}
+
+// halfBand holds the distinct taps of a half-band kernel of length 4K+3: the
+// nonzero taps h[0], h[2], ..., h[2K] before the center. The rest follow from
+// symmetry.
+type halfBand struct {
+	taps        []float64
+	numChannels int
+}
+
+func newHalfBand(transition, attenuation float64, opts []Option) (halfBand, error) {
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return halfBand{}, err
+	}
+	h, err := DesignHalfBand(transition, attenuation)
+	if err != nil {
+		return halfBand{}, err
+	}
+	return newHalfBandTaps(h, cfg), nil
+}
+
+func newHalfBandTaps(h []float64, cfg config) halfBand {
+	taps := make([]float64, (len(h)+1)/4)
+	for j := range taps {
+		taps[j] = h[2*j]
+	}
+	return halfBand{taps: taps, numChannels: cfg.numChannels}
+}
+
+// HalfBandDecimator halves the sample rate with a half-band filter, computing
+// only the samples that survive decimation and only the nonzero taps, each
+// once for the pair of samples it is shared by.
+type HalfBandDecimator struct {
+	halfBand
+
+	// history holds the most recent input of each channel twice over, as in
+	// Rational.
+	history [][]float64
+	pos     int
+
+	// odd is whether the next input sample has an odd index.
+	odd bool
+}
+
+// NewHalfBandDecimator returns a decimator by two with a half-band filter of
+// the given transition width, as a fraction of the input rate, and stop band
+// attenuation in decibels. See DesignHalfBand.
+func NewHalfBandDecimator(transition, attenuation float64, opts ...Option) (*HalfBandDecimator, error) {
+	hb, err := newHalfBand(transition, attenuation, opts)
+	if err != nil {
+		return nil, err
+	}
+	return newHalfBandDecimator(hb), nil
+}
+
+func newHalfBandDecimator(hb halfBand) *HalfBandDecimator {
+	d := &HalfBandDecimator{
+		halfBand: hb,
+		history:  make([][]float64, hb.numChannels),
+	}
+	for ch := range d.history {
+		d.history[ch] = make([]float64, 2*d.length())
+	}
+	return d
+}
+
+// length returns the length of the kernel.
+func (hb halfBand) length() int {
+	return 4*len(hb.taps) - 1
+}
+
+// MaxOutput returns the most samples per channel that Process produces from
+// numSamples samples.
+func (d *HalfBandDecimator) MaxOutput(numSamples int) int {
+	return (numSamples + 1) / 2
+}
+
+// Latency returns the number of samples, at the output rate, the output is
+// delayed by.
+func (d *HalfBandDecimator) Latency() int {
+	return len(d.taps) - 1
+}
+
+// Process decimates numSamples samples of each channel of in into out,
+// returning the number of samples written to each channel. out must hold
+// MaxOutput(numSamples) samples per channel.
+func (d *HalfBandDecimator) Process(out, in []float64, numSamples int) (int, error) {
+	if err := validateBuffers(out, in, numSamples, d.MaxOutput(numSamples), d.numChannels); err != nil {
+		return 0, err
+	}
+
+	var (
+		nc     = d.numChannels
+		length = d.length()
+		center = length / 2
+		n      = 0
+	)
+	for i := 0; i < numSamples; i++ {
+		d.pos++
+		if d.pos == length {
+			d.pos = 0
+		}
+		for ch, hist := range d.history {
+			v := in[i*nc+ch]
+			hist[d.pos] = v
+			hist[d.pos+length] = v
+		}
+
+		// Output samples line up with odd input samples, which leaves a whole
+		// number of output samples of delay.
+		odd := d.odd
+		d.odd = !d.odd
+		if !odd {
+			continue
+		}
+		for ch, hist := range d.history {
+			window := hist[d.pos+1 : d.pos+1+length]
+			sum := 0.5 * window[center]
+			for j, t := range d.taps {
+				sum += t * (window[2*j] + window[length-1-2*j])
+			}
+			out[n*nc+ch] = sum
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Reset clears the input history.
+func (d *HalfBandDecimator) Reset() {
+	for _, hist := range d.history {
+		for i := range hist {
+			hist[i] = 0
+		}
+	}
+	d.pos = 0
+	d.odd = false
+}
+
+// HalfBandInterpolator doubles the sample rate with a half-band filter. Of
+// each pair of output samples, one is a delayed input sample and the other
+// takes only the nonzero taps, each once for the pair of samples it is shared
+// by.
+type HalfBandInterpolator struct {
+	halfBand
+
+	history [][]float64
+	pos     int
+}
+
+// NewHalfBandInterpolator returns an interpolator by two with a half-band
+// filter of the given transition width, as a fraction of the output rate, and
+// stop band attenuation in decibels. See DesignHalfBand.
+func NewHalfBandInterpolator(transition, attenuation float64, opts ...Option) (*HalfBandInterpolator, error) {
+	hb, err := newHalfBand(transition, attenuation, opts)
+	if err != nil {
+		return nil, err
+	}
+	return newHalfBandInterpolator(hb), nil
+}
+
+func newHalfBandInterpolator(hb halfBand) *HalfBandInterpolator {
+	u := &HalfBandInterpolator{
+		halfBand: hb,
+		history:  make([][]float64, hb.numChannels),
+	}
+	for ch := range u.history {
+		u.history[ch] = make([]float64, 4*len(hb.taps))
+	}
+	return u
+}
+
+// MaxOutput returns the most samples per channel that Process produces from
+// numSamples samples.
+func (u *HalfBandInterpolator) MaxOutput(numSamples int) int {
+	return 2 * numSamples
+}
+
+// Latency returns the number of samples, at the output rate, the output is
+// delayed by.
+func (u *HalfBandInterpolator) Latency() int {
+	return u.length() / 2
+}
+
+// Process interpolates numSamples samples of each channel of in into out,
+// returning the number of samples written to each channel. out must hold
+// MaxOutput(numSamples) samples per channel.
+func (u *HalfBandInterpolator) Process(out, in []float64, numSamples int) (int, error) {
+	if err := validateBuffers(out, in, numSamples, u.MaxOutput(numSamples), u.numChannels); err != nil {
+		return 0, err
+	}
+
+	var (
+		nc     = u.numChannels
+		length = 2 * len(u.taps)
+		n      = 0
+	)
+	for i := 0; i < numSamples; i++ {
+		u.pos++
+		if u.pos == length {
+			u.pos = 0
+		}
+		for ch, hist := range u.history {
+			v := in[i*nc+ch]
+			hist[u.pos] = v
+			hist[u.pos+length] = v
+
+			// The up-sampling zeros would halve the gain, so the taps are
+			// doubled.
+			window := hist[u.pos+1 : u.pos+1+length]
+			var sum float64
+			for j, t := range u.taps {
+				sum += t * (window[j] + window[length-1-j])
+			}
+			out[n*nc+ch] = 2 * sum
+			out[(n+1)*nc+ch] = window[length-len(u.taps)]
+		}
+		n += 2
+	}
+	return n, nil
+}
+
+// Reset clears the input history.
+func (u *HalfBandInterpolator) Reset() {
+	for _, hist := range u.history {
+		for i := range hist {
+			hist[i] = 0
+		}
+	}
+	u.pos = 0
+}
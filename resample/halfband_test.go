@@ -0,0 +1,116 @@
+package resample
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/brettbuddin/fourier/filter"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDesignHalfBand(t *testing.T) {
+	for _, v := range []struct{ transition, attenuation float64 }{
+		{0.1, 60},
+		{0.05, 90},
+		{0.2, 40},
+	} {
+		h, err := DesignHalfBand(v.transition, v.attenuation)
+		require.NoError(t, err)
+		require.Equal(t, 3, len(h)%4)
+		require.Equal(t, filter.TypeI, filter.SymmetryOf(h))
+
+		center := len(h) / 2
+		require.Equal(t, 0.5, h[center])
+		require.NotZero(t, h[0])
+		for i := range h {
+			if i != center && (i-center)%2 == 0 {
+				require.Zero(t, h[i])
+			}
+		}
+
+		// The gains at f and 0.5-f sum to one.
+		freqs := []float64{0, 0.05, 0.1, 0.2}
+		mirror := make([]float64, len(freqs))
+		for i, f := range freqs {
+			mirror[i] = 0.5 - f
+		}
+		a, err := filter.ResponseAt(h, nil, freqs)
+		require.NoError(t, err)
+		b, err := filter.ResponseAt(h, nil, mirror)
+		require.NoError(t, err)
+		for i := range freqs {
+			// Zero-phase amplitudes: undo the delay of the center tap.
+			shift := func(f float64) complex128 {
+				return complex(math.Cos(2*math.Pi*f*float64(center)), math.Sin(2*math.Pi*f*float64(center)))
+			}
+			sum := real(a.Values[i]*shift(freqs[i])) + real(b.Values[i]*shift(mirror[i]))
+			require.InDelta(t, 1, sum, 1e-12)
+		}
+
+		r, err := filter.NewResponse(h, nil, 1<<14)
+		require.NoError(t, err)
+		require.True(t, r.Attenuation(0.25+v.transition/2, 0.5) > v.attenuation-1)
+	}
+
+	_, err := DesignHalfBand(0, 60)
+	require.Error(t, err)
+	_, err = DesignHalfBand(0.1, 0)
+	require.Error(t, err)
+}
+
+func noise(seed int64, n int) []float64 {
+	rng := rand.New(rand.NewSource(seed))
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = rng.Float64()*2 - 1
+	}
+	return x
+}
+
+func TestHalfBand(t *testing.T) {
+	h, err := DesignHalfBand(0.1, 80)
+	require.NoError(t, err)
+	cfg := config{numChannels: 2}
+	in := noise(1, 2*2001)
+
+	// The half-band resamplers match polyphase resamplers with the same
+	// kernel, which do every multiply.
+	for _, v := range []struct {
+		fast, reference Resampler
+	}{
+		{newHalfBandDecimator(newHalfBandTaps(h, cfg)), newRational(1, 2, h, cfg)},
+		{newHalfBandInterpolator(newHalfBandTaps(h, cfg)), newRational(2, 1, h, cfg)},
+	} {
+		require.Equal(t, v.reference.Latency(), v.fast.Latency())
+
+		expect := make([]float64, v.reference.MaxOutput(2001)*2)
+		n, err := v.reference.Process(expect, in, 2001)
+		require.NoError(t, err)
+
+		out := streaming(t, v.fast, in, 2)
+		require.Equal(t, 2*n, len(out))
+		for i := range out {
+			require.InDelta(t, expect[i], out[i], 1e-12)
+		}
+
+		v.fast.Reset()
+		again := make([]float64, v.fast.MaxOutput(2001)*2)
+		m, err := v.fast.Process(again, in, 2001)
+		require.NoError(t, err)
+		require.Equal(t, n, m)
+		require.Equal(t, out, again[:2*m])
+	}
+}
+
+func TestHalfBandErrors(t *testing.T) {
+	_, err := NewHalfBandDecimator(0.6, 60)
+	require.Error(t, err)
+	_, err = NewHalfBandInterpolator(0.1, 60, Channels(0))
+	require.Error(t, err)
+
+	d, err := NewHalfBandDecimator(0.1, 60)
+	require.NoError(t, err)
+	_, err = d.Process(make([]float64, 1), make([]float64, 4), 4)
+	require.Error(t, err)
+}
@@ -0,0 +1,339 @@
+package resample
+
+import (
+	"errors"
+
+	"github.com/brettbuddin/fourier/filter"
+	"github.com/brettbuddin/fourier/window"
+)
+
+// maxStages is the most stages PlanDecimation splits a rate change into.
+const maxStages = 4
+
+// Stage is one stage of a multistage rate change.
+type Stage struct {
+	// Factor is the stage's decimation (or interpolation) factor.
+	Factor int
+
+	// HalfBand is whether the stage uses a half-band filter. Only stages by
+	// two other than the last do.
+	HalfBand bool
+
+	// Passband and Stopband are the edges of the stage's filter, as fractions
+	// of its high sample rate.
+	Passband, Stopband float64
+
+	// Taps is the length of the stage's filter.
+	Taps int
+
+	// Cost is the number of multiplies the stage makes per sample at the
+	// high rate of the whole chain.
+	Cost float64
+
+	kernel []float64
+}
+
+// Plan is a rate change split into stages, highest rate first.
+type Plan struct {
+	Stages []Stage
+
+	// Cost is the total number of multiplies per sample at the high rate.
+	Cost float64
+}
+
+// PlanDecimation finds the split of a decimation by factor into up to four
+// stages that costs the fewest multiplies, for a chain that passes passband,
+// a fraction of the output Nyquist frequency, and attenuates aliases by
+// attenuation decibels.
+//
+// Each stage but the last lets aliases fall into the final transition band,
+// from passband to the output Nyquist frequency. That makes stages by two
+// half-band filters. The last stage attenuates everything above the output
+// Nyquist frequency.
+//
+// Reference: R. E. Crochiere and L. R. Rabiner, "Optimum FIR Digital Filter
+// Implementations for Decimation, Interpolation, and Narrow-Band Filtering",
+// IEEE Transactions on Acoustics, Speech, and Signal Processing, 1975.
+func PlanDecimation(factor int, passband, attenuation float64) (Plan, error) {
+	if factor < 2 {
+		return Plan{}, errors.New("factor must be at least 2")
+	}
+	if passband <= 0 || passband >= 1 {
+		return Plan{}, errors.New("passband must be between 0 and 1")
+	}
+	if attenuation <= 0 {
+		return Plan{}, errors.New("attenuation must be positive")
+	}
+
+	var (
+		best  Plan
+		found bool
+	)
+	for _, factors := range factorizations(factor, maxStages) {
+		p, err := plan(factors, passband, attenuation)
+		if err != nil {
+			return Plan{}, err
+		}
+		if !found || p.Cost < best.Cost {
+			best, found = p, true
+		}
+	}
+	return best, nil
+}
+
+// plan estimates the cost of decimating by the product of factors in stages,
+// with stage filters sized by Kaiser's formula.
+func plan(factors []int, passband, attenuation float64) (Plan, error) {
+	var (
+		total = 1
+		p     Plan
+	)
+	for _, f := range factors {
+		total *= f
+	}
+	var (
+		// Edges as fractions of the input rate.
+		nyquist = 0.5 / float64(total)
+		pass    = passband * nyquist
+		rate    = 1.0
+	)
+	for i, f := range factors {
+		var (
+			last = i == len(factors)-1
+			out  = rate / float64(f)
+			s    = Stage{
+				Factor:   f,
+				HalfBand: f == 2 && !last,
+				Passband: pass / rate,
+				Stopband: (out - pass) / rate,
+			}
+		)
+		if last {
+			s.Stopband = nyquist / rate
+		}
+
+		numTaps, _, err := filter.KaiserSpec{
+			Type:            filter.LowPass,
+			SampleRate:      1, // frequencies are fractions of the rate
+			Cutoffs:         []float64{(s.Passband + s.Stopband) / 2},
+			TransitionWidth: s.Stopband - s.Passband,
+			Attenuation:     attenuation,
+		}.Parameters()
+		if err != nil {
+			return Plan{}, err
+		}
+		if s.HalfBand {
+			s.Taps = 4*(numTaps/4) + 3
+			s.Cost = float64(s.Taps+5) / 4 * out
+		} else {
+			s.Taps = numTaps | 1
+			s.Cost = float64(s.Taps) * out
+		}
+		p.Stages = append(p.Stages, s)
+		p.Cost += s.Cost
+		rate = out
+	}
+	return p, nil
+}
+
+// factorizations returns every ordered way of writing n as a product of at
+// most maxLen factors greater than one.
+func factorizations(n, maxLen int) [][]int {
+	if n == 1 {
+		return [][]int{nil}
+	}
+	if maxLen == 0 {
+		return nil
+	}
+	var all [][]int
+	for f := 2; f <= n; f++ {
+		if n%f != 0 {
+			continue
+		}
+		for _, rest := range factorizations(n/f, maxLen-1) {
+			all = append(all, append([]int{f}, rest...))
+		}
+	}
+	return all
+}
+
+// design returns the stage's filter, at its high rate with unity gain at DC.
+func (s *Stage) design(attenuation float64) ([]float64, error) {
+	if s.HalfBand {
+		return DesignHalfBand(s.Stopband-s.Passband, attenuation)
+	}
+	spec := filter.KaiserSpec{
+		Type:            filter.LowPass,
+		SampleRate:      1, // frequencies are fractions of the rate
+		Cutoffs:         []float64{(s.Passband + s.Stopband) / 2},
+		TransitionWidth: s.Stopband - s.Passband,
+		Attenuation:     attenuation,
+	}
+	_, beta, err := spec.Parameters()
+	if err != nil {
+		return nil, err
+	}
+	h := make([]float64, s.Taps)
+	filter.MakeLowPass(h, window.Kaiser(beta), spec.Cutoffs[0])
+	return h, nil
+}
+
+// Decimator returns a Chain that decimates by the plan's stages in order.
+// attenuation is the stop band attenuation the plan was made for.
+func (p Plan) Decimator(attenuation float64, opts ...Option) (*Chain, error) {
+	return p.chain(attenuation, false, opts)
+}
+
+// Interpolator returns a Chain that interpolates by the plan's stages in
+// reverse order: the mirror image of Decimator.
+func (p Plan) Interpolator(attenuation float64, opts ...Option) (*Chain, error) {
+	return p.chain(attenuation, true, opts)
+}
+
+func (p Plan) chain(attenuation float64, interpolate bool, opts []Option) (*Chain, error) {
+	if len(p.Stages) == 0 {
+		return nil, errors.New("plan has no stages")
+	}
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Chain{
+		buffers:     make([][]float64, len(p.Stages)-1),
+		numChannels: cfg.numChannels,
+	}
+	for i := range p.Stages {
+		s := p.Stages[i]
+		if interpolate {
+			s = p.Stages[len(p.Stages)-1-i]
+		}
+		h, err := s.design(attenuation)
+		if err != nil {
+			return nil, err
+		}
+
+		var r Resampler
+		switch {
+		case s.HalfBand && interpolate:
+			r = newHalfBandInterpolator(newHalfBandTaps(h, cfg))
+		case s.HalfBand:
+			r = newHalfBandDecimator(newHalfBandTaps(h, cfg))
+		case interpolate:
+			r = newRational(s.Factor, 1, h, cfg)
+		default:
+			r = newRational(1, s.Factor, h, cfg)
+		}
+		c.stages = append(c.stages, r)
+	}
+
+	// Each stage delays by a whole number of its own output samples. When
+	// interpolating, that's a whole number of the chain's output samples too.
+	// When decimating, delaying the input by a few samples makes it so.
+	if interpolate {
+		for i, r := range c.stages {
+			l := r.Latency()
+			for _, s := range p.Stages[:len(p.Stages)-1-i] {
+				l *= s.Factor
+			}
+			c.latency += l
+		}
+		return c, nil
+	}
+	delay, factor := 0, 1
+	for i, r := range c.stages {
+		factor *= p.Stages[i].Factor
+		delay += r.Latency() * factor
+	}
+	pad := (factor - delay%factor) % factor
+	c.latency = (delay + pad) / factor
+	c.delay = make([]float64, pad*cfg.numChannels)
+	return c, nil
+}
+
+// Chain runs a signal through the stages of a Plan.
+type Chain struct {
+	stages      []Resampler
+	buffers     [][]float64
+	numChannels int
+	latency     int
+
+	// delay holds the samples of input held back to line the output up with
+	// the input; input holds the delayed input.
+	delay    []float64
+	delayPos int
+	input    []float64
+}
+
+// MaxOutput returns the most samples per channel that Process produces from
+// numSamples samples.
+func (c *Chain) MaxOutput(numSamples int) int {
+	for _, s := range c.stages {
+		numSamples = s.MaxOutput(numSamples)
+	}
+	return numSamples
+}
+
+// Latency returns the number of samples, at the output rate, the output is
+// delayed by.
+func (c *Chain) Latency() int {
+	return c.latency
+}
+
+// Process resamples numSamples samples of each channel of in through each
+// stage in turn into out, returning the number of samples written to each
+// channel. out must hold MaxOutput(numSamples) samples per channel.
+func (c *Chain) Process(out, in []float64, numSamples int) (int, error) {
+	if err := validateBuffers(out, in, numSamples, c.MaxOutput(numSamples), c.numChannels); err != nil {
+		return 0, err
+	}
+	if len(c.delay) > 0 {
+		in = c.delayInput(in, numSamples)
+	}
+
+	n := numSamples
+	for i, s := range c.stages {
+		dest := out
+		if i < len(c.stages)-1 {
+			size := s.MaxOutput(n) * c.numChannels
+			if len(c.buffers[i]) < size {
+				c.buffers[i] = make([]float64, size)
+			}
+			dest = c.buffers[i]
+		}
+		var err error
+		if n, err = s.Process(dest, in, n); err != nil {
+			return 0, err
+		}
+		in = dest
+	}
+	return n, nil
+}
+
+// delayInput returns numSamples samples of each channel of in, delayed.
+func (c *Chain) delayInput(in []float64, numSamples int) []float64 {
+	size := numSamples * c.numChannels
+	if len(c.input) < size {
+		c.input = make([]float64, size)
+	}
+	for i, v := range in[:size] {
+		c.input[i] = c.delay[c.delayPos]
+		c.delay[c.delayPos] = v
+		c.delayPos++
+		if c.delayPos == len(c.delay) {
+			c.delayPos = 0
+		}
+	}
+	return c.input
+}
+
+// Reset clears the state of every stage.
+func (c *Chain) Reset() {
+	for _, s := range c.stages {
+		s.Reset()
+	}
+	for i := range c.delay {
+		c.delay[i] = 0
+	}
+	c.delayPos = 0
+}
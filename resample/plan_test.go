@@ -0,0 +1,99 @@
+package resample
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFactorizations(t *testing.T) {
+	require.Equal(t, [][]int{{2, 2, 2}, {2, 4}, {4, 2}, {8}}, factorizations(8, 4))
+	require.Equal(t, [][]int{{2, 4}, {4, 2}, {8}}, factorizations(8, 2))
+	require.Equal(t, [][]int{{7}}, factorizations(7, 3))
+}
+
+func TestPlanDecimation(t *testing.T) {
+	p, err := PlanDecimation(64, 0.8, 90)
+	require.NoError(t, err)
+
+	product := 1
+	for _, s := range p.Stages {
+		product *= s.Factor
+	}
+	require.Equal(t, 64, product)
+	require.True(t, len(p.Stages) > 1)
+
+	// Splitting the decimation costs far less than doing it in one stage.
+	single, err := plan([]int{64}, 0.8, 90)
+	require.NoError(t, err)
+	require.True(t, p.Cost < single.Cost/5, "%g vs %g", p.Cost, single.Cost)
+
+	// The plan beats every other split.
+	for _, factors := range factorizations(64, maxStages) {
+		other, err := plan(factors, 0.8, 90)
+		require.NoError(t, err)
+		require.True(t, p.Cost <= other.Cost)
+	}
+
+	_, err = PlanDecimation(1, 0.8, 90)
+	require.Error(t, err)
+	_, err = PlanDecimation(8, 1, 90)
+	require.Error(t, err)
+	_, err = PlanDecimation(8, 0.8, 0)
+	require.Error(t, err)
+}
+
+func TestPlanChains(t *testing.T) {
+	const (
+		factor      = 24
+		attenuation = 80
+		numSamples  = 48000
+	)
+	p, err := PlanDecimation(factor, 0.8, attenuation)
+	require.NoError(t, err)
+
+	// A tone in the pass band comes out of the decimator intact, delayed by
+	// its latency.
+	d, err := p.Decimator(attenuation)
+	require.NoError(t, err)
+	var (
+		freq = 0.3 / factor
+		in   = sine(freq, 1, numSamples)
+		out  = streaming(t, d, in, 1)
+	)
+	require.Equal(t, numSamples/factor, len(out))
+	var maxErr float64
+	for m := 200; m < len(out)-200; m++ {
+		expect := math.Sin(2 * math.Pi * freq * float64((m-d.Latency())*factor))
+		maxErr = math.Max(maxErr, math.Abs(out[m]-expect))
+	}
+	require.True(t, maxErr < 2e-3, "error %g", maxErr)
+
+	// A tone above the output's Nyquist frequency is attenuated.
+	d.Reset()
+	out = streaming(t, d, sine(0.7/factor, 1, numSamples), 1)
+	level := 20 * math.Log10(rms(out, 200)*math.Sqrt2)
+	require.True(t, level < -attenuation+1, "alias at %g dB", level)
+
+	// The interpolator brings the decimated tone back up.
+	u, err := p.Interpolator(attenuation, Channels(2))
+	require.NoError(t, err)
+	low := sine(0.3, 1, 2000)
+	stereo := make([]float64, 2*len(low))
+	for i, v := range low {
+		stereo[2*i], stereo[2*i+1] = v, -v
+	}
+	up := streaming(t, u, stereo, 2)
+	require.Equal(t, 2*len(low)*factor, len(up))
+	maxErr = 0
+	for n := 200 * factor; n < len(up)/2-200*factor; n++ {
+		expect := math.Sin(2 * math.Pi * 0.3 * float64(n-u.Latency()) / factor)
+		maxErr = math.Max(maxErr, math.Abs(up[2*n]-expect))
+		require.Equal(t, up[2*n], -up[2*n+1])
+	}
+	require.True(t, maxErr < 2e-3, "error %g", maxErr)
+
+	_, err = Plan{}.Decimator(attenuation)
+	require.Error(t, err)
+}
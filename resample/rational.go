@@ -0,0 +1,158 @@
+package resample
+
+import "errors"
+
+// Rational resamples by a ratio of integers, up/down, with a polyphase filter:
+// it computes only the samples of the up-sampled and filtered signal that
+// survive decimation, each from one phase of the filter's kernel.
+type Rational struct {
+	up, down    int
+	numChannels int
+
+	// phases holds the kernel split into up phases, each reversed so that it
+	// lines up with the history oldest sample first.
+	phases [][]float64
+
+	// history holds the most recent input of each channel twice over, so
+	// that the last len(phases[0]) samples are contiguous wherever pos is.
+	history [][]float64
+	pos     int
+
+	phase, startPhase int
+	latency           int
+}
+
+// NewRational returns a resampler that produces up samples for every down
+// samples of input. The ratio is reduced to lowest terms.
+func NewRational(up, down int, q Quality, opts ...Option) (*Rational, error) {
+	if up < 1 || down < 1 {
+		return nil, errors.New("resampling factors must be positive")
+	}
+	d, err := q.design()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	g := gcd(up, down)
+	up, down = up/g, down/g
+	scale := up
+	if down > up {
+		scale = down
+	}
+	h, err := d.prototype(scale)
+	if err != nil {
+		return nil, err
+	}
+	return newRational(up, down, h, cfg), nil
+}
+
+// newRational returns a resampler by up/down that filters with the odd-length
+// kernel h, designed at up times the input rate with unity gain at DC.
+func newRational(up, down int, h []float64, cfg config) *Rational {
+	numTaps := (len(h) + up - 1) / up
+	r := &Rational{
+		up:          up,
+		down:        down,
+		numChannels: cfg.numChannels,
+		phases:      make([][]float64, up),
+		history:     make([][]float64, cfg.numChannels),
+	}
+	for p := range r.phases {
+		taps := make([]float64, numTaps)
+		for j := 0; j < numTaps; j++ {
+			if k := p + j*up; k < len(h) {
+				// The kernel has unity gain at DC; each phase needs that gain
+				// to make up for the zeros the up-sampling inserts.
+				taps[numTaps-1-j] = h[k] * float64(up)
+			}
+		}
+		r.phases[p] = taps
+	}
+	for ch := range r.history {
+		r.history[ch] = make([]float64, 2*numTaps)
+	}
+
+	// The kernel delays the up-sampled signal by half its length. Starting
+	// part way into the first input sample leaves a whole number of output
+	// samples of delay.
+	delay := (len(h) - 1) / 2
+	r.startPhase = delay % down
+	r.latency = delay / down
+	r.phase = r.startPhase
+	return r
+}
+
+// Ratio returns the reduced interpolation and decimation factors.
+func (r *Rational) Ratio() (up, down int) {
+	return r.up, r.down
+}
+
+// MaxOutput returns the most samples per channel that Process produces from
+// numSamples samples.
+func (r *Rational) MaxOutput(numSamples int) int {
+	return (numSamples*r.up + r.down - 1) / r.down
+}
+
+// Latency returns the number of samples, at the output rate, the output is
+// delayed by.
+func (r *Rational) Latency() int {
+	return r.latency
+}
+
+// Process resamples numSamples samples of each channel of in into out,
+// returning the number of samples written to each channel. out must hold
+// MaxOutput(numSamples) samples per channel.
+func (r *Rational) Process(out, in []float64, numSamples int) (int, error) {
+	if err := validateBuffers(out, in, numSamples, r.MaxOutput(numSamples), r.numChannels); err != nil {
+		return 0, err
+	}
+
+	var (
+		nc      = r.numChannels
+		numTaps = len(r.phases[0])
+		n       = 0
+	)
+	for i := 0; i < numSamples; i++ {
+		r.pos++
+		if r.pos == numTaps {
+			r.pos = 0
+		}
+		for ch, hist := range r.history {
+			v := in[i*nc+ch]
+			hist[r.pos] = v
+			hist[r.pos+numTaps] = v
+		}
+
+		for ; r.phase < r.up; r.phase += r.down {
+			taps := r.phases[r.phase]
+			for ch, hist := range r.history {
+				var (
+					window = hist[r.pos+1 : r.pos+1+numTaps]
+					sum    float64
+				)
+				for j, t := range taps {
+					sum += t * window[j]
+				}
+				out[n*nc+ch] = sum
+			}
+			n++
+		}
+		r.phase -= r.up
+	}
+	return n, nil
+}
+
+// Reset clears the input history.
+func (r *Rational) Reset() {
+	for _, hist := range r.history {
+		for i := range hist {
+			hist[i] = 0
+		}
+	}
+	r.pos = 0
+	r.phase = r.startPhase
+}
@@ -0,0 +1,73 @@
+package resample
+
+import (
+	"math"
+	"testing"
+
+	"github.com/brettbuddin/fourier/filter"
+	"github.com/stretchr/testify/require"
+)
+
+// kernel reassembles the polyphase kernel of r.
+func kernel(r *Rational) []float64 {
+	numTaps := len(r.phases[0])
+	h := make([]float64, numTaps*r.up)
+	for p, taps := range r.phases {
+		for j := range taps {
+			h[p+j*r.up] = taps[numTaps-1-j] / float64(r.up)
+		}
+	}
+	return h
+}
+
+func TestRationalKernel(t *testing.T) {
+	for _, q := range []Quality{Low, Medium, High} {
+		for _, ratio := range [][2]int{{160, 147}, {147, 160}, {2, 1}, {1, 3}} {
+			r, err := NewRational(ratio[0], ratio[1], q)
+			require.NoError(t, err)
+			d, err := q.design()
+			require.NoError(t, err)
+
+			var (
+				scale   = float64(ratio[0])
+				h       = kernel(r)
+				delta   = math.Pow(10, -d.attenuation/20)
+				ripple  = 20 * math.Log10((1+delta)/(1-delta))
+				nyquist = 0.5 / math.Max(float64(ratio[0]), float64(ratio[1]))
+			)
+			resp, err := filter.NewResponse(h, nil, 1<<16)
+			require.NoError(t, err)
+
+			// Within the passband the gain is flat, and beyond the lower
+			// Nyquist frequency aliases are attenuated, to within a
+			// decibel of the Kaiser estimates.
+			require.True(t, resp.Ripple(0, nyquist*d.passband) < ripple+1e-3, "%v %v: ripple", q, ratio)
+			require.True(t, resp.Attenuation(nyquist, 0.5) > d.attenuation-1, "%v %v: attenuation %g", q, ratio, resp.Attenuation(nyquist, 0.5))
+			require.InDelta(t, 1, resp.Magnitude()[0], 1e-9*scale)
+		}
+	}
+}
+
+func TestRationalLatency(t *testing.T) {
+	r, err := NewRational(3, 2, Medium)
+	require.NoError(t, err)
+
+	// An impulse comes out Latency samples late, centered on a whole sample.
+	in := make([]float64, 200)
+	in[0] = 1
+	out := make([]float64, r.MaxOutput(len(in)))
+	n, err := r.Process(out, in, len(in))
+	require.NoError(t, err)
+	require.Equal(t, 300, n)
+
+	peak := 0
+	for i, v := range out[:n] {
+		if v > out[peak] {
+			peak = i
+		}
+	}
+	require.Equal(t, r.Latency(), peak)
+	for k := 1; k < 20; k++ {
+		require.InDelta(t, out[peak-k], out[peak+k], 1e-12)
+	}
+}
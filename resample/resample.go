@@ -0,0 +1,243 @@
+// Package resample converts signals from one sample rate to another.
+//
+// Rational converts between rates whose ratio is a fraction L/M of small
+// integers with a polyphase filter, and Arbitrary converts by any ratio with
+// windowed-sinc interpolation. Both band-limit the signal to the lower of the
+// two rates with a Kaiser-windowed low-pass filter whose steepness and
+// attenuation are chosen by a Quality.
+package resample
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/brettbuddin/fourier/filter"
+	"github.com/brettbuddin/fourier/window"
+)
+
+// Quality is a trade-off between the accuracy and the cost of resampling.
+type Quality int
+
+// Qualities, from cheapest to most accurate.
+const (
+	// Low passes 80% of the lower Nyquist frequency and attenuates aliases by
+	// at least 60 dB.
+	Low Quality = iota
+	// Medium passes 90% of the lower Nyquist frequency and attenuates aliases
+	// by at least 90 dB.
+	Medium
+	// High passes 95% of the lower Nyquist frequency and attenuates aliases by
+	// at least 120 dB.
+	High
+)
+
+func (q Quality) String() string {
+	switch q {
+	case Low:
+		return "low"
+	case Medium:
+		return "medium"
+	case High:
+		return "high"
+	default:
+		return fmt.Sprintf("Quality(%d)", int(q))
+	}
+}
+
+// design describes the low-pass filter used at a Quality.
+type design struct {
+	// passband is the fraction of the lower Nyquist frequency left flat. The
+	// transition band runs from there to the Nyquist frequency.
+	passband float64
+
+	// attenuation is the least attenuation in the stop band, in decibels.
+	attenuation float64
+
+	// phases is the number of points per sample at which Arbitrary tabulates
+	// its kernel.
+	phases int
+}
+
+func (q Quality) design() (design, error) {
+	switch q {
+	case Low:
+		return design{passband: 0.8, attenuation: 60, phases: 128}, nil
+	case Medium:
+		return design{passband: 0.9, attenuation: 90, phases: 512}, nil
+	case High:
+		return design{passband: 0.95, attenuation: 120, phases: 2048}, nil
+	default:
+		return design{}, fmt.Errorf("unknown quality %v", q)
+	}
+}
+
+// prototype returns the odd-length low-pass kernel for a sample rate scale
+// times the lower of the two rates, with unity gain at DC.
+func (d design) prototype(scale int) ([]float64, error) {
+	var (
+		nyquist = 0.5 / float64(scale)
+		spec    = filter.KaiserSpec{
+			Type:            filter.LowPass,
+			SampleRate:      1, // frequencies are fractions of the rate
+			Cutoffs:         []float64{nyquist * (1 + d.passband) / 2},
+			TransitionWidth: nyquist * (1 - d.passband),
+			Attenuation:     d.attenuation,
+		}
+	)
+	numTaps, beta, err := spec.Parameters()
+	if err != nil {
+		return nil, err
+	}
+	if numTaps%2 == 0 {
+		numTaps++
+	}
+	h := make([]float64, numTaps)
+	filter.MakeLowPass(h, window.Kaiser(beta), spec.Cutoffs[0])
+	return h, nil
+}
+
+// maxFactor is the largest interpolation or decimation factor New uses a
+// Rational resampler for.
+const maxFactor = 1024
+
+// Resampler converts a stream of interleaved samples from one sample rate to
+// another.
+type Resampler interface {
+	// Process resamples numSamples samples of each channel of in into out,
+	// returning the number of samples written to each channel. out must hold
+	// MaxOutput(numSamples) samples per channel.
+	Process(out, in []float64, numSamples int) (int, error)
+
+	// MaxOutput returns the most samples per channel that Process produces
+	// from numSamples samples.
+	MaxOutput(numSamples int) int
+
+	// Latency returns the number of samples, at the output rate, the output
+	// is delayed by.
+	Latency() int
+
+	// Reset clears the input history.
+	Reset()
+}
+
+// New returns a Resampler from inRate to outRate: a Rational when the rates
+// are whole numbers with a ratio of small enough integers, and an Arbitrary
+// otherwise.
+func New(inRate, outRate float64, q Quality, opts ...Option) (Resampler, error) {
+	if inRate <= 0 || outRate <= 0 {
+		return nil, errors.New("sample rates must be positive")
+	}
+	if inRate == math.Trunc(inRate) && outRate == math.Trunc(outRate) {
+		var (
+			in, out = int(inRate), int(outRate)
+			d       = gcd(in, out)
+		)
+		if out/d <= maxFactor && in/d <= maxFactor {
+			return NewRational(out/d, in/d, q, opts...)
+		}
+	}
+	return NewArbitrary(outRate/inRate, q, opts...)
+}
+
+// Resample converts a whole signal of interleaved samples from inRate to
+// outRate, compensating for the latency of the Resampler so that the output
+// lines up with the input. The output is ceil(len*outRate/inRate) samples per
+// channel long.
+func Resample(in []float64, inRate, outRate float64, q Quality, opts ...Option) ([]float64, error) {
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	r, err := New(inRate, outRate, q, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	const blockSize = 1024
+	var (
+		nc        = cfg.numChannels
+		numFrames = len(in) / nc
+		want      = int(math.Ceil(float64(numFrames) * outRate / inRate))
+		skip      = r.Latency()
+		out       = make([]float64, want*nc)
+		buf       = make([]float64, r.MaxOutput(blockSize)*nc)
+		silence   = make([]float64, blockSize*nc)
+		written   = 0
+	)
+	for pos := 0; written < want; pos += blockSize {
+		block := silence
+		if pos < numFrames {
+			block = in[pos*nc : min(pos+blockSize, numFrames)*nc]
+		}
+		n, err := r.Process(buf, block, len(block)/nc)
+		if err != nil {
+			return nil, err
+		}
+		drop := min(skip, n)
+		skip -= drop
+		m := min(n-drop, want-written)
+		copy(out[written*nc:], buf[drop*nc:(drop+m)*nc])
+		written += m
+	}
+	return out, nil
+}
+
+// config holds the options shared by the resamplers.
+type config struct {
+	numChannels int
+}
+
+// Option is a configuration option for a resampler.
+type Option func(*config) error
+
+// Channels configures a resampler to convert every channel of buffers that
+// contain numChannels interleaved channels. The default is 1.
+func Channels(numChannels int) Option {
+	return func(c *config) error {
+		if numChannels < 1 {
+			return errors.New("number of channels cannot be less than 1")
+		}
+		c.numChannels = numChannels
+		return nil
+	}
+}
+
+func newConfig(opts []Option) (config, error) {
+	c := config{numChannels: 1}
+	for _, opt := range opts {
+		if err := opt(&c); err != nil {
+			return config{}, err
+		}
+	}
+	return c, nil
+}
+
+// validateBuffers checks that in holds numSamples samples per channel and out
+// holds maxOutput.
+func validateBuffers(out, in []float64, numSamples, maxOutput, numChannels int) error {
+	if numSamples < 0 {
+		return errors.New("number of samples cannot be negative")
+	}
+	if len(in) < numSamples*numChannels {
+		return fmt.Errorf("input buffer must hold %d samples", numSamples*numChannels)
+	}
+	if len(out) < maxOutput*numChannels {
+		return fmt.Errorf("output buffer must hold %d samples", maxOutput*numChannels)
+	}
+	return nil
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
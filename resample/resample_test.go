@@ -0,0 +1,212 @@
+package resample
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func sine(freq, sampleRate float64, numSamples int) []float64 {
+	s := make([]float64, numSamples)
+	for i := range s {
+		s[i] = math.Sin(2 * math.Pi * freq * float64(i) / sampleRate)
+	}
+	return s
+}
+
+// maxError returns the largest difference between out and a sine at freq
+// sampled at sampleRate, away from the edges of the signal.
+func maxError(out []float64, freq, sampleRate float64, edge int) float64 {
+	var max float64
+	for i := edge; i < len(out)-edge; i++ {
+		expect := math.Sin(2 * math.Pi * freq * float64(i) / sampleRate)
+		max = math.Max(max, math.Abs(out[i]-expect))
+	}
+	return max
+}
+
+// rms returns the RMS level of x away from its edges.
+func rms(x []float64, edge int) float64 {
+	var sum float64
+	for _, v := range x[edge : len(x)-edge] {
+		sum += v * v
+	}
+	return math.Sqrt(sum / float64(len(x)-2*edge))
+}
+
+func TestResample(t *testing.T) {
+	for _, v := range []struct {
+		inRate, outRate float64
+		quality         Quality
+		tolerance       float64
+	}{
+		{inRate: 44100, outRate: 48000, quality: Low, tolerance: 2e-3},
+		{inRate: 44100, outRate: 48000, quality: Medium, tolerance: 1e-4},
+		{inRate: 44100, outRate: 48000, quality: High, tolerance: 1e-5},
+		{inRate: 44100, outRate: 96000, quality: Medium, tolerance: 1e-4},
+		{inRate: 96000, outRate: 44100, quality: Medium, tolerance: 1e-4},
+		{inRate: 48000, outRate: 16000, quality: Medium, tolerance: 1e-4},
+		{inRate: 44100, outRate: 47999.5, quality: Low, tolerance: 2e-3},
+		{inRate: 44100, outRate: 47999.5, quality: Medium, tolerance: 1e-4},
+		{inRate: 48000, outRate: 22049.9, quality: Medium, tolerance: 1e-4},
+	} {
+		const freq = 997
+		in := sine(freq, v.inRate, 8000)
+		out, err := Resample(in, v.inRate, v.outRate, v.quality)
+		require.NoError(t, err)
+		require.Equal(t, int(math.Ceil(8000*v.outRate/v.inRate)), len(out))
+		require.True(t, maxError(out, freq, v.outRate, 1000) < v.tolerance,
+			"%g -> %g at %v: error %g", v.inRate, v.outRate, v.quality, maxError(out, freq, v.outRate, 1000))
+	}
+}
+
+func TestResampleAliasing(t *testing.T) {
+	for _, v := range []struct {
+		inRate, outRate float64
+		quality         Quality
+	}{
+		{inRate: 48000, outRate: 32000, quality: Low},
+		{inRate: 48000, outRate: 32000, quality: Medium},
+		{inRate: 48000, outRate: 32000, quality: High},
+		{inRate: 48000, outRate: 31999.3, quality: Low},
+		{inRate: 48000, outRate: 31999.3, quality: Medium},
+	} {
+		d, err := v.quality.design()
+		require.NoError(t, err)
+
+		// A tone above the output's Nyquist frequency would alias to 12 kHz.
+		in := sine(20000, v.inRate, 16000)
+		out, err := Resample(in, v.inRate, v.outRate, v.quality)
+		require.NoError(t, err)
+		level := 20 * math.Log10(rms(out, 1000)*math.Sqrt2)
+		require.True(t, level < -d.attenuation, "%g -> %g at %v: alias at %g dB", v.inRate, v.outRate, v.quality, level)
+	}
+}
+
+func TestResampleChannels(t *testing.T) {
+	const numSamples = 3000
+	var (
+		left   = sine(440, 44100, numSamples)
+		right  = sine(3000, 44100, numSamples)
+		stereo = make([]float64, 2*numSamples)
+	)
+	for i := range left {
+		stereo[2*i], stereo[2*i+1] = left[i], right[i]
+	}
+
+	out, err := Resample(stereo, 44100, 48000, Medium, Channels(2))
+	require.NoError(t, err)
+	l, err := Resample(left, 44100, 48000, Medium)
+	require.NoError(t, err)
+	r, err := Resample(right, 44100, 48000, Medium)
+	require.NoError(t, err)
+	require.Equal(t, 2*len(l), len(out))
+	for i := range l {
+		require.Equal(t, l[i], out[2*i])
+		require.Equal(t, r[i], out[2*i+1])
+	}
+}
+
+// streaming feeds in through r in irregular chunks.
+func streaming(t *testing.T, r Resampler, in []float64, numChannels int) []float64 {
+	var (
+		rng = rand.New(rand.NewSource(1))
+		out []float64
+	)
+	numSamples := len(in) / numChannels
+	for pos := 0; pos < numSamples; {
+		n := rng.Intn(200)
+		if pos+n > numSamples {
+			n = numSamples - pos
+		}
+		buf := make([]float64, r.MaxOutput(n)*numChannels)
+		m, err := r.Process(buf, in[pos*numChannels:], n)
+		require.NoError(t, err)
+		out = append(out, buf[:m*numChannels]...)
+		pos += n
+	}
+	return out
+}
+
+func TestStreaming(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	in := make([]float64, 2*5000)
+	for i := range in {
+		in[i] = rng.Float64()*2 - 1
+	}
+
+	for _, rates := range [][2]float64{{44100, 48000}, {48000, 44100}, {44100, 47999.5}, {48000, 17000.25}} {
+		a, err := New(rates[0], rates[1], Medium, Channels(2))
+		require.NoError(t, err)
+		whole := make([]float64, a.MaxOutput(5000)*2)
+		n, err := a.Process(whole, in, 5000)
+		require.NoError(t, err)
+
+		b, err := New(rates[0], rates[1], Medium, Channels(2))
+		require.NoError(t, err)
+		chunked := streaming(t, b, in, 2)
+		require.Equal(t, 2*n, len(chunked))
+		for i := range chunked {
+			require.InDelta(t, whole[i], chunked[i], 1e-9)
+		}
+
+		// After a reset, the same input produces the same output.
+		a.Reset()
+		again := make([]float64, len(whole))
+		m, err := a.Process(again, in, 5000)
+		require.NoError(t, err)
+		require.Equal(t, n, m)
+		require.Equal(t, whole[:2*n], again[:2*m])
+	}
+}
+
+func TestNew(t *testing.T) {
+	r, err := New(44100, 48000, Medium)
+	require.NoError(t, err)
+	rational, ok := r.(*Rational)
+	require.True(t, ok)
+	up, down := rational.Ratio()
+	require.Equal(t, 160, up)
+	require.Equal(t, 147, down)
+
+	r, err = New(44100, 48000.5, Medium)
+	require.NoError(t, err)
+	arbitrary, ok := r.(*Arbitrary)
+	require.True(t, ok)
+	require.InEpsilon(t, 48000.5/44100, arbitrary.Ratio(), 1e-12)
+
+	r, err = New(44099, 48000, Medium)
+	require.NoError(t, err)
+	_, ok = r.(*Arbitrary)
+	require.True(t, ok)
+}
+
+func TestErrors(t *testing.T) {
+	_, err := New(0, 48000, Medium)
+	require.Error(t, err)
+	_, err = New(44100, -1, Medium)
+	require.Error(t, err)
+	_, err = New(44100, 48000, Quality(7))
+	require.Error(t, err)
+	_, err = New(44100, 48000, Medium, Channels(0))
+	require.Error(t, err)
+	_, err = NewRational(0, 1, Medium)
+	require.Error(t, err)
+	_, err = NewArbitrary(math.NaN(), Medium)
+	require.Error(t, err)
+	_, err = Resample(nil, 44100, 48000, Quality(-1))
+	require.Error(t, err)
+
+	for _, rates := range [][2]float64{{44100, 48000}, {44100, 48000.5}} {
+		r, err := New(rates[0], rates[1], Low)
+		require.NoError(t, err)
+		_, err = r.Process(make([]float64, r.MaxOutput(100)), make([]float64, 99), 100)
+		require.Error(t, err)
+		_, err = r.Process(make([]float64, r.MaxOutput(100)-1), make([]float64, 100), 100)
+		require.Error(t, err)
+		_, err = r.Process(nil, nil, -1)
+		require.Error(t, err)
+	}
+}
@@ -0,0 +1,164 @@
+package fourier
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvolver_SwapImpulseResponse(t *testing.T) {
+	// The new response is shorter than the old one, so all of the input
+	// history it needs is carried over and the output continues exactly.
+	var (
+		blockSize = 64
+		irA       = make([]float64, 3000)
+		irB       = make([]float64, 2000)
+		input     = make([]float64, 12000)
+		swapAt    = 4133
+	)
+	for i := range irA {
+		irA[i] = math.Sin(float64(i)*0.3) * math.Exp(-float64(i)/500)
+	}
+	for i := range irB {
+		irB[i] = math.Cos(float64(i)*0.1) * math.Exp(-float64(i)/1000)
+	}
+	for i := range input {
+		input[i] = math.Cos(float64(i) * 0.05)
+	}
+
+	var (
+		expectedA = directConvolve(input, irA)
+		expectedB = directConvolve(input, irB)
+	)
+
+	for _, tc := range []struct {
+		name string
+		opts []ConvolverOption
+
+		// settle is the number of samples after the swap before the output
+		// matches the new impulse response exactly.
+		settle int
+	}{
+		{"overlap-add", nil, 4 * blockSize},
+		{"overlap-save", []ConvolverOption{WithMethod(OverlapSave)}, 0},
+		{"non-uniform", []ConvolverOption{NonUniform(true)}, 0},
+		{"non-uniform-background", []ConvolverOption{NonUniform(true), BackgroundTail()}, 0},
+	} {
+		for _, fadeLength := range []int{0, 300} {
+			t.Run(fmt.Sprintf("%s/fade=%d", tc.name, fadeLength), func(t *testing.T) {
+				conv, err := NewConvolver(blockSize, irA, tc.opts...)
+				require.NoError(t, err)
+				defer conv.Close()
+
+				r, err := conv.PrepareImpulseResponse(irB)
+				require.NoError(t, err)
+
+				output := make([]float64, len(input))
+				for i, n := 0, 1; i < len(input); i, n = i+n, n%37+5 {
+					var (
+						begin = i
+						end   = min(i+n, len(input))
+					)
+					if begin <= swapAt && swapAt < end {
+						// Split the chunk so the swap lands exactly.
+						require.NoError(t, conv.Convolve(output[begin:swapAt], input[begin:swapAt], swapAt-begin))
+						require.NoError(t, conv.SwapImpulseResponse(r, fadeLength))
+						begin = swapAt
+					}
+					require.NoError(t, conv.Convolve(output[begin:end], input[begin:end], end-begin))
+				}
+
+				for i := 0; i < swapAt; i++ {
+					require.InDelta(t, expectedA[i], output[i], epsilon, "sample %d", i)
+				}
+				if tc.settle == 0 {
+					for i := swapAt; i < swapAt+fadeLength; i++ {
+						x := 0.5 * math.Pi * float64(i-swapAt) / float64(fadeLength)
+						expected := expectedA[i]*math.Cos(x) + expectedB[i]*math.Sin(x)
+						require.InDelta(t, expected, output[i], epsilon, "sample %d", i)
+					}
+				}
+				for i := swapAt + fadeLength + tc.settle; i < len(output); i++ {
+					require.InDelta(t, expectedB[i], output[i], epsilon, "sample %d", i)
+				}
+			})
+		}
+	}
+}
+
+func TestConvolver_SwapImpulseResponseAllocations(t *testing.T) {
+	var (
+		blockSize = 64
+		in        = make([]float64, blockSize)
+		out       = make([]float64, blockSize)
+	)
+	conv, err := NewConvolver(blockSize, make([]float64, 1000), NonUniform(true))
+	require.NoError(t, err)
+
+	r, err := conv.PrepareImpulseResponse(make([]float64, 2000))
+	require.NoError(t, err)
+	require.NoError(t, conv.SwapImpulseResponse(r, 10*blockSize))
+
+	allocs := testing.AllocsPerRun(20, func() {
+		conv.Convolve(out, in, blockSize)
+	})
+	require.Zero(t, allocs)
+}
+
+func TestConvolver_SwapImpulseResponseErrors(t *testing.T) {
+	conv, err := NewConvolver(64, []float64{1})
+	require.NoError(t, err)
+
+	_, err = conv.PrepareImpulseResponse(nil)
+	require.Error(t, err)
+
+	r, err := conv.PrepareImpulseResponse([]float64{1})
+	require.NoError(t, err)
+	require.Error(t, conv.SwapImpulseResponse(r, -1))
+	require.Error(t, conv.SwapImpulseResponse(nil, 0))
+
+	// A prepared response can only be swapped in once, even before the swap
+	// has taken effect.
+	require.NoError(t, conv.SwapImpulseResponse(r, 0))
+	require.Error(t, conv.SwapImpulseResponse(r, 10))
+	out := make([]float64, 64)
+	require.NoError(t, conv.Convolve(out, out, 64))
+	require.Error(t, conv.SwapImpulseResponse(r, 0))
+}
+
+func TestConvolver_SwapImpulseResponseDiscarded(t *testing.T) {
+	conv, err := NewConvolver(64, []float64{1})
+	require.NoError(t, err)
+
+	impulse := func() float64 {
+		var (
+			in  = make([]float64, 64)
+			out = make([]float64, 64)
+		)
+		in[0] = 1
+		require.NoError(t, conv.Convolve(out, in, 64))
+		return out[0]
+	}
+
+	// A swap that hasn't started is discarded by SetImpulseResponse...
+	r, err := conv.PrepareImpulseResponse([]float64{2})
+	require.NoError(t, err)
+	require.NoError(t, conv.SwapImpulseResponse(r, 0))
+	require.NoError(t, conv.SetImpulseResponse([]float64{3}))
+	require.Equal(t, 3.0, impulse())
+
+	// ...and by Reset.
+	r, err = conv.PrepareImpulseResponse([]float64{2})
+	require.NoError(t, err)
+	require.NoError(t, conv.SwapImpulseResponse(r, 0))
+	conv.Reset()
+	require.Equal(t, 3.0, impulse())
+
+	// A later swap still takes effect.
+	r, err = conv.PrepareImpulseResponse([]float64{4})
+	require.NoError(t, err)
+	require.NoError(t, conv.SwapImpulseResponse(r, 0))
+	require.Equal(t, 4.0, impulse())
+}
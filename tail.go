@@ -0,0 +1,92 @@
+package fourier
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+// maxTailJobs is the capacity of the queue between a Convolver and its tail
+// worker. Each stage has at most one job in flight.
+const maxTailJobs = 64
+
+// Stage job states
+const (
+	jobIdle int32 = iota
+	jobQueued
+)
+
+// tailWorker computes the late stages of a non-uniformly partitioned
+// Convolver on a background goroutine. Jobs are handed off through a
+// single-producer, single-consumer ring buffer, so the audio thread never
+// takes a lock.
+type tailWorker struct {
+	jobs       [maxTailJobs]*stage
+	head, tail uint32
+	wake       chan struct{}
+	done       chan struct{}
+	offline    bool
+}
+
+// newTailWorker starts a tail worker.
+func newTailWorker() *tailWorker {
+	w := &tailWorker{
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// run computes queued jobs until the worker is closed. A job's error is left
+// on its stage, to be returned by Convolve when the block is due.
+func (w *tailWorker) run() {
+	defer close(w.done)
+	for range w.wake {
+		for {
+			head := atomic.LoadUint32(&w.head)
+			if head == atomic.LoadUint32(&w.tail) {
+				break
+			}
+			s := w.jobs[head%maxTailJobs]
+			s.err = s.compute()
+			atomic.StoreUint32(&w.head, head+1)
+			atomic.StoreInt32(&s.state, jobIdle)
+		}
+	}
+}
+
+// submit queues a stage's pending block. It reports false if the job could
+// not be queued, in which case the caller should compute it itself.
+func (w *tailWorker) submit(s *stage) bool {
+	if w.offline {
+		return false
+	}
+	tail := atomic.LoadUint32(&w.tail)
+	if tail-atomic.LoadUint32(&w.head) == maxTailJobs {
+		return false
+	}
+	w.jobs[tail%maxTailJobs] = s
+	atomic.StoreInt32(&s.state, jobQueued)
+	atomic.StoreUint32(&w.tail, tail+1)
+
+	// Wake the worker without blocking. If a wake-up is already pending the
+	// worker will find this job when it drains the queue.
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// close stops the worker after it finishes any queued jobs.
+func (w *tailWorker) close() {
+	close(w.wake)
+	<-w.done
+}
+
+// wait blocks until the stage's job (if any) has been computed.
+func (s *stage) wait() {
+	for atomic.LoadInt32(&s.state) == jobQueued {
+		runtime.Gosched()
+	}
+}
@@ -1,19 +1,24 @@
 package fourier
 
-import "math"
+import (
+	"math"
+	"sync"
+)
 
 // table is a trigonometric "twiddle" table.
 type table struct {
 	sin, cos []float64
 }
 
-var twiddleTables = map[int]*table{}
+// twiddleTables caches tables by FFT size. It's safe for concurrent use, since
+// transforms may run on several goroutines at once (see BackgroundTail).
+var twiddleTables sync.Map
 
 // twiddleTable looks up a twiddle table for a particular FFT size. If the table
 // has already been calculated, a cached version is returned.
 func twiddleTable(size int) *table {
-	if _, ok := twiddleTables[size]; ok {
-		return twiddleTables[size]
+	if t, ok := twiddleTables.Load(size); ok {
+		return t.(*table)
 	}
 	t := &table{
 		cos: make([]float64, size/2),
@@ -25,6 +30,6 @@ func twiddleTable(size int) *table {
 		t.cos[i] = math.Cos(2 * math.Pi * fi / fsize)
 		t.sin[i] = math.Sin(2 * math.Pi * fi / fsize)
 	}
-	twiddleTables[size] = t
+	twiddleTables.Store(size, t)
 	return t
 }
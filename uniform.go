@@ -0,0 +1,251 @@
+package fourier
+
+// uniform is an engine that partitions the impulse response into equally
+// sized segments. The input for the current block is transformed on every call
+// to process, so there is no latency regardless of how many samples the caller
+// provides.
+type uniform struct {
+	// Sizes
+	blockSize, fftSize int
+	method             Method
+
+	// Buffers
+	inputSegments, responseSegments [][]complex128
+	output, temp                    []complex128
+	input, overlap                  []float64
+
+	// Internal state
+	inputSegmentPos, inputPos int
+	irSize                    int
+}
+
+// newUniform returns a uniformly partitioned engine for an impulse response.
+func newUniform(blockSize, fftSize int, method Method, ir []float64) *uniform {
+	var (
+		fillSize            = fftSize - blockSize
+		numResponseSegments = int(len(ir)/fillSize + 1)
+	)
+
+	numInputSegments := numResponseSegments
+	if blockSize <= 128 {
+		numInputSegments = 3 * numResponseSegments
+	}
+
+	// Allocate input segments
+	inputSegments := make([][]complex128, numInputSegments)
+	for i := range inputSegments {
+		inputSegments[i] = make([]complex128, fftSize)
+	}
+
+	// Allocate frequency response segments
+	responseSegments := make([][]complex128, numResponseSegments)
+	for i := range responseSegments {
+		responseSegments[i] = make([]complex128, fftSize)
+	}
+	loadIR(responseSegments, ir, fillSize)
+
+	return &uniform{
+		blockSize:        blockSize,
+		fftSize:          fftSize,
+		method:           method,
+		inputSegments:    inputSegments,
+		responseSegments: responseSegments,
+		input:            make([]float64, fftSize),
+		overlap:          make([]float64, fftSize),
+		output:           make([]complex128, fftSize),
+		temp:             make([]complex128, fftSize),
+		irSize:           len(ir),
+	}
+}
+
+// uniformSize returns the number of bytes newUniform allocates for an impulse
+// response of irSize samples.
+func uniformSize(blockSize, fftSize, irSize int) int {
+	var (
+		numResponseSegments = irSize/(fftSize-blockSize) + 1
+		numInputSegments    = numResponseSegments
+	)
+	if blockSize <= 128 {
+		numInputSegments = 3 * numResponseSegments
+	}
+	return (numInputSegments+numResponseSegments+2)*fftSize*sizeofComplex128 +
+		2*fftSize*sizeofFloat64
+}
+
+func (u *uniform) process(out, in []float64) error {
+	var (
+		numInputSegments    = len(u.inputSegments)
+		fftSize             = u.fftSize
+		blockSize           = u.blockSize
+		numSamples          = len(in)
+		numSamplesProcessed = 0
+
+		// Overlap-add places the current block at the head of the FFT
+		// buffer. Overlap-save places it at the end, behind the history of
+		// previous blocks, and reads the valid portion of the output from
+		// there.
+		offset = 0
+	)
+	if u.method == OverlapSave {
+		offset = fftSize - blockSize
+	}
+
+	for numSamplesProcessed < numSamples {
+		var (
+			numRemaining        = numSamples - numSamplesProcessed
+			blockLimit          = blockSize - u.inputPos
+			numSamplesToProcess = min(numRemaining, blockLimit)
+		)
+
+		// Copy the input into the internal input buffer
+		copy(u.input[offset+u.inputPos:], in[numSamplesProcessed:numSamplesProcessed+numSamplesToProcess])
+		inputSegment := u.inputSegments[u.inputSegmentPos]
+		if err := cmplxCopyReal(inputSegment, u.input); err != nil {
+			return err
+		}
+
+		// Forward FFT
+		if err := Forward(inputSegment); err != nil {
+			return err
+		}
+
+		// Multiply
+		if u.inputPos == 0 {
+			u.accumulate()
+		}
+
+		if err := cmplxCopy(u.output, u.temp); err != nil {
+			return err
+		}
+		if err := cmplxMultiplyAdd(u.output, inputSegment, u.responseSegments[0]); err != nil {
+			return err
+		}
+
+		// Inverse FFT
+		if err := Inverse(u.output); err != nil {
+			return err
+		}
+
+		// Add overlap to the output
+		for i := 0; i < numSamplesToProcess; i++ {
+			var (
+				outIdx = numSamplesProcessed + i
+				pos    = u.inputPos + i
+			)
+			if u.method == OverlapSave {
+				out[outIdx] = real(u.output[offset+pos])
+			} else {
+				out[outIdx] = real(u.output[pos]) + u.overlap[pos]
+			}
+		}
+
+		u.inputPos += numSamplesToProcess
+
+		if u.inputPos == blockSize {
+			u.inputPos = 0
+			if err := u.advance(); err != nil {
+				return err
+			}
+
+			// Step the current segment backwards
+			if u.inputSegmentPos > 0 {
+				u.inputSegmentPos--
+			} else {
+				u.inputSegmentPos = numInputSegments - 1
+			}
+		}
+
+		numSamplesProcessed += numSamplesToProcess
+	}
+
+	return nil
+}
+
+// accumulate multiplies the previous blocks of input by all but the first
+// segment of the frequency response. The sum is the same for every call within
+// a block.
+func (u *uniform) accumulate() {
+	var (
+		numResponseSegments = len(u.responseSegments)
+		numInputSegments    = len(u.inputSegments)
+		step                = numInputSegments / numResponseSegments
+	)
+
+	cmplxZero(u.temp)
+
+	index := u.inputSegmentPos
+	for i := 1; i < numResponseSegments; i++ {
+		index += step
+		if index >= numInputSegments {
+			index -= numInputSegments
+		}
+		cmplxMultiplyAdd(u.temp, u.inputSegments[index], u.responseSegments[i])
+	}
+}
+
+// advance prepares the input and overlap buffers for the next block.
+func (u *uniform) advance() error {
+	var (
+		fftSize   = u.fftSize
+		blockSize = u.blockSize
+	)
+
+	if u.method == OverlapSave {
+		// Slide the history window forward by a block. The space for the
+		// next block is left zeroed.
+		copy(u.input, u.input[blockSize:])
+		zero(u.input[fftSize-blockSize:])
+		return nil
+	}
+
+	zero(u.input)
+
+	// Additional overlap when segment size > block size
+	arErr := cmplxAddReal(u.output[blockSize:], u.overlap[blockSize:], fftSize-2*blockSize)
+	if arErr != nil {
+		return arErr
+	}
+
+	// Save the tail of the output as overlap
+	for i := 0; i < fftSize-blockSize; i++ {
+		u.overlap[i] = real(u.output[i+blockSize])
+	}
+	return nil
+}
+
+func (u *uniform) latency() int { return 0 }
+
+func (u *uniform) length() int { return u.irSize }
+
+func (u *uniform) reset() {
+	for _, s := range u.inputSegments {
+		cmplxZero(s)
+	}
+	cmplxZero(u.output)
+	cmplxZero(u.temp)
+	zero(u.input)
+	zero(u.overlap)
+	u.inputSegmentPos = 0
+	u.inputPos = 0
+}
+
+func (u *uniform) seed(from engine) error {
+	prev, ok := from.(*uniform)
+	if !ok || prev.fftSize != u.fftSize || prev.method != u.method {
+		return nil
+	}
+
+	copy(u.input, prev.input)
+	u.inputPos = prev.inputPos
+	copySegments(u.inputSegments, u.inputSegmentPos, prev.inputSegments, prev.inputSegmentPos)
+
+	// The previous blocks are normally accumulated at the start of a block.
+	if u.inputPos > 0 {
+		u.accumulate()
+	}
+
+	// The overlap depends on the impulse response, so an overlap-add engine
+	// can't recover it from the input history. The first block is missing
+	// the tail of the previous one, which the crossfade masks.
+	return nil
+}
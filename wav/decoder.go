@@ -0,0 +1,268 @@
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// maxReadFrames is the most frames Read decodes at once, which bounds the size
+// of its intermediate buffer.
+const maxReadFrames = 16384
+
+// maxFormatSize is the most of a format chunk that is read. The
+// WAVE_FORMAT_EXTENSIBLE form is 40 bytes; anything beyond that is skipped.
+const maxFormatSize = 40
+
+// Decoder reads samples from a WAVE stream.
+type Decoder struct {
+	r      io.Reader
+	format Format
+
+	// remaining is the number of bytes left in the data chunk, or -1 if the
+	// data chunk extends to the end of the stream.
+	remaining int64
+	numFrames int
+
+	buf []byte
+}
+
+// NewDecoder reads the header of a WAVE stream, up to the start of the sample
+// data. Chunks other than the format and data chunks are skipped.
+func NewDecoder(r io.Reader) (*Decoder, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, fmt.Errorf("reading RIFF header: %v", err)
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return nil, errors.New("not a RIFF/WAVE stream")
+	}
+
+	var (
+		d          = &Decoder{r: r}
+		haveFormat bool
+	)
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			if err == io.EOF {
+				return nil, errors.New("no data chunk")
+			}
+			return nil, fmt.Errorf("reading chunk header: %v", err)
+		}
+		var (
+			id   = string(chunk[0:4])
+			size = binary.LittleEndian.Uint32(chunk[4:8])
+		)
+
+		switch id {
+		case "fmt ":
+			n := size
+			if n > maxFormatSize {
+				n = maxFormatSize
+			}
+			body := make([]byte, n)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return nil, fmt.Errorf("reading format chunk: %v", err)
+			}
+			if _, err := io.CopyN(ioutil.Discard, r, int64(size)-int64(len(body))); err != nil {
+				return nil, fmt.Errorf("skipping format chunk: %v", err)
+			}
+			f, err := parseFormat(body)
+			if err != nil {
+				return nil, err
+			}
+			d.format = f
+			haveFormat = true
+			if err := skipPad(r, int64(size)); err != nil {
+				return nil, err
+			}
+
+		case "data":
+			if !haveFormat {
+				return nil, errors.New("data chunk precedes format chunk")
+			}
+			d.remaining = int64(size)
+			d.numFrames = int(size) / d.format.blockAlign()
+			if size == unknownSize {
+				d.remaining = -1
+				d.numFrames = -1
+			}
+			return d, nil
+
+		default:
+			if _, err := io.CopyN(ioutil.Discard, r, int64(size)); err != nil {
+				return nil, fmt.Errorf("skipping %q chunk: %v", id, err)
+			}
+			if err := skipPad(r, int64(size)); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// NewRawDecoder returns a Decoder for headerless little-endian sample data of
+// the given format, such as raw PCM. The data extends to the end of r.
+func NewRawDecoder(r io.Reader, f Format) (*Decoder, error) {
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	return &Decoder{r: r, format: f, remaining: -1, numFrames: -1}, nil
+}
+
+// parseFormat parses the body of a format chunk.
+func parseFormat(b []byte) (Format, error) {
+	if len(b) < 16 {
+		return Format{}, errors.New("format chunk too short")
+	}
+	var (
+		tag = binary.LittleEndian.Uint16(b[0:2])
+		f   = Format{
+			NumChannels: int(binary.LittleEndian.Uint16(b[2:4])),
+			SampleRate:  int(binary.LittleEndian.Uint32(b[4:8])),
+			BitDepth:    int(binary.LittleEndian.Uint16(b[14:16])),
+		}
+	)
+
+	if tag == formatExtensible {
+		if len(b) < 40 {
+			return Format{}, errors.New("extensible format chunk too short")
+		}
+		var guid [14]byte
+		copy(guid[:], b[26:40])
+		if guid != extensibleGUID {
+			return Format{}, errors.New("unsupported extensible sub-format")
+		}
+		tag = binary.LittleEndian.Uint16(b[24:26])
+	}
+
+	switch tag {
+	case formatPCM:
+	case formatFloat:
+		f.Float = true
+	default:
+		return Format{}, fmt.Errorf("unsupported format tag %#04x", tag)
+	}
+
+	if err := f.validate(); err != nil {
+		return Format{}, err
+	}
+	if align := int(binary.LittleEndian.Uint16(b[12:14])); align != f.blockAlign() {
+		return Format{}, fmt.Errorf("block align %d doesn't match format", align)
+	}
+	return f, nil
+}
+
+// skipPad skips the pad byte that follows a chunk of odd size.
+func skipPad(r io.Reader, size int64) error {
+	if size%2 == 0 {
+		return nil
+	}
+	var pad [1]byte
+	if _, err := io.ReadFull(r, pad[:]); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// Format returns the format of the stream.
+func (d *Decoder) Format() Format {
+	return d.format
+}
+
+// NumFrames returns the number of frames (one sample for every channel) in the
+// stream, or -1 if the stream doesn't declare its length.
+func (d *Decoder) NumFrames() int {
+	return d.numFrames
+}
+
+// Read decodes up to len(samples) interleaved samples, always stopping on a
+// frame boundary, and returns the number of samples decoded. It may decode
+// fewer samples than requested even when more are available. It returns io.EOF
+// once the data is exhausted, and io.ErrUnexpectedEOF if the stream ends
+// partway through a frame.
+func (d *Decoder) Read(samples []float64) (int, error) {
+	var (
+		blockAlign     = d.format.blockAlign()
+		bytesPerSample = d.format.bytesPerSample()
+		numFrames      = min(len(samples)/d.format.NumChannels, maxReadFrames)
+		numBytes       = int64(numFrames * blockAlign)
+	)
+	if d.remaining == 0 {
+		return 0, io.EOF
+	}
+	if d.remaining > 0 && numBytes > d.remaining {
+		numBytes = d.remaining - d.remaining%int64(blockAlign)
+		if numBytes == 0 {
+			return 0, io.ErrUnexpectedEOF
+		}
+	}
+	if numBytes == 0 {
+		return 0, nil
+	}
+
+	if int64(cap(d.buf)) < numBytes {
+		d.buf = make([]byte, numBytes)
+	}
+	buf := d.buf[:numBytes]
+
+	n, err := io.ReadFull(d.r, buf)
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		// A stream of unknown length ends wherever the data runs out, but
+		// only on a frame boundary.
+		if d.remaining > 0 || n%blockAlign != 0 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		d.remaining = 0
+		if n == 0 {
+			return 0, io.EOF
+		}
+	case err != nil:
+		return 0, err
+	}
+	if d.remaining > 0 {
+		d.remaining -= int64(n)
+	}
+
+	numSamples := n / bytesPerSample
+	for i := 0; i < numSamples; i++ {
+		samples[i] = decodeSample(buf[i*bytesPerSample:], d.format)
+	}
+	return numSamples, nil
+}
+
+// ReadAll decodes the remaining samples in the stream.
+func (d *Decoder) ReadAll() ([]float64, error) {
+	// The declared length comes from the file, so only a block's worth is
+	// allocated up front and the rest as frames actually arrive.
+	var samples []float64
+	if d.numFrames > 0 {
+		samples = make([]float64, 0, min(d.numFrames, maxReadFrames)*d.format.NumChannels)
+	}
+	for d.remaining != 0 {
+		if cap(samples)-len(samples) < d.format.NumChannels {
+			grown := make([]float64, len(samples), 2*cap(samples)+4096*d.format.NumChannels)
+			copy(grown, samples)
+			samples = grown
+		}
+		n, err := d.Read(samples[len(samples):cap(samples)])
+		samples = samples[:len(samples)+n]
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return samples, err
+		}
+	}
+	return samples, nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
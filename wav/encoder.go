@@ -0,0 +1,165 @@
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Encoder writes samples to a WAVE stream.
+//
+// If the underlying writer is seekable, Close fills in the chunk sizes in the
+// header. Otherwise the sizes are written as 0xFFFFFFFF, which Decoder
+// (and most other readers) take to mean the data extends to the end of the
+// stream.
+type Encoder struct {
+	w      io.Writer
+	format Format
+
+	headerSize int
+	dataSize   int64
+	closed     bool
+
+	buf []byte
+}
+
+// NewEncoder returns an Encoder that writes samples of the given format to w.
+// The header is written immediately.
+func NewEncoder(w io.Writer, f Format) (*Encoder, error) {
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+
+	e := &Encoder{w: w, format: f}
+	header := e.header(unknownSize)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	e.headerSize = len(header)
+	return e, nil
+}
+
+// header returns the RIFF header, format chunk and data chunk header for a
+// data chunk of dataSize bytes.
+func (e *Encoder) header(dataSize uint32) []byte {
+	var (
+		f          = e.format
+		tag        = uint16(formatPCM)
+		formatSize = 16
+	)
+	if f.Float {
+		tag = formatFloat
+	}
+	if f.extensible() {
+		formatSize = 40
+	}
+
+	var (
+		b       = make([]byte, 12+8+formatSize+8)
+		le      = binary.LittleEndian
+		riffLen = uint32(unknownSize)
+	)
+	if dataSize != unknownSize {
+		riffLen = uint32(len(b)-8) + dataSize + dataSize%2
+	}
+
+	copy(b[0:], "RIFF")
+	le.PutUint32(b[4:], riffLen)
+	copy(b[8:], "WAVE")
+
+	copy(b[12:], "fmt ")
+	le.PutUint32(b[16:], uint32(formatSize))
+	fmtChunk := b[20:]
+	le.PutUint16(fmtChunk[0:], tag)
+	le.PutUint16(fmtChunk[2:], uint16(f.NumChannels))
+	le.PutUint32(fmtChunk[4:], uint32(f.SampleRate))
+	le.PutUint32(fmtChunk[8:], uint32(f.SampleRate*f.blockAlign()))
+	le.PutUint16(fmtChunk[12:], uint16(f.blockAlign()))
+	le.PutUint16(fmtChunk[14:], uint16(f.BitDepth))
+	if f.extensible() {
+		le.PutUint16(fmtChunk[0:], formatExtensible)
+		le.PutUint16(fmtChunk[16:], 22)
+		le.PutUint16(fmtChunk[18:], uint16(f.BitDepth)) // valid bits
+		le.PutUint32(fmtChunk[20:], 0)                  // channel mask: unassigned
+		le.PutUint16(fmtChunk[24:], tag)
+		copy(fmtChunk[26:], extensibleGUID[:])
+	}
+
+	data := b[20+formatSize:]
+	copy(data, "data")
+	le.PutUint32(data[4:], dataSize)
+	return b
+}
+
+// Format returns the format of the stream.
+func (e *Encoder) Format() Format {
+	return e.format
+}
+
+// Write encodes interleaved samples. The number of samples must be a multiple
+// of the number of channels. Integer samples are clipped to [-1, 1).
+func (e *Encoder) Write(samples []float64) error {
+	if e.closed {
+		return errors.New("write to closed encoder")
+	}
+	if len(samples)%e.format.NumChannels != 0 {
+		return errors.New("number of samples must be a multiple of the number of channels")
+	}
+
+	bytesPerSample := e.format.bytesPerSample()
+	for len(samples) > 0 {
+		n := min(len(samples), maxReadFrames*e.format.NumChannels)
+		if cap(e.buf) < n*bytesPerSample {
+			e.buf = make([]byte, n*bytesPerSample)
+		}
+		buf := e.buf[:n*bytesPerSample]
+		for i, v := range samples[:n] {
+			encodeSample(buf[i*bytesPerSample:], v, e.format)
+		}
+		if _, err := e.w.Write(buf); err != nil {
+			return err
+		}
+		e.dataSize += int64(len(buf))
+		samples = samples[n:]
+	}
+	return nil
+}
+
+// Close pads the data chunk to an even length and fills in the chunk sizes in
+// the header, if the underlying writer is seekable. A stream of unknown length
+// is left unpadded, since a reader can't tell a pad byte from sample data. It
+// doesn't close the underlying writer.
+func (e *Encoder) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+
+	ws, ok := e.w.(io.WriteSeeker)
+	if !ok {
+		return nil
+	}
+	end, err := ws.Seek(0, io.SeekCurrent)
+	if err != nil {
+		// Not actually seekable, such as a pipe.
+		return nil
+	}
+	if e.dataSize >= unknownSize {
+		return errors.New("data exceeds the maximum size of a WAVE file")
+	}
+
+	if e.dataSize%2 == 1 {
+		if _, err := ws.Write([]byte{0}); err != nil {
+			return err
+		}
+		end++
+	}
+	if _, err := ws.Seek(end-e.dataSize-e.dataSize%2-int64(e.headerSize), io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := ws.Write(e.header(uint32(e.dataSize))); err != nil {
+		return err
+	}
+	_, err = ws.Seek(end, io.SeekStart)
+	return err
+}
@@ -0,0 +1,47 @@
+package wav_test
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/brettbuddin/fourier"
+	"github.com/brettbuddin/fourier/wav"
+)
+
+func Example() {
+	// Encode a stereo signal and an impulse response to WAVE in memory.
+	var (
+		format      = wav.Format{SampleRate: 48000, NumChannels: 2, BitDepth: 24}
+		signal      = []float64{0.5, -0.5, 0, 0, 0, 0, 0, 0}
+		impulse     = []float64{1, 0, 0.5}
+		signalFile  bytes.Buffer
+		impulseFile bytes.Buffer
+	)
+	enc, _ := wav.NewEncoder(&signalFile, format)
+	enc.Write(signal)
+	enc.Close()
+	enc, _ = wav.NewEncoder(&impulseFile, wav.Format{SampleRate: 48000, NumChannels: 1, BitDepth: 32, Float: true})
+	enc.Write(impulse)
+	enc.Close()
+
+	// Decode them and convolve each channel of the signal with the impulse
+	// response.
+	dec, _ := wav.NewDecoder(&impulseFile)
+	ir, _ := dec.ReadAll()
+	dec, _ = wav.NewDecoder(&signalFile)
+	in, _ := dec.ReadAll()
+
+	var (
+		numChannels = dec.Format().NumChannels
+		numFrames   = len(in) / numChannels
+		out         = make([]float64, len(in))
+	)
+	for c := 0; c < numChannels; c++ {
+		conv, _ := fourier.NewConvolver(4, ir, fourier.ForChannel(c, numChannels))
+		conv.Convolve(out, in, numFrames)
+	}
+	fmt.Println(out)
+
+	// Output:
+	// [0.5 -0.5 0 0 0.25 -0.25 0 0]
+}
@@ -0,0 +1,224 @@
+// Package wav decodes and encodes RIFF/WAVE audio. Samples are exchanged as
+// interleaved float64 values in the range [-1, 1], the layout a Convolver
+// reads with ForChannel.
+//
+// Integer PCM of 8, 16, 24 and 32 bits and IEEE float of 32 and 64 bits are
+// supported, in both the plain and WAVE_FORMAT_EXTENSIBLE forms of the format
+// chunk.
+package wav
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+)
+
+// Format tags of the format chunk
+const (
+	formatPCM        = 0x0001
+	formatFloat      = 0x0003
+	formatExtensible = 0xFFFE
+)
+
+// extensibleGUID is the tail shared by the sub-format GUIDs of
+// WAVE_FORMAT_EXTENSIBLE. The first two bytes of the GUID hold the format tag.
+var extensibleGUID = [14]byte{
+	0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xaa, 0x00, 0x38, 0x9b, 0x71,
+}
+
+// unknownSize is written in place of chunk sizes when a stream can't be
+// rewound to fill them in. A data chunk of this size extends to the end of the
+// stream.
+const unknownSize = 0xFFFFFFFF
+
+// Format describes the layout of the samples in a WAVE file.
+type Format struct {
+	SampleRate  int
+	NumChannels int
+
+	// BitDepth is the number of bits each sample occupies in the file.
+	BitDepth int
+
+	// Float is true for IEEE floating-point samples and false for integer
+	// PCM.
+	Float bool
+}
+
+// bytesPerSample returns the size of a single sample in bytes.
+func (f Format) bytesPerSample() int {
+	return (f.BitDepth + 7) / 8
+}
+
+// blockAlign returns the size of a frame (one sample for every channel) in
+// bytes.
+func (f Format) blockAlign() int {
+	return f.NumChannels * f.bytesPerSample()
+}
+
+// validate checks that the format is one this package can decode and encode.
+func (f Format) validate() error {
+	if f.SampleRate <= 0 {
+		return errors.New("sample rate must be positive")
+	}
+	if f.NumChannels <= 0 {
+		return errors.New("number of channels must be positive")
+	}
+	if f.Float {
+		if f.BitDepth != 32 && f.BitDepth != 64 {
+			return fmt.Errorf("unsupported floating-point bit depth %d", f.BitDepth)
+		}
+		return nil
+	}
+	switch f.BitDepth {
+	case 8, 16, 24, 32:
+		return nil
+	default:
+		return fmt.Errorf("unsupported PCM bit depth %d", f.BitDepth)
+	}
+}
+
+// extensible reports whether the format should be written with
+// WAVE_FORMAT_EXTENSIBLE, which is expected for more than two channels and for
+// integer PCM of more than 16 bits.
+func (f Format) extensible() bool {
+	return f.NumChannels > 2 || (!f.Float && f.BitDepth > 16)
+}
+
+// ReadFile decodes a whole WAVE file, returning its interleaved samples and
+// format.
+func ReadFile(path string) ([]float64, Format, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, Format{}, err
+	}
+	defer file.Close()
+
+	d, err := NewDecoder(file)
+	if err != nil {
+		return nil, Format{}, err
+	}
+	samples, err := d.ReadAll()
+	return samples, d.Format(), err
+}
+
+// WriteFile encodes interleaved samples to a WAVE file, creating or
+// truncating it.
+func WriteFile(path string, samples []float64, f Format) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	e, err := NewEncoder(file, f)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if err := e.Write(samples); err != nil {
+		file.Close()
+		return err
+	}
+	if err := e.Close(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Deinterleave splits interleaved samples into one slice per channel. A
+// trailing partial frame is dropped.
+func Deinterleave(samples []float64, numChannels int) [][]float64 {
+	var (
+		numFrames = len(samples) / numChannels
+		channels  = make([][]float64, numChannels)
+	)
+	for c := range channels {
+		channels[c] = make([]float64, numFrames)
+		for i := range channels[c] {
+			channels[c][i] = samples[i*numChannels+c]
+		}
+	}
+	return channels
+}
+
+// Interleave combines one slice per channel into interleaved samples. The
+// result is as long as the longest channel; shorter channels are padded with
+// silence.
+func Interleave(channels [][]float64) []float64 {
+	var numFrames int
+	for _, ch := range channels {
+		if len(ch) > numFrames {
+			numFrames = len(ch)
+		}
+	}
+	var (
+		numChannels = len(channels)
+		samples     = make([]float64, numFrames*numChannels)
+	)
+	for c, ch := range channels {
+		for i, v := range ch {
+			samples[i*numChannels+c] = v
+		}
+	}
+	return samples
+}
+
+// decodeSample converts a sample from its encoding in b.
+func decodeSample(b []byte, f Format) float64 {
+	if f.Float {
+		if f.BitDepth == 32 {
+			return float64(math.Float32frombits(uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24))
+		}
+		var bits uint64
+		for i := 7; i >= 0; i-- {
+			bits = bits<<8 | uint64(b[i])
+		}
+		return math.Float64frombits(bits)
+	}
+
+	switch f.BitDepth {
+	case 8:
+		// 8-bit PCM is unsigned.
+		return float64(int(b[0])-128) / (1 << 7)
+	case 16:
+		return float64(int16(uint16(b[0])|uint16(b[1])<<8)) / (1 << 15)
+	case 24:
+		v := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+		return float64(v) / (1 << 23)
+	default:
+		return float64(int32(uint32(b[0])|uint32(b[1])<<8|uint32(b[2])<<16|uint32(b[3])<<24)) / (1 << 31)
+	}
+}
+
+// encodeSample writes the encoding of v into b. Integer samples are clipped to
+// [-1, 1), and NaN is encoded as silence.
+func encodeSample(b []byte, v float64, f Format) {
+	if f.Float {
+		if f.BitDepth == 32 {
+			bits := math.Float32bits(float32(v))
+			b[0], b[1], b[2], b[3] = byte(bits), byte(bits>>8), byte(bits>>16), byte(bits>>24)
+			return
+		}
+		bits := math.Float64bits(v)
+		for i := 0; i < 8; i++ {
+			b[i] = byte(bits >> uint(8*i))
+		}
+		return
+	}
+
+	if math.IsNaN(v) {
+		v = 0
+	}
+	var (
+		scale = float64(int64(1) << uint(f.BitDepth-1))
+		i     = int64(math.Max(-scale, math.Min(scale-1, math.Round(v*scale))))
+	)
+	if f.BitDepth == 8 {
+		b[0] = byte(i + 128)
+		return
+	}
+	for n := 0; n < f.bytesPerSample(); n++ {
+		b[n] = byte(i >> uint(8*n))
+	}
+}
@@ -0,0 +1,330 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// seekBuffer is an in-memory io.WriteSeeker.
+type seekBuffer struct {
+	buf []byte
+	pos int
+}
+
+func (s *seekBuffer) Write(p []byte) (int, error) {
+	if end := s.pos + len(p); end > len(s.buf) {
+		s.buf = append(s.buf, make([]byte, end-len(s.buf))...)
+	}
+	copy(s.buf[s.pos:], p)
+	s.pos += len(p)
+	return len(p), nil
+}
+
+func (s *seekBuffer) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += int64(s.pos)
+	case io.SeekEnd:
+		offset += int64(len(s.buf))
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	s.pos = int(offset)
+	return offset, nil
+}
+
+func testSignal(numFrames, numChannels int) []float64 {
+	samples := make([]float64, numFrames*numChannels)
+	for i := range samples {
+		var (
+			frame = i / numChannels
+			c     = i % numChannels
+		)
+		samples[i] = 0.9 * math.Sin(float64(frame)*0.01*float64(c+1))
+	}
+	return samples
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, f := range []Format{
+		{SampleRate: 44100, NumChannels: 1, BitDepth: 8},
+		{SampleRate: 44100, NumChannels: 2, BitDepth: 16},
+		{SampleRate: 48000, NumChannels: 1, BitDepth: 24},
+		{SampleRate: 48000, NumChannels: 6, BitDepth: 24},
+		{SampleRate: 96000, NumChannels: 2, BitDepth: 32},
+		{SampleRate: 96000, NumChannels: 2, BitDepth: 32, Float: true},
+		{SampleRate: 96000, NumChannels: 3, BitDepth: 64, Float: true},
+	} {
+		for _, seekable := range []bool{true, false} {
+			name := fmt.Sprintf("ch=%d/bits=%d/float=%t/seekable=%t", f.NumChannels, f.BitDepth, f.Float, seekable)
+			t.Run(name, func(t *testing.T) {
+				var (
+					samples = testSignal(1001, f.NumChannels)
+					sb      = &seekBuffer{}
+					bb      = &bytes.Buffer{}
+					w       io.Writer
+				)
+				w = bb
+				if seekable {
+					w = sb
+				}
+
+				e, err := NewEncoder(w, f)
+				require.NoError(t, err)
+				require.NoError(t, e.Write(samples[:10*f.NumChannels]))
+				require.NoError(t, e.Write(samples[10*f.NumChannels:]))
+				require.NoError(t, e.Close())
+
+				data := bb.Bytes()
+				if seekable {
+					data = sb.buf
+					require.Equal(t, 0, len(data)%2)
+				}
+
+				d, err := NewDecoder(bytes.NewReader(data))
+				require.NoError(t, err)
+				require.Equal(t, f, d.Format())
+				if seekable {
+					require.Equal(t, 1001, d.NumFrames())
+					require.Equal(t, uint32(len(data)-8), binary.LittleEndian.Uint32(data[4:8]))
+				} else {
+					require.Equal(t, -1, d.NumFrames())
+				}
+
+				decoded, err := d.ReadAll()
+				require.NoError(t, err)
+				require.Len(t, decoded, len(samples))
+
+				delta := 1e-15
+				switch {
+				case f.Float && f.BitDepth == 32:
+					delta = 1e-7
+				case !f.Float:
+					delta = 1 / float64(int64(1)<<uint(f.BitDepth-1))
+				}
+				require.InDeltaSlice(t, samples, decoded, delta)
+			})
+		}
+	}
+}
+
+func TestDecoder_KnownBytes(t *testing.T) {
+	var b bytes.Buffer
+	le := binary.LittleEndian
+	write := func(v ...interface{}) {
+		for _, x := range v {
+			if s, ok := x.(string); ok {
+				b.WriteString(s)
+				continue
+			}
+			binary.Write(&b, le, x)
+		}
+	}
+
+	write("RIFF", uint32(0), "WAVE")
+	// An odd-sized chunk before the format chunk is skipped along with its
+	// pad byte.
+	write("LIST", uint32(3), []byte{1, 2, 3}, byte(0))
+	write("fmt ", uint32(16), uint16(formatPCM), uint16(2), uint32(8000), uint32(32000), uint16(4), uint16(16))
+	write("data", uint32(8), int16(-32768), int16(16384), int16(0), int16(32767))
+
+	d, err := NewDecoder(&b)
+	require.NoError(t, err)
+	require.Equal(t, Format{SampleRate: 8000, NumChannels: 2, BitDepth: 16}, d.Format())
+	require.Equal(t, 2, d.NumFrames())
+
+	// A buffer that can't hold a whole frame decodes nothing.
+	n, err := d.Read(make([]float64, 1))
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	buf := make([]float64, 3)
+	n, err = d.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, []float64{-1, 0.5}, buf[:n])
+
+	n, err = d.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, []float64{0, 32767.0 / 32768}, buf[:n])
+
+	_, err = d.Read(buf)
+	require.Equal(t, io.EOF, err)
+}
+
+func TestEncoder_Extensible(t *testing.T) {
+	sb := &seekBuffer{}
+	e, err := NewEncoder(sb, Format{SampleRate: 48000, NumChannels: 2, BitDepth: 24})
+	require.NoError(t, err)
+	require.NoError(t, e.Write([]float64{0.5, -0.5}))
+	require.NoError(t, e.Close())
+
+	le := binary.LittleEndian
+	require.Equal(t, uint32(40), le.Uint32(sb.buf[16:20]))
+	require.Equal(t, uint16(formatExtensible), le.Uint16(sb.buf[20:22]))
+	require.Equal(t, uint16(formatPCM), le.Uint16(sb.buf[44:46]))
+	require.Equal(t, "data", string(sb.buf[60:64]))
+	require.Equal(t, uint32(6), le.Uint32(sb.buf[64:68]))
+
+	// Plain 16-bit stereo uses the basic format chunk.
+	sb = &seekBuffer{}
+	e, err = NewEncoder(sb, Format{SampleRate: 48000, NumChannels: 2, BitDepth: 16})
+	require.NoError(t, err)
+	require.NoError(t, e.Close())
+	require.Equal(t, uint32(16), le.Uint32(sb.buf[16:20]))
+	require.Equal(t, uint16(formatPCM), le.Uint16(sb.buf[20:22]))
+}
+
+func TestEncoder_Clipping(t *testing.T) {
+	sb := &seekBuffer{}
+	e, err := NewEncoder(sb, Format{SampleRate: 8000, NumChannels: 1, BitDepth: 16})
+	require.NoError(t, err)
+	require.NoError(t, e.Write([]float64{2, -2}))
+	require.NoError(t, e.Close())
+
+	d, err := NewDecoder(bytes.NewReader(sb.buf))
+	require.NoError(t, err)
+	decoded, err := d.ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, []float64{32767.0 / 32768, -1}, decoded)
+
+	// NaN is encoded as silence.
+	sb = &seekBuffer{}
+	e, err = NewEncoder(sb, Format{SampleRate: 8000, NumChannels: 1, BitDepth: 24})
+	require.NoError(t, err)
+	require.NoError(t, e.Write([]float64{math.NaN(), 0.5}))
+	require.NoError(t, e.Close())
+
+	d, err = NewDecoder(bytes.NewReader(sb.buf))
+	require.NoError(t, err)
+	decoded, err = d.ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, []float64{0, 0.5}, decoded)
+}
+
+func TestDecoder_FormatSize(t *testing.T) {
+	le := binary.LittleEndian
+	format := func(size uint32, extra int) []byte {
+		var b bytes.Buffer
+		b.WriteString("RIFF\x00\x00\x00\x00WAVEfmt ")
+		binary.Write(&b, le, size)
+		binary.Write(&b, le, []uint16{formatPCM, 1})
+		binary.Write(&b, le, []uint32{8000, 16000})
+		binary.Write(&b, le, []uint16{2, 16})
+		b.Write(make([]byte, extra))
+		b.WriteString("data\x02\x00\x00\x00\x00\x40")
+		return b.Bytes()
+	}
+
+	// Trailing bytes of an oversized format chunk are skipped.
+	d, err := NewDecoder(bytes.NewReader(format(16+50, 50)))
+	require.NoError(t, err)
+	require.Equal(t, Format{SampleRate: 8000, NumChannels: 1, BitDepth: 16}, d.Format())
+	samples, err := d.ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, []float64{0.5}, samples)
+
+	// A huge declared size fails on the short stream rather than allocating
+	// the whole chunk.
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err = NewDecoder(bytes.NewReader(format(0xFFFFFFF0, 0)))
+	runtime.ReadMemStats(&after)
+	require.Error(t, err)
+	require.True(t, after.TotalAlloc-before.TotalAlloc < 1<<20)
+}
+
+func TestDecoder_DataSize(t *testing.T) {
+	// A data chunk that claims far more than the stream holds.
+	var b bytes.Buffer
+	b.WriteString("RIFF\x00\x00\x00\x00WAVEfmt \x10\x00\x00\x00")
+	binary.Write(&b, binary.LittleEndian, []uint16{formatPCM, 1})
+	binary.Write(&b, binary.LittleEndian, []uint32{8000, 16000})
+	binary.Write(&b, binary.LittleEndian, []uint16{2, 16})
+	b.WriteString("data\xf0\xff\xff\xff\x00\x40")
+
+	d, err := NewDecoder(bytes.NewReader(b.Bytes()))
+	require.NoError(t, err)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	samples, err := d.ReadAll()
+	runtime.ReadMemStats(&after)
+	require.Equal(t, io.ErrUnexpectedEOF, err)
+	require.Empty(t, samples)
+	require.True(t, after.TotalAlloc-before.TotalAlloc < 1<<20)
+}
+
+func TestErrors(t *testing.T) {
+	_, err := NewDecoder(bytes.NewReader([]byte("RIFX\x00\x00\x00\x00WAVE")))
+	require.Error(t, err)
+
+	_, err = NewEncoder(&bytes.Buffer{}, Format{SampleRate: 8000, NumChannels: 1, BitDepth: 12})
+	require.Error(t, err)
+	_, err = NewEncoder(&bytes.Buffer{}, Format{SampleRate: 8000, NumChannels: 1, BitDepth: 16, Float: true})
+	require.Error(t, err)
+	_, err = NewEncoder(&bytes.Buffer{}, Format{SampleRate: 8000, BitDepth: 16})
+	require.Error(t, err)
+
+	e, err := NewEncoder(&bytes.Buffer{}, Format{SampleRate: 8000, NumChannels: 2, BitDepth: 16})
+	require.NoError(t, err)
+	require.Error(t, e.Write([]float64{1}))
+
+	// Data chunk shorter than declared
+	sb := &seekBuffer{}
+	e, err = NewEncoder(sb, Format{SampleRate: 8000, NumChannels: 1, BitDepth: 16})
+	require.NoError(t, err)
+	require.NoError(t, e.Write(make([]float64, 10)))
+	require.NoError(t, e.Close())
+
+	d, err := NewDecoder(bytes.NewReader(sb.buf[:len(sb.buf)-4]))
+	require.NoError(t, err)
+	_, err = d.ReadAll()
+	require.Equal(t, io.ErrUnexpectedEOF, err)
+
+	// Data before format
+	var b bytes.Buffer
+	b.WriteString("RIFF\x00\x00\x00\x00WAVEdata\x00\x00\x00\x00")
+	_, err = NewDecoder(&b)
+	require.Error(t, err)
+}
+
+func TestInterleave(t *testing.T) {
+	channels := Deinterleave([]float64{1, 2, 3, 4, 5, 6, 7}, 2)
+	require.Equal(t, [][]float64{{1, 3, 5}, {2, 4, 6}}, channels)
+	require.Equal(t, []float64{1, 2, 3, 4, 5, 6}, Interleave(channels))
+	require.Equal(t, []float64{1, 2, 3, 0}, Interleave([][]float64{{1, 3}, {2}}))
+}
+
+func TestRawDecoder(t *testing.T) {
+	var (
+		f   = Format{SampleRate: 8000, NumChannels: 2, BitDepth: 16}
+		raw = []byte{0x00, 0x80, 0x00, 0x40, 0x00, 0x00, 0xff, 0x7f}
+	)
+	d, err := NewRawDecoder(bytes.NewReader(raw), f)
+	require.NoError(t, err)
+	require.Equal(t, -1, d.NumFrames())
+
+	samples, err := d.ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, []float64{-1, 0.5, 0, 32767.0 / 32768}, samples)
+
+	// A partial frame at the end
+	d, err = NewRawDecoder(bytes.NewReader(raw[:6]), f)
+	require.NoError(t, err)
+	_, err = d.ReadAll()
+	require.Equal(t, io.ErrUnexpectedEOF, err)
+
+	_, err = NewRawDecoder(bytes.NewReader(raw), Format{SampleRate: 8000, NumChannels: 2, BitDepth: 20})
+	require.Error(t, err)
+}
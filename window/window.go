@@ -2,11 +2,24 @@
 // filters.
 package window
 
-import "math"
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
 
 // Func is a windowing function.
 type Func func(x float64, n int) float64
 
+// Rectangular is a rectangular windowing function: it leaves the signal
+// unchanged.
+//
+// Reference: https://en.wikipedia.org/wiki/Window_function#Rectangular_window
+func Rectangular(x float64, n int) float64 {
+	return 1
+}
+
 // Blackman is a Blackman windowing function.
 //
 // Reference: https://en.wikipedia.org/wiki/Window_function#Blackman_window
@@ -46,6 +59,36 @@ func Bartlett(x float64, n int) float64 {
 	return 1 - 2*math.Abs(x-float64(n)/2)/float64(n)
 }
 
+// Kaiser returns a Kaiser windowing function with shape parameter beta. A beta
+// of 0 gives a rectangular window; larger values trade a wider main lobe for
+// lower side lobes. A beta of about 5.7 resembles Hamming and 8.6 Blackman.
+//
+// Reference: https://en.wikipedia.org/wiki/Kaiser_window
+func Kaiser(beta float64) Func {
+	scale := 1 / BesselI0(beta)
+	return func(x float64, n int) float64 {
+		r := 2*x/float64(n) - 1
+		return BesselI0(beta*math.Sqrt(math.Max(0, 1-r*r))) * scale
+	}
+}
+
+// BesselI0 is the zeroth-order modified Bessel function of the first kind. Use
+// it to create other window functions.
+//
+// Reference: https://en.wikipedia.org/wiki/Bessel_function#Modified_Bessel_functions:_I%CE%B1,_K%CE%B1
+func BesselI0(x float64) float64 {
+	var (
+		sum  = 1.0
+		term = 1.0
+		half = x / 2
+	)
+	for k := 1; term > 1e-17*sum; k++ {
+		term *= (half / float64(k)) * (half / float64(k))
+		sum += term
+	}
+	return sum
+}
+
 // Sinc is the cardinal sinc function. Use it to create other window functions.
 //
 // Reference: https://en.wikipedia.org/wiki/Sinc_function
@@ -55,3 +98,33 @@ func Sinc(x float64) float64 {
 	}
 	return math.Sin(math.Pi*x) / (math.Pi * x)
 }
+
+var byName = map[string]Func{
+	"rectangular": Rectangular,
+	"blackman":    Blackman,
+	"hann":        Hann,
+	"hamming":     Hamming,
+	"lanczos":     Lanczos,
+	"bartlett":    Bartlett,
+}
+
+// ByName returns the windowing function with the given name, ignoring case.
+// The names are those returned by Names.
+func ByName(name string) (Func, error) {
+	wf, ok := byName[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown window %q (known: %s)", name, strings.Join(Names(), ", "))
+	}
+	return wf, nil
+}
+
+// Names returns the names of the windowing functions known to ByName, in
+// alphabetical order.
+func Names() []string {
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -106,3 +106,38 @@ func TestWindows(t *testing.T) {
 		}
 	}
 }
+
+func TestKaiser(t *testing.T) {
+	require.InEpsilon(t, 1.2660658777520082, BesselI0(1), 1e-12)
+	require.InEpsilon(t, 27.239871823604442, BesselI0(5), 1e-12)
+	require.InEpsilon(t, 2815.716628466254, BesselI0(10), 1e-12)
+	require.Equal(t, 1.0, BesselI0(0))
+
+	const n = 10
+	rect := Kaiser(0)
+	for i := 0; i <= n; i++ {
+		require.Equal(t, 1.0, rect(float64(i), n))
+	}
+
+	wf := Kaiser(8.6)
+	require.InEpsilon(t, 1, wf(n/2, n), 1e-12)
+	require.InEpsilon(t, 1/BesselI0(8.6), wf(0, n), 1e-12)
+	for i := 0; i <= n; i++ {
+		require.InEpsilon(t, wf(float64(i), n), wf(float64(n-i), n), 1e-12)
+	}
+}
+
+func TestByName(t *testing.T) {
+	for _, name := range Names() {
+		wf, err := ByName(name)
+		require.NoError(t, err)
+		require.NotNil(t, wf)
+	}
+
+	wf, err := ByName("Hann")
+	require.NoError(t, err)
+	require.Equal(t, Hann(3, 10), wf(3, 10))
+
+	_, err = ByName("kaiser-bessel")
+	require.Error(t, err)
+}